@@ -13,14 +13,22 @@ import (
 	"time"
 
 	"pages/internal/analytics"
+	"pages/internal/audit"
+	"pages/internal/auth"
 	"pages/internal/config"
+	"pages/internal/jobs"
 	"pages/internal/logging"
 	"pages/internal/server"
 	"pages/internal/site"
+	"pages/internal/tenant"
+	"pages/internal/trash"
 )
 
 const configPath = "config.toml"
 
+// trashPurgeInterval 回收站到期清除的检查间隔
+const trashPurgeInterval = 10 * time.Minute
+
 func main() {
 	fmt.Println(" ██████╗  █████╗  ██████╗ ███████╗███████╗")
 	fmt.Println(" ██╔══██╗██╔══██╗██╔════╝ ██╔════╝██╔════╝")
@@ -52,8 +60,62 @@ func main() {
 	analyticsDir := filepath.Join(cfg.Server.DataDir, "analytics")
 	am := analytics.NewManager(analyticsDir)
 
+	// 初始化管理员账户
+	users, err := initUsers(cfg)
+	if err != nil {
+		fmt.Printf("账户初始化失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 加载 API Token
+	tokens := auth.NewTokenStore(cfg.Server.DataDir)
+	if err := tokens.Load(); err != nil {
+		fmt.Printf("加载 API Token 失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 初始化审计日志
+	auditLog := audit.NewLogger(
+		filepath.Join(cfg.Server.DataDir, "audit"),
+		int64(cfg.Audit.MaxSizeMB)<<20,
+		cfg.Audit.MaxFiles,
+	)
+	defer auditLog.Close()
+
+	// 加载租户（登记仅通过站点隐式存在的租户，并恢复停用状态）
+	tenants, err := initTenants(cfg, sm)
+	if err != nil {
+		fmt.Printf("租户初始化失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 加载站点回收站并启动到期清除任务
+	// 保留时长为负数时不保留，删除后立即彻底删除
+	bin := trash.NewBin(cfg.Server.DataDir, time.Duration(max(cfg.Trash.RetentionHours, 0))*time.Hour)
+	if err := bin.Load(); err != nil {
+		fmt.Printf("加载回收站失败: %v\n", err)
+		os.Exit(1)
+	}
+	bin.Start(trashPurgeInterval)
+	defer bin.Stop()
+
+	// 加载异步任务日志（任务在服务器注册路由后开始执行）
+	jm := jobs.NewManager(cfg.Server.DataDir, cfg.Jobs.Workers, cfg.Jobs.History)
+	if err := jm.Load(); err != nil {
+		fmt.Printf("加载任务日志失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer jm.Stop()
+
+	// 初始化单点登录
+	oidc, err := initOIDC(cfg)
+	if err != nil {
+		fmt.Printf("单点登录配置无效: %v\n", err)
+		os.Exit(1)
+	}
+
 	// 创建并启动服务器
-	srv := server.New(cfg, sm, am)
+	srv := server.New(cfg, sm, am, users, tokens, auditLog, oidc, tenants, bin, jm)
 	
 	// 在 goroutine 中启动服务器
 	go func() {
@@ -128,6 +190,82 @@ func initSites(cfg *config.Config) (*site.ManagerLockFree, error) {
 	return sm, nil
 }
 
+// initUsers 加载管理员账户，账户为空时使用配置中的凭据创建超级管理员
+func initUsers(cfg *config.Config) (*auth.UserStore, error) {
+	users := auth.NewUserStore(cfg.Server.DataDir)
+	if err := users.Load(); err != nil {
+		return nil, fmt.Errorf("加载账户失败: %w", err)
+	}
+
+	created, err := users.Bootstrap(cfg.Server.AdminUser, cfg.Server.AdminPass)
+	if err != nil {
+		return nil, fmt.Errorf("创建初始管理员失败: %w", err)
+	}
+	if created {
+		slog.Info("已创建初始超级管理员", "username", cfg.Server.AdminUser)
+	}
+
+	return users, nil
+}
+
+// initTenants 加载租户存储，为已有站点的租户补充登记，并对已停用租户停止站点服务
+func initTenants(cfg *config.Config, sm *site.ManagerLockFree) (*tenant.Store, error) {
+	tenants := tenant.NewStore(cfg.Server.DataDir)
+	if err := tenants.Load(); err != nil {
+		return nil, err
+	}
+
+	sites, err := sm.ListAll()
+	if err != nil {
+		return nil, fmt.Errorf("加载站点失败: %w", err)
+	}
+	for _, s := range sites {
+		created, err := tenants.Ensure(s.Username)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			slog.Info("已登记租户", "tenant", s.Username)
+		}
+	}
+
+	for _, t := range tenants.List() {
+		if t.Suspended {
+			sm.SuspendUser(t.Username)
+		}
+	}
+	return tenants, nil
+}
+
+// initOIDC 根据配置创建单点登录客户端（未启用时返回 nil）
+func initOIDC(cfg *config.Config) (*auth.OIDCProvider, error) {
+	if !cfg.OIDC.Enabled {
+		return nil, nil
+	}
+
+	mappings := make([]auth.OIDCGroupMapping, len(cfg.OIDC.GroupMappings))
+	for i, m := range cfg.OIDC.GroupMappings {
+		mappings[i] = auth.OIDCGroupMapping{Group: m.Group, Role: m.Role, Tenant: m.Tenant}
+	}
+
+	oidc, err := auth.NewOIDCProvider(auth.OIDCConfig{
+		Issuer:        cfg.OIDC.Issuer,
+		ClientID:      cfg.OIDC.ClientID,
+		ClientSecret:  cfg.OIDC.ClientSecret,
+		RedirectURL:   cfg.OIDC.RedirectURL,
+		Scopes:        cfg.OIDC.Scopes,
+		UsernameClaim: cfg.OIDC.UsernameClaim,
+		GroupsClaim:   cfg.OIDC.GroupsClaim,
+		GroupMappings: mappings,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("已启用单点登录", "issuer", cfg.OIDC.Issuer)
+	return oidc, nil
+}
+
 // createDefaultSites 创建默认站点（支持多租户）
 func createDefaultSites(sm *site.ManagerLockFree) error {
 	defaultSites := []*site.Site{
@@ -2,7 +2,11 @@ module pages
 
 go 1.24.4
 
-require github.com/labstack/echo/v4 v4.13.4
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/labstack/echo/v4 v4.13.4
+	github.com/ulikunitz/xz v0.5.17
+)
 
 require (
 	github.com/labstack/gommon v0.4.2 // indirect
@@ -12,9 +16,9 @@ require (
 	github.com/pelletier/go-toml/v2 v2.2.4
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	github.com/valyala/fasttemplate v1.2.2 // indirect
-	golang.org/x/crypto v0.38.0 // indirect
+	golang.org/x/crypto v0.38.0
 	golang.org/x/net v0.40.0 // indirect
-	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/sys v0.33.0
 	golang.org/x/text v0.25.0 // indirect
 	golang.org/x/time v0.11.0 // indirect
 )
@@ -1,7 +1,10 @@
 package analytics
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 )
 
@@ -41,8 +44,7 @@ func (m *Manager) GetWorker(username, siteID string) *SiteWorker {
 
 	// 创建新 Worker
 	// 目录结构: <baseDir>/<username>/<site_id>/
-	siteDir := filepath.Join(m.baseDir, username, siteID)
-	w = NewSiteWorker(siteID, siteDir)
+	w = NewSiteWorker(siteID, m.SiteDir(username, siteID))
 	w.Start()
 	m.workers[key] = w
 	
@@ -72,6 +74,54 @@ func (m *Manager) GetStats(username, siteID string, full bool) (any, error) {
 	return w.GetStats(), nil
 }
 
+// MonthBytes 返回站点指定月份（格式 2006-01）累计发送的字节数
+func (m *Manager) MonthBytes(username, siteID, month string) int64 {
+	return m.GetWorker(username, siteID).MonthBytes(month)
+}
+
+// SiteDir 返回站点统计数据目录
+func (m *Manager) SiteDir(username, siteID string) string {
+	return filepath.Join(m.baseDir, username, siteID)
+}
+
+// StopSite 停止站点的 Worker（写入最后一次快照），之后可安全移动或删除统计目录
+func (m *Manager) StopSite(username, siteID string) {
+	key := username + ":" + siteID
+	m.mu.Lock()
+	w, ok := m.workers[key]
+	delete(m.workers, key)
+	m.mu.Unlock()
+
+	if ok {
+		w.Stop()
+	}
+}
+
+// RemoveUser 停止租户所有站点的 Worker 并删除其统计数据
+func (m *Manager) RemoveUser(username string) error {
+	prefix := username + ":"
+
+	m.mu.Lock()
+	var stopping []*SiteWorker
+	for key, w := range m.workers {
+		if strings.HasPrefix(key, prefix) {
+			stopping = append(stopping, w)
+			delete(m.workers, key)
+		}
+	}
+	m.mu.Unlock()
+
+	// Worker 停止时会写入最后一次快照，需在删除目录之前完成
+	for _, w := range stopping {
+		w.Stop()
+	}
+
+	if err := os.RemoveAll(filepath.Join(m.baseDir, username)); err != nil {
+		return fmt.Errorf("删除统计数据失败: %w", err)
+	}
+	return nil
+}
+
 // StopAll 停止所有 Worker
 func (m *Manager) StopAll() {
 	m.mu.Lock()
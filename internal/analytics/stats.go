@@ -3,6 +3,8 @@ package analytics
 import (
 	"sync"
 	"time"
+
+	"pages/pkg/api"
 )
 
 // AccessLog 访问日志
@@ -18,14 +20,10 @@ type AccessLog struct {
 }
 
 // DailyStats 每日统计聚合
+// 可序列化字段定义在 api.DailyStats 中，与 pkg/client 共用
 type DailyStats struct {
-	Date          string `json:"date"`           // 日期 "2006-01-02"
-	PV            int64  `json:"pv"`             // 页面浏览量
-	UV            int64  `json:"uv"`             // 独立访客 (简单计数)
-	Bytes         int64  `json:"bytes"`          // 流量 (字节)
-	TotalDuration int64  `json:"total_duration"` // 总响应时间 (用于计算平均值)
-	ErrorCount    int64  `json:"error_count"`    // 错误数 (状态码 >= 400)
-	
+	api.DailyStats
+
 	// 简单的 UV 统计辅助 (不序列化)
 	// 在实际生产中，应该使用 HyperLogLog 或 BloomFilter，这里为了轻量使用 map
 	// 注意：这会消耗内存，如果 UV 很大，需要优化
@@ -51,7 +49,7 @@ func NewSiteStats() *SiteStats {
 
 func NewDailyStats(date string) *DailyStats {
 	return &DailyStats{
-		Date:  date,
-		uvMap: make(map[string]struct{}),
+		DailyStats: api.DailyStats{Date: date},
+		uvMap:      make(map[string]struct{}),
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -208,3 +209,24 @@ func (w *SiteWorker) GetFullStats() *SiteStats {
 
 	return copy
 }
+
+// MonthBytes 返回指定月份（格式 2006-01）累计发送的字节数
+func (w *SiteWorker) MonthBytes(month string) int64 {
+	w.Stats.mu.RLock()
+	defer w.Stats.mu.RUnlock()
+
+	var total int64
+	counted := false
+	for date, s := range w.Stats.History {
+		if strings.HasPrefix(date, month) {
+			total += s.Bytes
+			if w.Stats.Today != nil && date == w.Stats.Today.Date {
+				counted = true
+			}
+		}
+	}
+	if w.Stats.Today != nil && !counted && strings.HasPrefix(w.Stats.Today.Date, month) {
+		total += w.Stats.Today.Bytes
+	}
+	return total
+}
@@ -0,0 +1,265 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pages/pkg/api"
+)
+
+const (
+	currentFileName = "audit.log"
+	rotatedPrefix   = "audit-"
+	rotatedSuffix   = ".log"
+
+	defaultMaxSize  = 10 << 20 // 单个日志文件默认上限 10MB
+	defaultMaxFiles = 5        // 默认保留的归档文件数
+	defaultLimit    = 100      // 默认查询条数
+)
+
+// Entry 审计日志条目（与 pkg/client 共用）
+type Entry = api.AuditEntry
+
+// Query 审计日志查询条件
+type Query struct {
+	Tenant string
+	SiteID string
+	Actor  string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// match 判断条目是否满足查询条件
+func (q *Query) match(e *Entry) bool {
+	if q.Tenant != "" && e.Tenant != q.Tenant {
+		return false
+	}
+	if q.SiteID != "" && e.SiteID != q.SiteID {
+		return false
+	}
+	if q.Actor != "" && e.Actor != q.Actor {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && e.Time.After(q.Until) {
+		return false
+	}
+	return true
+}
+
+// Logger 追加写入的审计日志（JSON Lines，按大小轮转）
+type Logger struct {
+	dir      string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewLogger 创建审计日志，maxSize/maxFiles 为 0 时使用默认值
+func NewLogger(dir string, maxSize int64, maxFiles int) *Logger {
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = defaultMaxFiles
+	}
+	return &Logger{
+		dir:      dir,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+}
+
+// Record 写入一条审计日志
+func (l *Logger) Record(e *Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("序列化审计日志失败: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.openInternal(); err != nil {
+		return err
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotateInternal(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("写入审计日志失败: %w", err)
+	}
+	return nil
+}
+
+// Query 查询审计日志（按时间倒序）
+func (l *Logger) Query(q Query) ([]Entry, error) {
+	if q.Limit <= 0 {
+		q.Limit = defaultLimit
+	}
+
+	l.mu.Lock()
+	files, err := l.filesInternal()
+	l.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// 从最新的文件开始读取，文件内按写入顺序（时间升序）
+	result := make([]Entry, 0)
+	for i := len(files) - 1; i >= 0 && len(result) < q.Limit; i-- {
+		entries, err := readFile(files[i], &q)
+		if err != nil {
+			return nil, err
+		}
+		for j := len(entries) - 1; j >= 0 && len(result) < q.Limit; j-- {
+			result = append(result, entries[j])
+		}
+	}
+	return result, nil
+}
+
+// Close 关闭日志文件
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// openInternal 打开当前日志文件（不加锁）
+func (l *Logger) openInternal() error {
+	if l.file != nil {
+		return nil
+	}
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return fmt.Errorf("创建审计日志目录失败: %w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(l.dir, currentFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("打开审计日志失败: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("获取审计日志信息失败: %w", err)
+	}
+	l.file = f
+	l.size = info.Size()
+	return nil
+}
+
+// rotateInternal 归档当前文件并清理过旧的归档（不加锁）
+func (l *Logger) rotateInternal() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("关闭审计日志失败: %w", err)
+	}
+	l.file = nil
+
+	rotated := filepath.Join(l.dir, rotatedPrefix+time.Now().Format("20060102-150405.000000000")+rotatedSuffix)
+	if err := os.Rename(filepath.Join(l.dir, currentFileName), rotated); err != nil {
+		return fmt.Errorf("轮转审计日志失败: %w", err)
+	}
+
+	files, err := l.rotatedFilesInternal()
+	if err != nil {
+		return err
+	}
+	for len(files) > l.maxFiles {
+		if err := os.Remove(files[0]); err != nil {
+			slog.Warn("删除过期审计日志失败", "file", files[0], "error", err)
+		}
+		files = files[1:]
+	}
+
+	return l.openInternal()
+}
+
+// rotatedFilesInternal 返回按时间升序排列的归档文件（不加锁）
+func (l *Logger) rotatedFilesInternal() ([]string, error) {
+	entries, err := os.ReadDir(l.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取审计日志目录失败: %w", err)
+	}
+
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasPrefix(name, rotatedPrefix) && strings.HasSuffix(name, rotatedSuffix) {
+			files = append(files, filepath.Join(l.dir, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// filesInternal 返回所有日志文件（归档在前，当前文件在最后）（不加锁）
+func (l *Logger) filesInternal() ([]string, error) {
+	files, err := l.rotatedFilesInternal()
+	if err != nil {
+		return nil, err
+	}
+	current := filepath.Join(l.dir, currentFileName)
+	if _, err := os.Stat(current); err == nil {
+		files = append(files, current)
+	}
+	return files, nil
+}
+
+// readFile 读取单个日志文件中满足条件的条目
+func readFile(path string, q *Query) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil // 查询期间被轮转删除
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开审计日志失败: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue // 跳过损坏的行
+		}
+		if q.match(&e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取审计日志失败: %w", err)
+	}
+	return entries, nil
+}
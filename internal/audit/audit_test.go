@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLoggerRotation(t *testing.T) {
+	dir := t.TempDir()
+	// 上限 1 字节：每条日志写入前都会轮转非空的当前文件
+	l := NewLogger(dir, 1, 2)
+	defer l.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := l.Record(&Entry{Time: time.Now(), Actor: "alice", Path: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rotated, err := filepath.Glob(filepath.Join(dir, rotatedPrefix+"*"+rotatedSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 2 {
+		t.Fatalf("归档文件数 = %d, want 2", len(rotated))
+	}
+	if _, err := os.Stat(filepath.Join(dir, currentFileName)); err != nil {
+		t.Fatalf("当前日志文件不存在: %v", err)
+	}
+
+	// 最旧的两条随归档一起被清理，其余按时间倒序返回
+	entries, err := l.Query(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Path)
+	}
+	want := []string{"4", "3", "2"}
+	if len(got) != len(want) {
+		t.Fatalf("Query() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Query() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestLoggerRotationKeepsSmallFiles(t *testing.T) {
+	dir := t.TempDir()
+	l := NewLogger(dir, 1<<20, 2)
+	defer l.Close()
+
+	for i := 0; i < 5; i++ {
+		if err := l.Record(&Entry{Time: time.Now(), Actor: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rotated, err := filepath.Glob(filepath.Join(dir, rotatedPrefix+"*"+rotatedSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 0 {
+		t.Fatalf("未超出大小上限时不应轮转，归档文件数 = %d", len(rotated))
+	}
+}
+
+func TestLoggerQuery(t *testing.T) {
+	l := NewLogger(t.TempDir(), 0, 0)
+	defer l.Close()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []Entry{
+		{Time: base, Actor: "root", Tenant: "alice", SiteID: "blog"},
+		{Time: base.Add(time.Hour), Actor: "alice", Tenant: "alice", SiteID: "docs"},
+		{Time: base.Add(2 * time.Hour), Actor: "bob", Tenant: "bob", SiteID: "blog"},
+		{Time: base.Add(3 * time.Hour), Actor: "alice", Tenant: "alice", SiteID: "blog"},
+	}
+	for i := range records {
+		records[i].Path = strconv.Itoa(i)
+		if err := l.Record(&records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"无条件按时间倒序", Query{}, []string{"3", "2", "1", "0"}},
+		{"按租户", Query{Tenant: "alice"}, []string{"3", "1", "0"}},
+		{"按租户和站点", Query{Tenant: "alice", SiteID: "blog"}, []string{"3", "0"}},
+		{"按操作者", Query{Actor: "alice"}, []string{"3", "1"}},
+		{"起始时间（含）", Query{Since: base.Add(2 * time.Hour)}, []string{"3", "2"}},
+		{"截止时间（含）", Query{Until: base.Add(time.Hour)}, []string{"1", "0"}},
+		{"时间范围", Query{Since: base.Add(time.Hour), Until: base.Add(2 * time.Hour)}, []string{"2", "1"}},
+		{"条数限制", Query{Limit: 2}, []string{"3", "2"}},
+		{"无匹配", Query{Tenant: "carol"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := l.Query(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Fatalf("Query() 返回 %d 条, want %v", len(entries), tt.want)
+			}
+			for i, e := range entries {
+				if e.Path != tt.want[i] {
+					t.Errorf("entries[%d] = %s, want %s", i, e.Path, tt.want[i])
+				}
+			}
+		})
+	}
+}
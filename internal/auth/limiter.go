@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pages/pkg/api"
+)
+
+const (
+	defaultMaxFailuresPerUser = 5
+	defaultMaxFailuresPerIP   = 20
+	defaultLockoutBase        = 30 * time.Second
+	defaultLockoutMax         = 15 * time.Minute
+
+	// limiterIdleTTL 无失败记录超过该时长的条目会被清理
+	limiterIdleTTL = time.Hour
+	// limiterSweepInterval 清理间隔
+	limiterSweepInterval = time.Minute
+
+	ipKeyPrefix   = "ip:"
+	userKeyPrefix = "user:"
+)
+
+// LimiterConfig 登录限流配置，零值字段使用默认值
+type LimiterConfig struct {
+	MaxFailuresPerUser int           // 单个用户名允许的连续失败次数
+	MaxFailuresPerIP   int           // 单个 IP 允许的连续失败次数
+	LockoutBase        time.Duration // 首次锁定时长，之后每次失败翻倍
+	LockoutMax         time.Duration // 锁定时长上限
+}
+
+// attemptRecord 失败记录
+type attemptRecord struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// LimiterStats 登录限流统计（与 pkg/api 共用）
+type LimiterStats = api.LoginLimiterStats
+
+// Limiter 按 IP 与用户名统计认证失败，超过阈值后按指数退避临时锁定
+type Limiter struct {
+	config LimiterConfig
+
+	mu        sync.Mutex
+	records   map[string]*attemptRecord
+	lastSweep time.Time
+
+	failures atomic.Int64
+	lockouts atomic.Int64
+}
+
+// NewLimiter 创建登录限流器
+func NewLimiter(config LimiterConfig) *Limiter {
+	if config.MaxFailuresPerUser <= 0 {
+		config.MaxFailuresPerUser = defaultMaxFailuresPerUser
+	}
+	if config.MaxFailuresPerIP <= 0 {
+		config.MaxFailuresPerIP = defaultMaxFailuresPerIP
+	}
+	if config.LockoutBase <= 0 {
+		config.LockoutBase = defaultLockoutBase
+	}
+	if config.LockoutMax <= 0 {
+		config.LockoutMax = defaultLockoutMax
+	}
+	return &Limiter{
+		config:  config,
+		records: make(map[string]*attemptRecord),
+	}
+}
+
+// Check 返回 IP 或用户名仍需等待的锁定时长（0 表示允许尝试）
+func (l *Limiter) Check(ip, username string) time.Duration {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var wait time.Duration
+	for _, key := range limiterKeys(ip, username) {
+		if r, ok := l.records[key]; ok && now.Before(r.lockedUntil) {
+			wait = max(wait, r.lockedUntil.Sub(now))
+		}
+	}
+	return wait
+}
+
+// Failure 记录一次认证失败，返回触发锁定时的锁定时长
+func (l *Limiter) Failure(ip, username string) time.Duration {
+	now := time.Now()
+	l.failures.Add(1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweepInternal(now)
+
+	var wait time.Duration
+	for _, key := range limiterKeys(ip, username) {
+		r, ok := l.records[key]
+		if !ok {
+			r = &attemptRecord{}
+			l.records[key] = r
+		}
+		r.failures++
+		r.lastFailure = now
+
+		threshold := l.config.MaxFailuresPerIP
+		if strings.HasPrefix(key, userKeyPrefix) {
+			threshold = l.config.MaxFailuresPerUser
+		}
+		if r.failures < threshold {
+			continue
+		}
+
+		// 指数退避: base * 2^(failures-threshold)，上限 LockoutMax
+		lockout := l.config.LockoutBase
+		for i := threshold; i < r.failures && lockout < l.config.LockoutMax; i++ {
+			lockout *= 2
+		}
+		lockout = min(lockout, l.config.LockoutMax)
+		r.lockedUntil = now.Add(lockout)
+		wait = max(wait, lockout)
+
+		l.lockouts.Add(1)
+		slog.Warn("认证失败次数过多，已临时锁定",
+			"key", key,
+			"failures", r.failures,
+			"lockout", lockout,
+		)
+	}
+	return wait
+}
+
+// Success 认证成功后清除该用户名的失败记录
+// IP 的失败记录保留到自然过期，避免攻击者穿插使用自己的账户成功登录来重置 IP 计数
+func (l *Limiter) Success(ip, username string) {
+	if username == "" {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.records, userKeyPrefix+username)
+}
+
+// Stats 返回限流统计
+func (l *Limiter) Stats() LimiterStats {
+	now := time.Now()
+
+	l.mu.Lock()
+	locked := 0
+	for _, r := range l.records {
+		if now.Before(r.lockedUntil) {
+			locked++
+		}
+	}
+	l.mu.Unlock()
+
+	return LimiterStats{
+		Failures: l.failures.Load(),
+		Lockouts: l.lockouts.Load(),
+		Locked:   locked,
+	}
+}
+
+// sweepInternal 清理长时间无失败且未锁定的记录（不加锁）
+func (l *Limiter) sweepInternal(now time.Time) {
+	if now.Sub(l.lastSweep) < limiterSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, r := range l.records {
+		if now.After(r.lockedUntil) && now.Sub(r.lastFailure) > limiterIdleTTL {
+			delete(l.records, key)
+		}
+	}
+}
+
+// limiterKeys 返回 IP 与用户名对应的记录键
+func limiterKeys(ip, username string) []string {
+	keys := make([]string, 0, 2)
+	if ip != "" {
+		keys = append(keys, ipKeyPrefix+ip)
+	}
+	if username != "" {
+		keys = append(keys, userKeyPrefix+username)
+	}
+	return keys
+}
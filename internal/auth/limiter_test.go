@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterBackoff(t *testing.T) {
+	l := NewLimiter(LimiterConfig{
+		MaxFailuresPerUser: 3,
+		MaxFailuresPerIP:   100,
+		LockoutBase:        time.Minute,
+		LockoutMax:         5 * time.Minute,
+	})
+
+	// 达到阈值前不锁定，之后每次失败锁定时长翻倍，不超过上限
+	want := []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, w := range want {
+		if got := l.Failure("10.0.0.1", "alice"); got != w {
+			t.Errorf("第 %d 次失败锁定 %v, want %v", i+1, got, w)
+		}
+	}
+	if wait := l.Check("10.0.0.2", "alice"); wait <= 4*time.Minute || wait > 5*time.Minute {
+		t.Errorf("用户名被锁定时从其他 IP 尝试 Check = %v", wait)
+	}
+	if wait := l.Check("10.0.0.1", "bob"); wait != 0 {
+		t.Errorf("其他用户名 Check = %v, want 0", wait)
+	}
+
+	stats := l.Stats()
+	if stats.Failures != 7 || stats.Lockouts != 5 || stats.Locked != 1 {
+		t.Errorf("Stats = %+v", stats)
+	}
+}
+
+func TestLimiterIPLockout(t *testing.T) {
+	l := NewLimiter(LimiterConfig{MaxFailuresPerUser: 100, MaxFailuresPerIP: 2, LockoutBase: time.Minute})
+
+	// 同一 IP 轮换用户名也会被锁定
+	l.Failure("10.0.0.1", "a")
+	if got := l.Failure("10.0.0.1", "b"); got != time.Minute {
+		t.Fatalf("IP 锁定 %v, want 1m", got)
+	}
+	if l.Check("10.0.0.1", "c") == 0 {
+		t.Error("IP 锁定后应拒绝任何用户名")
+	}
+	if l.Check("10.0.0.2", "c") != 0 {
+		t.Error("其他 IP 不应被锁定")
+	}
+}
+
+func TestLimiterSuccessClearsOnlyUser(t *testing.T) {
+	l := NewLimiter(LimiterConfig{MaxFailuresPerUser: 2, MaxFailuresPerIP: 3, LockoutBase: time.Minute})
+
+	l.Failure("10.0.0.1", "alice")
+	l.Failure("10.0.0.1", "bob")
+	// 攻击者用自己的账户成功登录，只清除该用户名的记录，IP 计数保留
+	l.Success("10.0.0.1", "mallory")
+	l.Success("10.0.0.1", "alice")
+	if got := l.Failure("10.0.0.1", "alice"); got != time.Minute {
+		t.Fatalf("IP 第 3 次失败锁定 %v, want 1m", got)
+	}
+	if l.Check("", "alice") != 0 {
+		t.Error("Success 后用户名失败计数应从零开始")
+	}
+	if l.Check("10.0.0.1", "") == 0 {
+		t.Error("Success 不应清除 IP 的失败记录")
+	}
+}
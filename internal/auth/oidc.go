@@ -0,0 +1,565 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	_ "crypto/sha512" // SHA-384/512 签名算法
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	oidcHTTPTimeout    = 10 * time.Second
+	oidcPendingTTL     = 10 * time.Minute
+	oidcMaxPending     = 10000
+	oidcKeysMinRefresh = 30 * time.Second
+	oidcClockSkew      = time.Minute
+)
+
+// ErrOIDCNotAllowed 用户不属于任何已授权的用户组
+var ErrOIDCNotAllowed = errors.New("用户不属于任何已授权的用户组")
+
+// OIDCGroupMapping 身份提供方用户组到角色/租户的映射
+type OIDCGroupMapping struct {
+	Group  string
+	Role   string
+	Tenant string
+}
+
+// OIDCConfig OpenID Connect 单点登录配置
+type OIDCConfig struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string // 回调地址，为空时按请求地址推导
+	Scopes        []string
+	UsernameClaim string // 作为账户名的声明
+	GroupsClaim   string // 用户组声明
+	// GroupMappings 按顺序匹配，使用第一个命中的映射；未命中任何映射的用户拒绝登录
+	GroupMappings []OIDCGroupMapping
+}
+
+// OIDCProvider OpenID Connect 授权码流程客户端（PKCE + state + nonce）
+type OIDCProvider struct {
+	config OIDCConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	meta    *oidcMetadata
+	keys    map[string]crypto.PublicKey
+	keysAt  time.Time
+	pending map[string]*oidcPending
+}
+
+// oidcMetadata 身份提供方发现文档
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcPending 进行中的登录请求
+type oidcPending struct {
+	nonce       string
+	verifier    string
+	redirectURL string
+	expiresAt   time.Time
+}
+
+// NewOIDCProvider 校验配置并创建 OIDC 客户端（发现文档在首次登录时获取）
+func NewOIDCProvider(config OIDCConfig) (*OIDCProvider, error) {
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+	if config.Issuer == "" || config.ClientID == "" {
+		return nil, fmt.Errorf("issuer 和 client_id 不能为空")
+	}
+	if len(config.GroupMappings) == 0 {
+		return nil, fmt.Errorf("至少需要配置一个用户组映射")
+	}
+	for _, m := range config.GroupMappings {
+		u := &User{Username: m.Group, Role: m.Role, Tenant: m.Tenant}
+		if err := u.Validate(); err != nil {
+			return nil, fmt.Errorf("用户组映射 %q 无效: %w", m.Group, err)
+		}
+	}
+	if !slices.Contains(config.Scopes, "openid") {
+		config.Scopes = append([]string{"openid"}, config.Scopes...)
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = "preferred_username"
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+
+	return &OIDCProvider{
+		config:  config,
+		client:  &http.Client{Timeout: oidcHTTPTimeout},
+		pending: make(map[string]*oidcPending),
+	}, nil
+}
+
+// RedirectURL 返回配置的回调地址（未配置时为空）
+func (p *OIDCProvider) RedirectURL() string {
+	return p.config.RedirectURL
+}
+
+// AuthCodeURL 生成跳转到身份提供方的授权地址，返回地址与 state
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, redirectURL string) (string, string, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	for k, v := range p.pending {
+		if now.After(v.expiresAt) {
+			delete(p.pending, k)
+		}
+	}
+	if len(p.pending) >= oidcMaxPending {
+		p.mu.Unlock()
+		return "", "", fmt.Errorf("进行中的登录请求过多，请稍后重试")
+	}
+	p.pending[state] = &oidcPending{
+		nonce:       nonce,
+		verifier:    verifier,
+		redirectURL: redirectURL,
+		expiresAt:   now.Add(oidcPendingTTL),
+	}
+	p.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {redirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), state, nil
+}
+
+// Exchange 使用授权码换取并校验 ID Token，按用户组映射返回请求主体
+func (p *OIDCProvider) Exchange(ctx context.Context, state, code string) (*Principal, error) {
+	p.mu.Lock()
+	pending, ok := p.pending[state]
+	delete(p.pending, state)
+	p.mu.Unlock()
+	if !ok || time.Now().After(pending.expiresAt) {
+		return nil, fmt.Errorf("登录请求无效或已过期")
+	}
+
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {pending.redirectURL},
+		"code_verifier": {pending.verifier},
+		"client_id":     {p.config.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 Token 端点失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("解析 Token 响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("换取 Token 失败: %s %s", token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("Token 响应中缺少 id_token")
+	}
+
+	claims, err := p.verifyIDToken(ctx, meta, token.IDToken, pending.nonce)
+	if err != nil {
+		return nil, err
+	}
+	return p.principal(claims)
+}
+
+// principal 根据 ID Token 声明和用户组映射构建请求主体
+func (p *OIDCProvider) principal(claims map[string]any) (*Principal, error) {
+	username, _ := claims[p.config.UsernameClaim].(string)
+	if username == "" {
+		return nil, fmt.Errorf("ID Token 缺少声明 %s", p.config.UsernameClaim)
+	}
+
+	var groups []string
+	switch v := claims[p.config.GroupsClaim].(type) {
+	case string:
+		groups = []string{v}
+	case []any:
+		for _, g := range v {
+			if s, ok := g.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	}
+
+	for _, m := range p.config.GroupMappings {
+		if slices.Contains(groups, m.Group) {
+			return &Principal{
+				Username: username,
+				Role:     m.Role,
+				Tenant:   m.Tenant,
+				Method:   MethodOIDC,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrOIDCNotAllowed, username)
+}
+
+// verifyIDToken 校验 ID Token 签名及 iss/aud/exp/nonce，返回声明
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, meta *oidcMetadata, raw, nonce string) (map[string]any, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("ID Token 格式错误")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("ID Token 头部无效: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("ID Token 签名无效: %w", err)
+	}
+
+	key, err := p.publicKey(ctx, meta, header.Kid, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		// 身份提供方可能复用 kid 轮换密钥，刷新 JWKS 后重试一次
+		if key, err = p.publicKey(ctx, meta, header.Kid, true); err != nil {
+			return nil, err
+		}
+		if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+			return nil, err
+		}
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("ID Token 声明无效: %w", err)
+	}
+
+	if iss, _ := claims["iss"].(string); iss != meta.Issuer {
+		return nil, fmt.Errorf("ID Token 签发方不匹配: %s", iss)
+	}
+
+	var audiences []string
+	switch v := claims["aud"].(type) {
+	case string:
+		audiences = []string{v}
+	case []any:
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				audiences = append(audiences, s)
+			}
+		}
+	}
+	if !slices.Contains(audiences, p.config.ClientID) {
+		return nil, fmt.Errorf("ID Token 受众不匹配")
+	}
+	if azp, ok := claims["azp"].(string); ok && len(audiences) > 1 && azp != p.config.ClientID {
+		return nil, fmt.Errorf("ID Token 授权方不匹配")
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Now().Add(-oidcClockSkew).After(time.Unix(int64(exp), 0)) {
+		return nil, fmt.Errorf("ID Token 已过期")
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, fmt.Errorf("ID Token nonce 不匹配")
+	}
+	return claims, nil
+}
+
+// metadata 获取并缓存发现文档
+func (p *OIDCProvider) metadata(ctx context.Context) (*oidcMetadata, error) {
+	p.mu.Lock()
+	meta := p.meta
+	p.mu.Unlock()
+	if meta != nil {
+		return meta, nil
+	}
+
+	meta = &oidcMetadata{}
+	if err := p.getJSON(ctx, p.config.Issuer+"/.well-known/openid-configuration", meta); err != nil {
+		return nil, fmt.Errorf("获取 OIDC 发现文档失败: %w", err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != p.config.Issuer {
+		return nil, fmt.Errorf("发现文档 issuer 不匹配: %s", meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("发现文档缺少必要端点")
+	}
+
+	p.mu.Lock()
+	p.meta = meta
+	p.mu.Unlock()
+	return meta, nil
+}
+
+// publicKey 按 kid 查找签名公钥，未命中或 refresh 时刷新 JWKS（限制刷新频率）
+func (p *OIDCProvider) publicKey(ctx context.Context, meta *oidcMetadata, kid string, refresh bool) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	key := lookupKey(p.keys, kid)
+	stale := time.Since(p.keysAt) >= oidcKeysMinRefresh
+	p.mu.Unlock()
+	if key != nil && !refresh {
+		return key, nil
+	}
+	if !stale {
+		if key != nil {
+			return nil, fmt.Errorf("ID Token 签名校验失败")
+		}
+		return nil, fmt.Errorf("未找到签名公钥: %s", kid)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("获取 JWKS 失败: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if pub, err := k.publicKey(); err == nil {
+			keys[k.Kid] = pub
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysAt = time.Now()
+	p.mu.Unlock()
+
+	if key = lookupKey(keys, kid); key == nil {
+		return nil, fmt.Errorf("未找到签名公钥: %s", kid)
+	}
+	return key, nil
+}
+
+// lookupKey 按 kid 查找公钥；ID Token 未指定 kid 且仅有一个公钥时使用该公钥
+func lookupKey(keys map[string]crypto.PublicKey, kid string) crypto.PublicKey {
+	if key, ok := keys[kid]; ok {
+		return key
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// getJSON 获取并解析 JSON 文档
+func (p *OIDCProvider) getJSON(ctx context.Context, u string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// jsonWebKey JWKS 中的单个公钥
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey 解析 RSA 或 EC 公钥
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("RSA 指数无效")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("不支持的曲线: %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("不支持的密钥类型: %s", k.Kty)
+}
+
+// verifySignature 校验 JWS 签名，仅支持 RS/PS/ES 系列非对称算法
+func verifySignature(alg string, key crypto.PublicKey, input string, sig []byte) error {
+	var hash crypto.Hash
+	switch alg[min(2, len(alg)):] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("不支持的签名算法: %s", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(input))
+	digest := h.Sum(nil)
+
+	invalid := fmt.Errorf("ID Token 签名校验失败")
+	switch {
+	case strings.HasPrefix(alg, "RS"), strings.HasPrefix(alg, "PS"):
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return invalid
+		}
+		var err error
+		if alg[0] == 'R' {
+			err = rsa.VerifyPKCS1v15(pub, hash, digest, sig)
+		} else {
+			err = rsa.VerifyPSS(pub, hash, digest, sig, nil)
+		}
+		if err != nil {
+			return invalid
+		}
+		return nil
+	case strings.HasPrefix(alg, "ES"):
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return invalid
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return invalid
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return invalid
+		}
+		return nil
+	}
+	return fmt.Errorf("不支持的签名算法: %s", alg)
+}
+
+// decodeSegment 解码 JWT 的 base64url JSON 段
+func decodeSegment(seg string, out any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// decodeBigInt 解码 base64url 大整数
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("密钥参数无效")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSessionIdle     = 30 * time.Minute
+	defaultSessionAbsolute = 12 * time.Hour
+	sessionSweepInterval   = time.Minute
+)
+
+// Session 服务端会话
+type Session struct {
+	ID       string
+	Username string
+	Method   string // 登录方式：MethodSession（账户密码）或 MethodOIDC（单点登录）
+	// Role/Tenant 为单点登录时由用户组映射得出的权限；账户密码会话每次请求从账户存储重新读取
+	Role      string
+	Tenant    string
+	CSRFToken string
+	CreatedAt time.Time
+	LastSeen  time.Time
+	ExpiresAt time.Time // 绝对过期时间
+}
+
+// CheckCSRF 校验 CSRF Token（常量时间比较）
+func (s *Session) CheckCSRF(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(s.CSRFToken), []byte(token)) == 1
+}
+
+// SessionStore 内存会话存储（重启后所有会话失效）
+type SessionStore struct {
+	idle     time.Duration
+	absolute time.Duration
+
+	mu        sync.Mutex
+	sessions  map[string]*Session
+	lastSweep time.Time
+}
+
+// NewSessionStore 创建会话存储，idle/absolute 为 0 时使用默认值
+func NewSessionStore(idle, absolute time.Duration) *SessionStore {
+	if idle <= 0 {
+		idle = defaultSessionIdle
+	}
+	if absolute <= 0 {
+		absolute = defaultSessionAbsolute
+	}
+	return &SessionStore{
+		idle:     idle,
+		absolute: absolute,
+		sessions: make(map[string]*Session),
+	}
+}
+
+// Principal 由单点登录会话构建请求主体
+func (s *Session) Principal() *Principal {
+	return &Principal{
+		Username: s.Username,
+		Role:     s.Role,
+		Tenant:   s.Tenant,
+		Method:   s.Method,
+	}
+}
+
+// Create 为已认证主体创建新会话
+func (s *SessionStore) Create(p *Principal) (*Session, error) {
+	id, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	csrf, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sess := &Session{
+		ID:        id,
+		Username:  p.Username,
+		Method:    p.Method,
+		Role:      p.Role,
+		Tenant:    p.Tenant,
+		CSRFToken: csrf,
+		CreatedAt: now,
+		LastSeen:  now,
+		ExpiresAt: now.Add(s.absolute),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweepInternal(now)
+	s.sessions[id] = sess
+	clone := *sess
+	return &clone, nil
+}
+
+// Touch 获取有效会话并刷新空闲计时；会话不存在、空闲超时或超过绝对有效期时返回 nil
+func (s *SessionStore) Touch(id string) *Session {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil
+	}
+	if s.expired(sess, now) {
+		delete(s.sessions, id)
+		return nil
+	}
+	sess.LastSeen = now
+	clone := *sess
+	return &clone
+}
+
+// Delete 删除会话
+func (s *SessionStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// DeleteForUser 删除账户的所有会话（账户被删除或修改密码时调用）
+func (s *SessionStore) DeleteForUser(username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, sess := range s.sessions {
+		if sess.Username == username {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// DeleteForTenant 删除绑定到租户的所有会话（租户被删除时调用，包括单点登录会话）
+func (s *SessionStore) DeleteForTenant(tenant string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, sess := range s.sessions {
+		if sess.Tenant == tenant {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// MaxAge 返回会话的绝对有效期
+func (s *SessionStore) MaxAge() time.Duration {
+	return s.absolute
+}
+
+// expired 判断会话是否过期
+func (s *SessionStore) expired(sess *Session, now time.Time) bool {
+	return !now.Before(sess.ExpiresAt) || now.Sub(sess.LastSeen) >= s.idle
+}
+
+// sweepInternal 清理过期会话（不加锁）
+func (s *SessionStore) sweepInternal(now time.Time) {
+	if now.Sub(s.lastSweep) < sessionSweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for id, sess := range s.sessions {
+		if s.expired(sess, now) {
+			delete(s.sessions, id)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash 用于账户不存在时执行一次等价的 bcrypt 比较，避免通过响应时间枚举用户名
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("pages-dummy-password"), bcrypt.DefaultCost)
+
+// UserStore 基于文件的管理员账户存储
+type UserStore struct {
+	path  string
+	mu    sync.RWMutex
+	users map[string]*User
+}
+
+// NewUserStore 创建账户存储（<dataDir>/users.json）
+func NewUserStore(dataDir string) *UserStore {
+	return &UserStore{
+		path:  filepath.Join(dataDir, "users.json"),
+		users: make(map[string]*User),
+	}
+}
+
+// Load 从文件加载账户
+func (s *UserStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := make(map[string]*User)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		s.users = users
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取账户文件失败: %w", err)
+	}
+
+	var list []*User
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("解析账户文件失败: %w", err)
+	}
+	for _, u := range list {
+		users[u.Username] = u
+	}
+	s.users = users
+	return nil
+}
+
+// Bootstrap 账户为空时创建初始超级管理员
+func (s *UserStore) Bootstrap(username, password string) (bool, error) {
+	if s.Count() > 0 {
+		return false, nil
+	}
+	u, err := NewUser(username, password, RoleSuperAdmin, "")
+	if err != nil {
+		return false, err
+	}
+	if err := s.Add(u); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Authenticate 校验用户名和密码
+func (s *UserStore) Authenticate(username, password string) (*User, bool) {
+	s.mu.RLock()
+	u, ok := s.users[username]
+	s.mu.RUnlock()
+
+	if !ok {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, false
+	}
+	if !u.CheckPassword(password) {
+		return nil, false
+	}
+	clone := *u
+	return &clone, true
+}
+
+// Get 获取账户
+func (s *UserStore) Get(username string) *User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[username]
+	if !ok {
+		return nil
+	}
+	clone := *u
+	return &clone
+}
+
+// List 列出所有账户（按用户名排序）
+func (s *UserStore) List() []*User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*User, 0, len(s.users))
+	for _, u := range s.users {
+		clone := *u
+		list = append(list, &clone)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Username < list[j].Username })
+	return list
+}
+
+// Count 返回账户数量
+func (s *UserStore) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.users)
+}
+
+// Add 添加账户
+func (s *UserStore) Add(u *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[u.Username]; exists {
+		return fmt.Errorf("账户 %s 已存在", u.Username)
+	}
+	s.users[u.Username] = u
+	if err := s.saveInternal(); err != nil {
+		delete(s.users, u.Username)
+		return err
+	}
+	return nil
+}
+
+// Update 更新账户
+func (s *UserStore) Update(u *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, exists := s.users[u.Username]
+	if !exists {
+		return fmt.Errorf("账户 %s 不存在", u.Username)
+	}
+	if old.Role == RoleSuperAdmin && u.Role != RoleSuperAdmin && s.countRoleInternal(RoleSuperAdmin) == 1 {
+		return fmt.Errorf("不能降级最后一个超级管理员")
+	}
+	s.users[u.Username] = u
+	if err := s.saveInternal(); err != nil {
+		s.users[u.Username] = old
+		return err
+	}
+	return nil
+}
+
+// Remove 删除账户
+func (s *UserStore) Remove(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, exists := s.users[username]
+	if !exists {
+		return fmt.Errorf("账户 %s 不存在", username)
+	}
+	if old.Role == RoleSuperAdmin && s.countRoleInternal(RoleSuperAdmin) == 1 {
+		return fmt.Errorf("不能删除最后一个超级管理员")
+	}
+	delete(s.users, username)
+	if err := s.saveInternal(); err != nil {
+		s.users[username] = old
+		return err
+	}
+	return nil
+}
+
+// countRoleInternal 统计指定角色的账户数量（不加锁）
+func (s *UserStore) countRoleInternal(role string) int {
+	n := 0
+	for _, u := range s.users {
+		if u.Role == role {
+			n++
+		}
+	}
+	return n
+}
+
+// saveInternal 内部保存方法（不加锁）
+func (s *UserStore) saveInternal() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	list := make([]*User, 0, len(s.users))
+	for _, u := range s.users {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Username < list[j].Username })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化账户失败: %w", err)
+	}
+
+	// 账户文件包含密码哈希，仅允许属主读写
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("写入账户文件失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pages/pkg/api"
+)
+
+const (
+	// tokenPrefix 明文 Token 前缀
+	tokenPrefix = "pages_"
+	// lastUsedPersistInterval 最后使用时间的落盘间隔，避免每次请求都写文件
+	lastUsedPersistInterval = time.Minute
+)
+
+// validScopes 支持的权限范围
+var validScopes = []string{
+	api.ScopeSiteRead,
+	api.ScopeSiteDeploy,
+	api.ScopeCheckpointWrite,
+	api.ScopeAnalyticsRead,
+}
+
+// Token API Token（仅保存哈希）
+type Token struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Hash       string     `json:"hash"`   // 明文 Token 的 SHA-256
+	Prefix     string     `json:"prefix"` // 明文前缀
+	Tenant     string     `json:"tenant"`
+	SiteID     string     `json:"site_id,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// NewToken 生成新的 Token，返回 Token 记录与明文（明文仅此一次可见）
+func NewToken(name, tenant, siteID string, scopes []string, expiresAt *time.Time, createdBy string) (*Token, string, error) {
+	if tenant == "" {
+		return nil, "", fmt.Errorf("Token 必须绑定租户")
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("至少需要一个权限范围")
+	}
+	for _, s := range scopes {
+		if !slices.Contains(validScopes, s) {
+			return nil, "", fmt.Errorf("未知权限范围: %s", s)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("过期时间必须晚于当前时间")
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+	plain := tokenPrefix + secret
+
+	t := &Token{
+		ID:        id,
+		Name:      name,
+		Hash:      hashToken(plain),
+		Prefix:    plain[:len(tokenPrefix)+8],
+		Tenant:    tenant,
+		SiteID:    siteID,
+		Scopes:    slices.Clone(scopes),
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	return t, plain, nil
+}
+
+// Expired 判断 Token 是否已过期
+func (t *Token) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// Info 返回不含哈希的 Token 视图
+func (t *Token) Info() api.APIToken {
+	return api.APIToken{
+		ID:         t.ID,
+		Name:       t.Name,
+		Prefix:     t.Prefix,
+		Tenant:     t.Tenant,
+		SiteID:     t.SiteID,
+		Scopes:     slices.Clone(t.Scopes),
+		CreatedBy:  t.CreatedBy,
+		CreatedAt:  t.CreatedAt,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+	}
+}
+
+// TokenStore 基于文件的 Token 存储
+type TokenStore struct {
+	path      string
+	mu        sync.RWMutex
+	tokens    map[string]*Token // id -> token
+	persisted map[string]time.Time
+}
+
+// NewTokenStore 创建 Token 存储（<dataDir>/tokens.json）
+func NewTokenStore(dataDir string) *TokenStore {
+	return &TokenStore{
+		path:      filepath.Join(dataDir, "tokens.json"),
+		tokens:    make(map[string]*Token),
+		persisted: make(map[string]time.Time),
+	}
+}
+
+// Load 从文件加载 Token
+func (s *TokenStore) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens := make(map[string]*Token)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		s.tokens = tokens
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取 Token 文件失败: %w", err)
+	}
+
+	var list []*Token
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("解析 Token 文件失败: %w", err)
+	}
+	for _, t := range list {
+		tokens[t.ID] = t
+	}
+	s.tokens = tokens
+	return nil
+}
+
+// Authenticate 校验明文 Token，成功时更新最后使用时间
+func (s *TokenStore) Authenticate(plain string) (*Token, bool) {
+	if !strings.HasPrefix(plain, tokenPrefix) {
+		return nil, false
+	}
+	hash := hashToken(plain)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found *Token
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) == 1 {
+			found = t
+		}
+	}
+	if found == nil || found.Expired(now) {
+		return nil, false
+	}
+
+	found.LastUsedAt = &now
+	if now.Sub(s.persisted[found.ID]) >= lastUsedPersistInterval {
+		s.persisted[found.ID] = now
+		_ = s.saveInternal() // 最后使用时间落盘失败不影响认证
+	}
+
+	clone := *found
+	return &clone, true
+}
+
+// ListForTenant 列出租户的所有 Token（按创建时间倒序）
+func (s *TokenStore) ListForTenant(tenant string) []*Token {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*Token, 0)
+	for _, t := range s.tokens {
+		if t.Tenant == tenant {
+			clone := *t
+			list = append(list, &clone)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// Add 添加 Token
+func (s *TokenStore) Add(t *Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tokens[t.ID]; exists {
+		return fmt.Errorf("Token %s 已存在", t.ID)
+	}
+	s.tokens[t.ID] = t
+	if err := s.saveInternal(); err != nil {
+		delete(s.tokens, t.ID)
+		return err
+	}
+	return nil
+}
+
+// Revoke 吊销租户的 Token
+func (s *TokenStore) Revoke(tenant, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, exists := s.tokens[id]
+	if !exists || t.Tenant != tenant {
+		return fmt.Errorf("%s: %s", api.MsgTokenNotFound, id)
+	}
+	delete(s.tokens, id)
+	delete(s.persisted, id)
+	if err := s.saveInternal(); err != nil {
+		s.tokens[id] = t
+		return err
+	}
+	return nil
+}
+
+// RevokeForTenant 吊销租户的所有 Token，返回吊销数量
+func (s *TokenStore) RevokeForTenant(tenant string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := make(map[string]*Token)
+	for id, t := range s.tokens {
+		if t.Tenant == tenant {
+			removed[id] = t
+			delete(s.tokens, id)
+			delete(s.persisted, id)
+		}
+	}
+	if len(removed) == 0 {
+		return 0, nil
+	}
+	if err := s.saveInternal(); err != nil {
+		for id, t := range removed {
+			s.tokens[id] = t
+		}
+		return 0, err
+	}
+	return len(removed), nil
+}
+
+// saveInternal 内部保存方法（不加锁）
+func (s *TokenStore) saveInternal() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	list := make([]*Token, 0, len(s.tokens))
+	for _, t := range s.tokens {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 Token 失败: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("写入 Token 文件失败: %w", err)
+	}
+	return nil
+}
+
+// hashToken 计算明文 Token 的 SHA-256（Token 为高熵随机值，无需慢哈希）
+func hashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex 生成 n 字节随机数的十六进制表示
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pages/pkg/api"
+)
+
+func TestNewToken(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name    string
+		tenant  string
+		scopes  []string
+		expires *time.Time
+		wantErr string
+	}{
+		{name: "有效", tenant: "alice", scopes: []string{api.ScopeSiteRead}, expires: &future},
+		{name: "未绑定租户", scopes: []string{api.ScopeSiteRead}, wantErr: "必须绑定租户"},
+		{name: "没有权限范围", tenant: "alice", wantErr: "至少需要一个权限范围"},
+		{name: "未知权限范围", tenant: "alice", scopes: []string{"admin"}, wantErr: "未知权限范围"},
+		{name: "过期时间已过", tenant: "alice", scopes: []string{api.ScopeSiteRead}, expires: &past, wantErr: "过期时间"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok, plain, err := NewToken("ci", tt.tenant, "", tt.scopes, tt.expires, "admin")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(plain, tokenPrefix) || !strings.HasPrefix(plain, tok.Prefix) {
+				t.Errorf("明文 %q 与前缀 %q 不符", plain, tok.Prefix)
+			}
+			if tok.Hash != hashToken(plain) || strings.Contains(tok.Hash, plain) {
+				t.Error("Token 应只保存明文的哈希")
+			}
+		})
+	}
+}
+
+// addToken 创建并保存 Token，返回明文
+func addToken(t *testing.T, s *TokenStore, tenant, siteID string, scopes ...string) (*Token, string) {
+	t.Helper()
+	tok, plain, err := NewToken("ci", tenant, siteID, scopes, nil, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(tok); err != nil {
+		t.Fatal(err)
+	}
+	return tok, plain
+}
+
+func TestTokenStoreAuthenticate(t *testing.T) {
+	dir := t.TempDir()
+	s := NewTokenStore(dir)
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	tok, plain := addToken(t, s, "alice", "blog", api.ScopeSiteDeploy)
+	expired, expiredPlain := addToken(t, s, "alice", "", api.ScopeSiteRead)
+	past := time.Now().Add(-time.Second)
+	expired.ExpiresAt = &past
+	revoked, revokedPlain := addToken(t, s, "alice", "", api.ScopeSiteRead)
+	if err := s.Revoke("alice", revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		plain string
+		want  bool
+	}{
+		{"有效", plain, true},
+		{"缺少前缀", strings.TrimPrefix(plain, tokenPrefix), false},
+		{"明文被篡改", plain[:len(plain)-1] + "0", false},
+		{"以哈希代替明文", tok.Hash, false},
+		{"已过期", expiredPlain, false},
+		{"已吊销", revokedPlain, false},
+		{"空", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := s.Authenticate(tt.plain)
+			if ok != tt.want {
+				t.Fatalf("Authenticate = %v, want %v", ok, tt.want)
+			}
+			if ok && (got.ID != tok.ID || got.SiteID != "blog" || got.LastUsedAt == nil) {
+				t.Errorf("Token = %+v", got)
+			}
+		})
+	}
+
+	// 文件中只有哈希；重新加载后仍可认证，已吊销的 Token 不会恢复
+	data, err := os.ReadFile(filepath.Join(dir, "tokens.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), plain) {
+		t.Error("Token 文件中不应包含明文")
+	}
+	reloaded := NewTokenStore(dir)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reloaded.Authenticate(plain); !ok {
+		t.Error("重新加载后有效 Token 认证失败")
+	}
+	if _, ok := reloaded.Authenticate(revokedPlain); ok {
+		t.Error("重新加载后已吊销的 Token 仍可认证")
+	}
+}
+
+func TestTokenStoreRevoke(t *testing.T) {
+	s := NewTokenStore(t.TempDir())
+	a, aPlain := addToken(t, s, "alice", "", api.ScopeSiteRead)
+	_, a2Plain := addToken(t, s, "alice", "", api.ScopeSiteRead)
+	_, bPlain := addToken(t, s, "bob", "", api.ScopeSiteRead)
+
+	// 不能吊销其他租户的 Token
+	if err := s.Revoke("bob", a.ID); err == nil || !strings.Contains(err.Error(), api.MsgTokenNotFound) {
+		t.Errorf("Revoke 其他租户的 Token err = %v", err)
+	}
+	if _, ok := s.Authenticate(aPlain); !ok {
+		t.Error("被其他租户吊销失败的 Token 应仍然有效")
+	}
+
+	if n, err := s.RevokeForTenant("alice"); err != nil || n != 2 {
+		t.Fatalf("RevokeForTenant = %d, %v, want 2", n, err)
+	}
+	for _, plain := range []string{aPlain, a2Plain} {
+		if _, ok := s.Authenticate(plain); ok {
+			t.Error("租户的 Token 吊销后仍可认证")
+		}
+	}
+	if _, ok := s.Authenticate(bPlain); !ok {
+		t.Error("其他租户的 Token 不应被吊销")
+	}
+}
+
+func TestPrincipalAllowsScope(t *testing.T) {
+	tenantToken := PrincipalFromToken(&Token{ID: "t1", Tenant: "alice", Scopes: []string{api.ScopeSiteRead, api.ScopeSiteDeploy}})
+	siteToken := PrincipalFromToken(&Token{ID: "t2", Tenant: "alice", SiteID: "blog", Scopes: []string{api.ScopeSiteDeploy}})
+
+	tests := []struct {
+		name   string
+		p      *Principal
+		scope  string
+		tenant string
+		siteID string
+		want   bool
+	}{
+		{"租户 Token 访问任意站点", tenantToken, api.ScopeSiteDeploy, "alice", "docs", true},
+		{"租户 Token 访问租户级路由", tenantToken, api.ScopeSiteRead, "alice", "", true},
+		{"其他租户", tenantToken, api.ScopeSiteRead, "bob", "blog", false},
+		{"非租户路由", tenantToken, api.ScopeSiteRead, "", "", false},
+		{"缺少权限范围", tenantToken, api.ScopeCheckpointWrite, "alice", "blog", false},
+		{"路由不允许 Token 访问", tenantToken, "", "alice", "blog", false},
+		{"站点 Token 访问绑定站点", siteToken, api.ScopeSiteDeploy, "alice", "blog", true},
+		{"站点 Token 访问其他站点", siteToken, api.ScopeSiteDeploy, "alice", "docs", false},
+		{"站点 Token 访问租户级路由", siteToken, api.ScopeSiteDeploy, "alice", "", false},
+		{"站点 Token 缺少权限范围", siteToken, api.ScopeSiteRead, "alice", "blog", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.AllowsScope(tt.scope, tt.tenant, tt.siteID); got != tt.want {
+				t.Errorf("AllowsScope(%q, %q, %q) = %v, want %v", tt.scope, tt.tenant, tt.siteID, got, tt.want)
+			}
+		})
+	}
+}
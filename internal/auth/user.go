@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"pages/pkg/api"
+)
+
+// 管理员角色
+const (
+	RoleSuperAdmin  = "superadmin"   // 超级管理员：可访问所有接口
+	RoleTenantAdmin = "tenant_admin" // 租户管理员：仅可访问所属租户的 /users/:username/... 接口
+	RoleReadOnly    = "readonly"     // 只读：仅可执行 GET 请求（可选限定租户）
+)
+
+// 认证方式
+const (
+	MethodBasic   = "basic"
+	MethodToken   = "token"
+	MethodSession = "session"
+	MethodOIDC    = "oidc"
+)
+
+// User 管理员账户
+type User struct {
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"`    // bcrypt 哈希
+	Role         string    `json:"role"`             // 角色
+	Tenant       string    `json:"tenant,omitempty"` // 绑定的租户（tenant_admin 必填，readonly 可选）
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// NewUser 创建管理员账户（密码以 bcrypt 哈希保存）
+func NewUser(username, password, role, tenant string) (*User, error) {
+	u := &User{
+		Username: username,
+		Role:     role,
+		Tenant:   tenant,
+	}
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	if err := u.SetPassword(password); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	u.CreatedAt = now
+	u.UpdatedAt = now
+	return u, nil
+}
+
+// Validate 校验账户字段
+func (u *User) Validate() error {
+	if u.Username == "" {
+		return fmt.Errorf("用户名不能为空")
+	}
+	switch u.Role {
+	case RoleSuperAdmin:
+		if u.Tenant != "" {
+			return fmt.Errorf("超级管理员不能绑定租户")
+		}
+	case RoleTenantAdmin:
+		if u.Tenant == "" {
+			return fmt.Errorf("租户管理员必须绑定租户")
+		}
+	case RoleReadOnly:
+	default:
+		return fmt.Errorf("未知角色: %s", u.Role)
+	}
+	return nil
+}
+
+// SetPassword 设置密码（bcrypt 哈希）
+func (u *User) SetPassword(password string) error {
+	if password == "" {
+		return fmt.Errorf("密码不能为空")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("密码哈希失败: %w", err)
+	}
+	u.PasswordHash = string(hash)
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// CheckPassword 校验密码（bcrypt 比较为常量时间）
+func (u *User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
+
+// Account 返回不含密码哈希的账户视图
+func (u *User) Account() api.Account {
+	return api.Account{
+		Username:  u.Username,
+		Role:      u.Role,
+		Tenant:    u.Tenant,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
+}
+
+// Principal 已认证的请求主体
+type Principal struct {
+	Username string   // 账户名（Token 认证时为 Token 创建者）
+	Role     string   // 角色（Token 认证时为空）
+	Tenant   string   // 绑定租户
+	Method   string   // 认证方式
+	TokenID  string   // Token ID（仅 Token 认证）
+	SiteID   string   // Token 绑定的站点（仅 Token 认证，可为空）
+	Scopes   []string // Token 权限范围（仅 Token 认证）
+}
+
+// PrincipalFromUser 由账户构建请求主体
+func PrincipalFromUser(u *User, method string) *Principal {
+	return &Principal{
+		Username: u.Username,
+		Role:     u.Role,
+		Tenant:   u.Tenant,
+		Method:   method,
+	}
+}
+
+// PrincipalFromToken 由 API Token 构建请求主体
+func PrincipalFromToken(t *Token) *Principal {
+	return &Principal{
+		Username: t.CreatedBy,
+		Tenant:   t.Tenant,
+		Method:   MethodToken,
+		TokenID:  t.ID,
+		SiteID:   t.SiteID,
+		Scopes:   slices.Clone(t.Scopes),
+	}
+}
+
+// AllowsScope 判断 Token 主体是否可以访问需要 scope 权限的租户路由
+// scope 为空表示该路由不允许 Token 访问；绑定站点的 Token 仅可访问带该站点 :id 的路由
+func (p *Principal) AllowsScope(scope, tenant, siteID string) bool {
+	if scope == "" || !slices.Contains(p.Scopes, scope) {
+		return false
+	}
+	if tenant == "" || tenant != p.Tenant {
+		return false
+	}
+	if p.SiteID != "" && siteID != p.SiteID {
+		return false
+	}
+	return true
+}
+
+// Allows 判断主体是否可以访问指定请求
+// tenant 为路由中的 :username 参数（非租户路由为空）
+func (p *Principal) Allows(method, tenant string) bool {
+	readOnly := method == http.MethodGet || method == http.MethodHead
+
+	switch p.Role {
+	case RoleSuperAdmin:
+		return true
+	case RoleTenantAdmin:
+		return tenant != "" && tenant == p.Tenant
+	case RoleReadOnly:
+		if !readOnly {
+			return false
+		}
+		if p.Tenant == "" {
+			return true
+		}
+		return tenant != "" && tenant == p.Tenant
+	}
+	return false
+}
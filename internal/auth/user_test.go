@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPrincipalAllows(t *testing.T) {
+	super := &Principal{Username: "root", Role: RoleSuperAdmin}
+	alice := &Principal{Username: "a", Role: RoleTenantAdmin, Tenant: "alice"}
+	reader := &Principal{Username: "r", Role: RoleReadOnly}
+	aliceReader := &Principal{Username: "ar", Role: RoleReadOnly, Tenant: "alice"}
+	unknown := &Principal{Username: "x", Role: "owner", Tenant: "alice"}
+
+	tests := []struct {
+		name   string
+		p      *Principal
+		method string
+		tenant string
+		want   bool
+	}{
+		{"超级管理员访问租户路由", super, http.MethodDelete, "alice", true},
+		{"超级管理员访问全局路由", super, http.MethodPost, "", true},
+		{"租户管理员访问所属租户", alice, http.MethodPost, "alice", true},
+		{"租户管理员访问其他租户", alice, http.MethodGet, "bob", false},
+		{"租户管理员访问全局路由", alice, http.MethodGet, "", false},
+		{"全局只读 GET", reader, http.MethodGet, "bob", true},
+		{"全局只读 HEAD 全局路由", reader, http.MethodHead, "", true},
+		{"全局只读变更请求", reader, http.MethodPost, "bob", false},
+		{"租户只读访问所属租户", aliceReader, http.MethodGet, "alice", true},
+		{"租户只读访问其他租户", aliceReader, http.MethodGet, "bob", false},
+		{"租户只读访问全局路由", aliceReader, http.MethodGet, "", false},
+		{"租户只读变更请求", aliceReader, http.MethodPut, "alice", false},
+		{"未知角色", unknown, http.MethodGet, "alice", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.Allows(tt.method, tt.tenant); got != tt.want {
+				t.Errorf("Allows(%s, %q) = %v, want %v", tt.method, tt.tenant, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    User
+		wantErr bool
+	}{
+		{"超级管理员", User{Username: "root", Role: RoleSuperAdmin}, false},
+		{"超级管理员不能绑定租户", User{Username: "root", Role: RoleSuperAdmin, Tenant: "alice"}, true},
+		{"租户管理员必须绑定租户", User{Username: "a", Role: RoleTenantAdmin}, true},
+		{"全局只读", User{Username: "r", Role: RoleReadOnly}, false},
+		{"未知角色", User{Username: "x", Role: "owner"}, true},
+		{"用户名为空", User{Role: RoleSuperAdmin}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.user.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
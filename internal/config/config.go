@@ -11,7 +11,14 @@ import (
 
 // Config 服务器配置（不包含站点数据）
 type Config struct {
-	Server ServerConfig `toml:"server"`
+	Server      ServerConfig      `toml:"server"`
+	Audit       AuditConfig       `toml:"audit"`
+	Deploy      DeployConfig      `toml:"deploy"`
+	Jobs        JobsConfig        `toml:"jobs"`
+	Trash       TrashConfig       `toml:"trash"`
+	Checkpoints CheckpointsConfig `toml:"checkpoints"`
+	Auth        AuthConfig        `toml:"auth"`
+	OIDC        OIDCConfig        `toml:"oidc"`
 }
 
 // ServerConfig 服务器配置
@@ -20,8 +27,80 @@ type ServerConfig struct {
 	LogLevel  string `toml:"log_level"`
 	DataDir   string `toml:"data_dir"`   // 数据目录（存放站点配置等）
 	SitesDir  string `toml:"sites_dir"`  // 静态站点文件根目录
-	AdminUser string `toml:"admin_user"` // 管理员用户名
-	AdminPass string `toml:"admin_pass"` // 管理员密码
+	AdminUser string `toml:"admin_user"` // 初始管理员用户名（仅在账户存储为空时用于创建超级管理员）
+	AdminPass string `toml:"admin_pass"` // 初始管理员密码
+
+	// 可信反向代理的地址段（CIDR）。仅当请求直接来自这些地址时才采用 X-Forwarded-For 中的客户端 IP，
+	// 为空时始终使用 TCP 连接的对端地址（登录限流与审计日志均依赖客户端 IP，不能信任客户端自报的请求头）
+	TrustedProxies []string `toml:"trusted_proxies"`
+}
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	MaxSizeMB int `toml:"max_size_mb"` // 单个日志文件大小上限（MB），超过后轮转
+	MaxFiles  int `toml:"max_files"`   // 保留的归档文件数
+}
+
+// DeployConfig 部署上传与解压限制（0 表示不限制）
+type DeployConfig struct {
+	MaxUploadMB         int `toml:"max_upload_mb"`         // 上传压缩包大小上限（MB）
+	MaxExtractedMB      int `toml:"max_extracted_mb"`      // 解压后文件总大小上限（MB）
+	MaxFiles            int `toml:"max_files"`             // 压缩包内条目数量上限
+	MaxPathDepth        int `toml:"max_path_depth"`        // 条目路径深度上限
+	MaxCompressionRatio int `toml:"max_compression_ratio"` // 压缩比上限（解压后总大小 / 压缩包大小）
+}
+
+// JobsConfig 异步任务配置
+type JobsConfig struct {
+	Workers int `toml:"workers"` // 并发执行的任务数（同一站点的任务始终依次执行）
+	History int `toml:"history"` // 任务日志中保留的已结束任务数量，0 或未设置时使用默认值
+}
+
+// TrashConfig 站点回收站配置
+type TrashConfig struct {
+	RetentionHours int `toml:"retention_hours"` // 删除的站点在回收站中保留的时长（小时），负数表示不保留、立即彻底删除，0 或未设置时使用默认值
+}
+
+// CheckpointsConfig 检查点配置：默认保留策略（站点可单独设置，满足任一规则的检查点被保留，全为 0 时不自动删除）和发布目录数量
+type CheckpointsConfig struct {
+	KeepLast   int `toml:"keep_last"`   // 保留最近创建的 N 个
+	KeepDays   int `toml:"keep_days"`   // 保留最近 N 天内创建的
+	KeepDaily  int `toml:"keep_daily"`  // 更早的检查点中，为最近 N 个有检查点的日期各保留一个
+	KeepWeekly int `toml:"keep_weekly"` // 更早的检查点中，为最近 N 个有检查点的周各保留一个
+
+	KeepReleases int `toml:"keep_releases"` // 每个站点保留的发布目录数量（含当前发布），负数表示不使用发布目录、直接替换站点目录，0 或未设置时使用默认值
+}
+
+// AuthConfig 管理认证配置
+type AuthConfig struct {
+	MaxFailuresPerUser int `toml:"max_failures_per_user"` // 单个用户名连续认证失败多少次后锁定
+	MaxFailuresPerIP   int `toml:"max_failures_per_ip"`   // 单个 IP 连续认证失败多少次后锁定
+	LockoutBaseSeconds int `toml:"lockout_base_seconds"`  // 首次锁定时长（秒），之后每次失败翻倍
+	LockoutMaxSeconds  int `toml:"lockout_max_seconds"`   // 锁定时长上限（秒）
+
+	SessionIdleMinutes  int  `toml:"session_idle_minutes"`  // 管理界面会话空闲超时（分钟）
+	SessionMaxMinutes   int  `toml:"session_max_minutes"`   // 管理界面会话绝对有效期（分钟）
+	SessionSecureCookie bool `toml:"session_secure_cookie"` // 始终为会话 Cookie 设置 Secure（经 HTTPS 反向代理部署时开启）
+}
+
+// OIDCConfig OpenID Connect 单点登录配置
+type OIDCConfig struct {
+	Enabled       bool               `toml:"enabled"`
+	Issuer        string             `toml:"issuer"` // 身份提供方地址（发现文档位于 <issuer>/.well-known/openid-configuration）
+	ClientID      string             `toml:"client_id"`
+	ClientSecret  string             `toml:"client_secret"`
+	RedirectURL   string             `toml:"redirect_url"` // 回调地址 <外部地址>/_api/oidc/callback，为空时按请求地址推导
+	Scopes        []string           `toml:"scopes"`
+	UsernameClaim string             `toml:"username_claim"` // 作为账户名的 ID Token 声明
+	GroupsClaim   string             `toml:"groups_claim"`   // 用户组声明
+	GroupMappings []OIDCGroupMapping `toml:"group_mappings,omitempty"` // 按顺序匹配，未命中的用户拒绝登录
+}
+
+// OIDCGroupMapping 用户组到角色/租户的映射
+type OIDCGroupMapping struct {
+	Group  string `toml:"group"`
+	Role   string `toml:"role"`   // superadmin / tenant_admin / readonly
+	Tenant string `toml:"tenant"` // tenant_admin 必填
 }
 
 // Default 返回默认配置
@@ -35,6 +114,40 @@ func Default() *Config {
 			AdminUser: "admin",
 			AdminPass: "admin",
 		},
+		Audit: AuditConfig{
+			MaxSizeMB: 10,
+			MaxFiles:  5,
+		},
+		Deploy: DeployConfig{
+			MaxUploadMB:         100,
+			MaxExtractedMB:      1024,
+			MaxFiles:            20000,
+			MaxPathDepth:        32,
+			MaxCompressionRatio: 100,
+		},
+		Jobs: JobsConfig{
+			Workers: 2,
+			History: 200,
+		},
+		Trash: TrashConfig{
+			RetentionHours: 72,
+		},
+		Checkpoints: CheckpointsConfig{
+			KeepReleases: 3,
+		},
+		Auth: AuthConfig{
+			MaxFailuresPerUser: 5,
+			MaxFailuresPerIP:   20,
+			LockoutBaseSeconds: 30,
+			LockoutMaxSeconds:  900,
+			SessionIdleMinutes: 30,
+			SessionMaxMinutes:  720,
+		},
+		OIDC: OIDCConfig{
+			Scopes:        []string{"openid", "profile", "email"},
+			UsernameClaim: "preferred_username",
+			GroupsClaim:   "groups",
+		},
 	}
 }
 
@@ -58,10 +171,12 @@ func LoadOrInit(path string, envOverride bool) (*Config, bool, error) {
 	if err != nil {
 		return nil, created, err
 	}
-	cfg := &Config{}
+	// 以默认配置为基础解析，配置文件中缺少的节和配置项（如升级后新增的配置）保持默认值
+	cfg := Default()
 	if err := toml.Unmarshal(data, cfg); err != nil {
 		return nil, created, err
 	}
+	applyDefaults(cfg)
 
 	// 存在则用环境变量覆盖配置（不写回文件）
 	if envOverride {
@@ -71,6 +186,22 @@ func LoadOrInit(path string, envOverride bool) (*Config, bool, error) {
 	return cfg, created, nil
 }
 
+// applyDefaults 将显式设置为 0 的配置项恢复为默认值
+// 这些配置项以负数表示关闭，0 与未设置等价，避免手写或旧版本生成的 0 被当作关闭；
+// 任务历史没有关闭选项，0 会使刚结束的任务立即被清理、无法查询结果，同样恢复为默认值
+func applyDefaults(cfg *Config) {
+	def := Default()
+	if cfg.Jobs.History <= 0 {
+		cfg.Jobs.History = def.Jobs.History
+	}
+	if cfg.Trash.RetentionHours == 0 {
+		cfg.Trash.RetentionHours = def.Trash.RetentionHours
+	}
+	if cfg.Checkpoints.KeepReleases == 0 {
+		cfg.Checkpoints.KeepReleases = def.Checkpoints.KeepReleases
+	}
+}
+
 // Save 保存配置到文件
 func (c *Config) Save(path string) error {
 	return writeToml(path, c)
@@ -117,4 +248,9 @@ func applyEnvOverrides(cfg *Config) {
 	if v := os.Getenv("PAGES_ADMIN_PASS"); v != "" {
 		cfg.Server.AdminPass = v
 	}
+
+	// OIDC
+	if v := os.Getenv("PAGES_OIDC_CLIENT_SECRET"); v != "" {
+		cfg.OIDC.ClientSecret = v
+	}
 }
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadOrInitKeepsDefaultsForMissingKeys(t *testing.T) {
+	tests := []struct {
+		name         string
+		toml         string
+		wantTrash    int
+		wantReleases int
+	}{
+		{
+			name:         "升级前的配置文件缺少新增的节",
+			toml:         "[server]\nport = \"8080\"\n",
+			wantTrash:    72,
+			wantReleases: 3,
+		},
+		{
+			name:         "显式设置为 0 与未设置等价",
+			toml:         "[trash]\nretention_hours = 0\n[checkpoints]\nkeep_releases = 0\n[jobs]\nhistory = 0\n",
+			wantTrash:    72,
+			wantReleases: 3,
+		},
+		{
+			name:         "负数表示关闭",
+			toml:         "[trash]\nretention_hours = -1\n[checkpoints]\nkeep_releases = -1\n",
+			wantTrash:    -1,
+			wantReleases: -1,
+		},
+		{
+			name:         "自定义值",
+			toml:         "[trash]\nretention_hours = 24\n[checkpoints]\nkeep_releases = 5\n",
+			wantTrash:    24,
+			wantReleases: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.toml")
+			if err := os.WriteFile(path, []byte(tt.toml), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, created, err := LoadOrInit(path, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created {
+				t.Error("已存在的配置文件不应被重新创建")
+			}
+			if cfg.Trash.RetentionHours != tt.wantTrash {
+				t.Errorf("RetentionHours = %d, want %d", cfg.Trash.RetentionHours, tt.wantTrash)
+			}
+			if cfg.Checkpoints.KeepReleases != tt.wantReleases {
+				t.Errorf("KeepReleases = %d, want %d", cfg.Checkpoints.KeepReleases, tt.wantReleases)
+			}
+			if cfg.Jobs.History != Default().Jobs.History {
+				t.Errorf("Jobs.History = %d, want default", cfg.Jobs.History)
+			}
+			// 其他缺少的配置同样保持默认值
+			if cfg.Deploy.MaxFiles != Default().Deploy.MaxFiles {
+				t.Errorf("Deploy.MaxFiles = %d, want default", cfg.Deploy.MaxFiles)
+			}
+		})
+	}
+}
+
+func TestLoadOrInitCreatesDefaultConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	cfg, created, err := LoadOrInit(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Error("配置文件不存在时应创建")
+	}
+	if cfg.Trash.RetentionHours != 72 || cfg.Checkpoints.KeepReleases != 3 {
+		t.Errorf("默认配置 = %+v %+v", cfg.Trash, cfg.Checkpoints)
+	}
+}
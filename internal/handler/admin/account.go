@@ -0,0 +1,203 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/auth"
+	"pages/internal/middleware"
+	"pages/pkg/api"
+)
+
+// AccountHandler 管理员账户接口处理器
+type AccountHandler struct {
+	users    *auth.UserStore
+	sessions *auth.SessionStore
+}
+
+// NewAccountHandler 创建账户接口处理器
+func NewAccountHandler(users *auth.UserStore, sessions *auth.SessionStore) *AccountHandler {
+	return &AccountHandler{
+		users:    users,
+		sessions: sessions,
+	}
+}
+
+// RegisterRoutes 注册账户管理路由
+func (h *AccountHandler) RegisterRoutes(g *echo.Group) {
+	g.GET("/accounts", h.ListAccounts)
+	g.POST("/accounts", h.CreateAccount)
+	g.GET("/accounts/:name", h.GetAccount)
+	g.PUT("/accounts/:name", h.UpdateAccount)
+	g.DELETE("/accounts/:name", h.DeleteAccount)
+}
+
+// ListAccounts 列出所有管理员账户
+func (h *AccountHandler) ListAccounts(c echo.Context) error {
+	users := h.users.List()
+	accounts := make([]api.Account, len(users))
+	for i, u := range users {
+		accounts[i] = u.Account()
+	}
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: api.AccountList{
+			Accounts: accounts,
+			Total:    len(accounts),
+		},
+	})
+}
+
+// CreateAccount 创建管理员账户
+func (h *AccountHandler) CreateAccount(c echo.Context) error {
+	var req api.CreateAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: api.MsgInvalidRequest,
+		})
+	}
+
+	u, err := auth.NewUser(req.Username, req.Password, req.Role, req.Tenant)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: fmt.Sprintf("创建账户失败: %v", err),
+		})
+	}
+
+	if err := h.users.Add(u); err != nil {
+		return c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Message: fmt.Sprintf("创建账户失败: %v", err),
+		})
+	}
+
+	middleware.AuditChange(c, nil, accountSummary(u))
+
+	return c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Message: "账户创建成功",
+		Data:    u.Account(),
+	})
+}
+
+// GetAccount 获取管理员账户
+func (h *AccountHandler) GetAccount(c echo.Context) error {
+	u := h.users.Get(c.Param("name"))
+	if u == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgAccountNotFound,
+		})
+	}
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    u.Account(),
+	})
+}
+
+// UpdateAccount 更新管理员账户（密码、角色、租户）
+func (h *AccountHandler) UpdateAccount(c echo.Context) error {
+	u := h.users.Get(c.Param("name"))
+	if u == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgAccountNotFound,
+		})
+	}
+
+	var req api.UpdateAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: api.MsgInvalidRequest,
+		})
+	}
+
+	before := accountSummary(u)
+
+	if req.Role != "" {
+		u.Role = req.Role
+	}
+	if req.Tenant != nil {
+		u.Tenant = *req.Tenant
+	}
+	if err := u.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: fmt.Sprintf("更新账户失败: %v", err),
+		})
+	}
+	if req.Password != "" {
+		if err := u.SetPassword(req.Password); err != nil {
+			return c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: fmt.Sprintf("更新账户失败: %v", err),
+			})
+		}
+	}
+	u.UpdatedAt = time.Now()
+
+	if err := h.users.Update(u); err != nil {
+		return c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Message: fmt.Sprintf("更新账户失败: %v", err),
+		})
+	}
+
+	// 修改密码后使该账户的所有登录会话失效
+	if req.Password != "" {
+		h.sessions.DeleteForUser(u.Username)
+	}
+
+	after := accountSummary(u)
+	after["password_changed"] = req.Password != ""
+	middleware.AuditChange(c, before, after)
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "账户更新成功",
+		Data:    u.Account(),
+	})
+}
+
+// DeleteAccount 删除管理员账户
+func (h *AccountHandler) DeleteAccount(c echo.Context) error {
+	name := c.Param("name")
+	u := h.users.Get(name)
+	if u == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgAccountNotFound,
+		})
+	}
+	middleware.AuditChange(c, accountSummary(u), nil)
+
+	if err := h.users.Remove(name); err != nil {
+		return c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Message: fmt.Sprintf("删除账户失败: %v", err),
+		})
+	}
+	h.sessions.DeleteForUser(name)
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "账户已删除",
+	})
+}
+
+// accountSummary 账户的审计摘要（不含密码哈希）
+func accountSummary(u *auth.User) map[string]any {
+	return map[string]any{
+		"username": u.Username,
+		"role":     u.Role,
+		"tenant":   u.Tenant,
+	}
+}
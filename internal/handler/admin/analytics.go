@@ -7,6 +7,7 @@ import (
 
 	"pages/internal/analytics"
 	"pages/internal/site"
+	"pages/pkg/api"
 )
 
 type AnalyticsHandler struct {
@@ -69,6 +70,15 @@ func (h *AnalyticsHandler) GetStats(c echo.Context) error {
 		})
 	}
 
+	// 不为不存在的站点（包括回收站中的站点）创建统计 Worker
+	if !h.sm.ExistsForUser(username, id) {
+		if s, _ := h.sm.GetFullSiteByIDForUser(username, id); s == nil {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": api.MsgSiteNotFound,
+			})
+		}
+	}
+
 	stats, err := h.am.GetStats(username, id, full)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
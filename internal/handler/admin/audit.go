@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/audit"
+	"pages/pkg/api"
+)
+
+// AuditHandler 审计日志接口处理器
+type AuditHandler struct {
+	logger *audit.Logger
+}
+
+// NewAuditHandler 创建审计日志接口处理器
+func NewAuditHandler(logger *audit.Logger) *AuditHandler {
+	return &AuditHandler{
+		logger: logger,
+	}
+}
+
+// RegisterRoutes 注册审计日志路由
+func (h *AuditHandler) RegisterRoutes(g *echo.Group) {
+	g.GET("/audit", h.QueryAudit)
+}
+
+// QueryAudit 查询审计日志
+// 查询参数: tenant, site, actor, since, until (RFC3339), limit
+func (h *AuditHandler) QueryAudit(c echo.Context) error {
+	q := audit.Query{
+		Tenant: c.QueryParam("tenant"),
+		SiteID: c.QueryParam("site"),
+		Actor:  c.QueryParam("actor"),
+	}
+
+	var err error
+	if v := c.QueryParam("since"); v != "" {
+		if q.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "since 必须为 RFC3339 时间",
+			})
+		}
+	}
+	if v := c.QueryParam("until"); v != "" {
+		if q.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "until 必须为 RFC3339 时间",
+			})
+		}
+	}
+	if v := c.QueryParam("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 0 {
+			return c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "limit 必须为非负整数",
+			})
+		}
+	}
+
+	entries, err := h.logger.Query(q)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("查询审计日志失败: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: api.AuditList{
+			Entries: entries,
+			Total:   len(entries),
+		},
+	})
+}
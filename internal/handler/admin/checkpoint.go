@@ -1,10 +1,17 @@
 package admin
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"os"
 
 	"github.com/labstack/echo/v4"
+
+	"pages/internal/handler/deploy"
+	"pages/internal/middleware"
+	"pages/pkg/api"
 )
 
 // ListCheckpoints 列出站点的所有检查点
@@ -17,7 +24,7 @@ func (h *Handler) ListCheckpoints(c echo.Context) error {
 	if s == nil {
 		return c.JSON(http.StatusNotFound, Response{
 			Success: false,
-			Message: "站点不存在",
+			Message: api.MsgSiteNotFound,
 		})
 	}
 
@@ -29,16 +36,78 @@ func (h *Handler) ListCheckpoints(c echo.Context) error {
 		})
 	}
 
+	setCheckpointETag(c, metadata.Current)
 	return c.JSON(http.StatusOK, Response{
 		Success: true,
-		Data: map[string]any{
-			"current":     metadata.Current,
-			"checkpoints": metadata.Checkpoints,
-			"total":       len(metadata.Checkpoints),
+		Data: api.CheckpointList{
+			Current:     metadata.Current,
+			Checkpoints: metadata.Checkpoints,
+			Total:       len(metadata.Checkpoints),
 		},
 	})
 }
 
+// CreateCheckpoint 为当前部署的站点内容手动创建检查点
+func (h *Handler) CreateCheckpoint(c echo.Context) error {
+	username := c.Param("username")
+	id := c.Param("id")
+
+	var req api.CreateCheckpointRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: api.MsgInvalidRequest,
+		})
+	}
+
+	// 验证站点是否存在
+	s, err := h.siteManager.GetFullSiteByIDForUser(username, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("获取站点失败: %v", err),
+		})
+	}
+	if s == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgSiteNotFound,
+		})
+	}
+
+	// 与部署、检查点切换互斥，保证快照的是完整的站点内容
+	unlock, err := h.lockSite(s, c.Request().Header.Get("If-Match"))
+	if err != nil {
+		return deployFailed(c, err)
+	}
+	defer unlock()
+
+	rootDir := h.initializer.SiteDir(s)
+	if _, err := os.Stat(rootDir); os.IsNotExist(err) {
+		return c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Message: "站点尚未部署，没有可保存的内容",
+		})
+	}
+
+	checkpoint, err := h.checkpointManager.CreateManualCheckpoint(username, id, rootDir, req.Note)
+	if err != nil {
+		return quotaFailed(c, "创建检查点失败", err)
+	}
+
+	middleware.AuditChange(c, nil, map[string]any{
+		"checkpoint": checkpoint.ID,
+		"note":       checkpoint.Note,
+	})
+	setCheckpointETag(c, checkpoint.ID)
+
+	return c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Message: "检查点已创建",
+		Data:    checkpoint,
+	})
+}
+
 // GetCheckpoint 获取指定检查点信息
 func (h *Handler) GetCheckpoint(c echo.Context) error {
 	username := c.Param("username")
@@ -50,24 +119,133 @@ func (h *Handler) GetCheckpoint(c echo.Context) error {
 	if s == nil {
 		return c.JSON(http.StatusNotFound, Response{
 			Success: false,
-			Message: "站点不存在",
+			Message: api.MsgSiteNotFound,
 		})
 	}
 
-	checkpoint, err := h.checkpointManager.GetCheckpoint(username, id, checkpointID)
+	checkpoint, err := h.checkpointManager.ResolveCheckpoint(username, id, checkpointID)
 	if err != nil {
+		return c.JSON(checkpointErrorStatus(err), Response{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    checkpoint,
+	})
+}
+
+// DiffCheckpoints 比较两个检查点的文件差异，两端均可使用检查点 ID、标签或 current
+func (h *Handler) DiffCheckpoints(c echo.Context) error {
+	username := c.Param("username")
+	id := c.Param("id")
+
+	// 验证站点是否存在
+	s := h.siteManager.GetByIDForUser(username, id)
+	if s == nil {
 		return c.JSON(http.StatusNotFound, Response{
 			Success: false,
-			Message: fmt.Sprintf("检查点不存在: %v", err),
+			Message: api.MsgSiteNotFound,
+		})
+	}
+
+	text := c.QueryParam("diff") == "1" || c.QueryParam("diff") == "true"
+	diff, err := h.checkpointManager.DiffCheckpoints(username, id, c.Param("checkpoint_id"), c.Param("to"), text)
+	if err != nil {
+		return c.JSON(checkpointErrorStatus(err), Response{
+			Success: false,
+			Message: err.Error(),
 		})
 	}
 
 	return c.JSON(http.StatusOK, Response{
 		Success: true,
+		Data:    diff,
+	})
+}
+
+// UpdateCheckpoint 修改检查点的备注、标签和固定状态
+func (h *Handler) UpdateCheckpoint(c echo.Context) error {
+	username := c.Param("username")
+	id := c.Param("id")
+	checkpointID := c.Param("checkpoint_id")
+
+	var req api.UpdateCheckpointRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: api.MsgInvalidRequest,
+		})
+	}
+
+	// 验证站点是否存在
+	s, err := h.siteManager.GetFullSiteByIDForUser(username, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("获取站点失败: %v", err),
+		})
+	}
+	if s == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgSiteNotFound,
+		})
+	}
+
+	// 与部署、检查点切换互斥，避免并发修改元数据
+	unlock, err := h.lockSite(s, "")
+	if err != nil {
+		return deployFailed(c, err)
+	}
+	defer unlock()
+
+	before, err := h.checkpointManager.GetCheckpoint(username, id, checkpointID)
+	if err != nil {
+		return c.JSON(checkpointErrorStatus(err), Response{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	checkpoint, err := h.checkpointManager.UpdateCheckpoint(username, id, checkpointID, req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, deploy.ErrCheckpointNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, deploy.ErrInvalidLabel):
+			status = http.StatusBadRequest
+		case errors.Is(err, deploy.ErrLabelInUse):
+			status = http.StatusConflict
+		}
+		return c.JSON(status, Response{
+			Success: false,
+			Message: fmt.Sprintf("修改检查点失败: %v", err),
+		})
+	}
+
+	middleware.AuditChange(c, checkpointSummary(before), checkpointSummary(checkpoint))
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "检查点已更新",
 		Data:    checkpoint,
 	})
 }
 
+// checkpointSummary 检查点可修改字段的审计摘要
+func checkpointSummary(cp *deploy.Checkpoint) map[string]any {
+	return map[string]any{
+		"checkpoint": cp.ID,
+		"note":       cp.Note,
+		"labels":     cp.Labels,
+		"pinned":     cp.Pinned,
+	}
+}
+
 // DeleteCheckpoint 删除检查点
 func (h *Handler) DeleteCheckpoint(c echo.Context) error {
 	username := c.Param("username")
@@ -75,15 +253,48 @@ func (h *Handler) DeleteCheckpoint(c echo.Context) error {
 	checkpointID := c.Param("checkpoint_id")
 
 	// 验证站点是否存在
-	s := h.siteManager.GetByIDForUser(username, id)
+	s, err := h.siteManager.GetFullSiteByIDForUser(username, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("获取站点失败: %v", err),
+		})
+	}
 	if s == nil {
 		return c.JSON(http.StatusNotFound, Response{
 			Success: false,
-			Message: "站点不存在",
+			Message: api.MsgSiteNotFound,
 		})
 	}
 
+	// 与部署、检查点切换互斥，避免并发修改元数据
+	unlock, err := h.lockSite(s, c.Request().Header.Get("If-Match"))
+	if err != nil {
+		return deployFailed(c, err)
+	}
+	defer unlock()
+
+	if cp, err := h.checkpointManager.GetCheckpoint(username, id, checkpointID); err == nil {
+		middleware.AuditChange(c, map[string]any{
+			"checkpoint": cp.ID,
+			"file_name":  cp.FileName,
+			"file_size":  cp.FileSize,
+		}, nil)
+	}
+
 	if err := h.checkpointManager.DeleteCheckpoint(username, id, checkpointID); err != nil {
+		switch {
+		case errors.Is(err, deploy.ErrCheckpointPinned):
+			return c.JSON(http.StatusConflict, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+		case errors.Is(err, deploy.ErrCheckpointNotFound):
+			return c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Message: err.Error(),
+			})
+		}
 		return c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
 			Message: fmt.Sprintf("删除检查点失败: %v", err),
@@ -93,15 +304,16 @@ func (h *Handler) DeleteCheckpoint(c echo.Context) error {
 	return c.JSON(http.StatusOK, Response{
 		Success: true,
 		Message: "检查点已删除",
-		Data: map[string]any{
-			"username":      username,
-			"id":            id,
-			"checkpoint_id": checkpointID,
+		Data: api.CheckpointRef{
+			Username:     username,
+			ID:           id,
+			CheckpointID: checkpointID,
 		},
 	})
 }
 
 // CheckoutCheckpoint 切换站点到指定检查点（仅切换，不创建新检查点）
+// If-Match 请求头指定期望的当前检查点，不符时返回 412
 func (h *Handler) CheckoutCheckpoint(c echo.Context) error {
 	username := c.Param("username")
 	id := c.Param("id")
@@ -118,35 +330,63 @@ func (h *Handler) CheckoutCheckpoint(c echo.Context) error {
 	if s == nil {
 		return c.JSON(http.StatusNotFound, Response{
 			Success: false,
-			Message: "站点不存在",
+			Message: api.MsgSiteNotFound,
 		})
 	}
 
-	sitesDirVal := c.Get("sitesDir")
-	baseDir, ok := sitesDirVal.(string)
-	if !ok || baseDir == "" {
-		return c.JSON(http.StatusInternalServerError, Response{
+	rootDir := h.initializer.SiteDir(s)
+
+	unlock, err := h.lockSite(s, c.Request().Header.Get("If-Match"))
+	if err != nil {
+		return deployFailed(c, err)
+	}
+	defer unlock()
+
+	// 路径参数可以是检查点 ID 或标签
+	cp, err := h.checkpointManager.ResolveCheckpoint(username, id, checkpointID)
+	if err != nil {
+		return c.JSON(checkpointErrorStatus(err), Response{
 			Success: false,
-			Message: "服务器配置缺失: sitesDir",
+			Message: err.Error(),
 		})
 	}
-	rootDir := s.GetRootDir(baseDir)
+	checkpointID = cp.ID
+
+	before := map[string]any{"checkpoint": h.currentCheckpointID(username, id)}
 
 	// 切换到指定检查点（不创建新检查点，仅切换 current 指针）
 	if err := h.checkpointManager.CheckoutCheckpoint(username, id, checkpointID, rootDir); err != nil {
-		return c.JSON(http.StatusInternalServerError, Response{
+		if errors.Is(err, deploy.ErrRollbackFailed) {
+			slog.Error("切换检查点失败且未能恢复站点目录", "tenant", username, "site", id, "checkpoint", checkpointID, "error", err)
+			return c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: fmt.Sprintf("切换检查点失败，且未能恢复原站点目录，站点可能无法访问: %v", err),
+			})
+		}
+		return c.JSON(checkpointErrorStatus(err), Response{
 			Success: false,
 			Message: fmt.Sprintf("切换检查点失败: %v", err),
 		})
 	}
 
+	middleware.AuditChange(c, before, map[string]any{"checkpoint": checkpointID})
+	setCheckpointETag(c, checkpointID)
+
 	return c.JSON(http.StatusOK, Response{
 		Success: true,
 		Message: "站点已切换到检查点",
-		Data: map[string]any{
-			"username":      username,
-			"id":            id,
-			"checkpoint_id": checkpointID,
+		Data: api.CheckpointRef{
+			Username:     username,
+			ID:           id,
+			CheckpointID: checkpointID,
 		},
 	})
 }
+
+// checkpointErrorStatus 检查点不存在时返回 404，其他错误（如读取元数据失败）返回 500
+func checkpointErrorStatus(err error) int {
+	if errors.Is(err, deploy.ErrCheckpointNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -1,18 +1,28 @@
 package admin
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"mime"
 	"net/http"
 	"os"
-	"strings"
+	"path/filepath"
 
 	"github.com/labstack/echo/v4"
 
 	"pages/internal/handler/deploy"
+	"pages/internal/jobs"
+	"pages/internal/middleware"
+	"pages/internal/quota"
+	"pages/internal/site"
+	"pages/pkg/api"
 )
 
 // DeploySite 上传压缩包并部署站点
+// async=1 时接收完上传即返回任务（202），解压、检查点和替换目录由后台任务完成
+// If-Match 请求头指定期望的当前检查点，不符时返回 412，避免覆盖他人在此期间的部署
 func (h *Handler) DeploySite(c echo.Context) error {
 	username := c.Param("username")
 	id := c.Param("id")
@@ -27,153 +37,258 @@ func (h *Handler) DeploySite(c echo.Context) error {
 	if s == nil {
 		return c.JSON(http.StatusNotFound, Response{
 			Success: false,
-			Message: "站点不存在",
+			Message: api.MsgSiteNotFound,
 		})
 	}
 
-	sitesDirVal := c.Get("sitesDir")
-	baseDir, ok := sitesDirVal.(string)
-	if !ok || baseDir == "" {
-		return c.JSON(http.StatusInternalServerError, Response{
-			Success: false,
-			Message: "服务器配置缺失: sitesDir",
-		})
+	rootDir := h.initializer.SiteDir(s)
+
+	// 前置条件不满足时在接收上传之前返回；替换站点目录前还会在站点锁内再次检查
+	ifMatch := c.Request().Header.Get("If-Match")
+	if current := h.currentCheckpointID(username, id); !ifMatchSatisfied(ifMatch, current) {
+		return deployFailed(c, preconditionError(current))
+	}
+
+	// 限制请求体大小，超大上传在读取阶段即被中止
+	uploadLimit := h.uploadLimit(s)
+	if uploadLimit > 0 {
+		if c.Request().ContentLength > uploadLimit+multipartOverhead {
+			return uploadTooLarge(c, uploadLimit)
+		}
+		c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, uploadLimit+multipartOverhead)
 	}
-	rootDir := s.GetRootDir(baseDir)
 
-	fileHeader, err := c.FormFile("file")
+	// 1. 取得压缩包数据流（multipart 的 file 字段或原始请求体）
+	archive, filename, err := uploadedArchive(c)
 	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return uploadTooLarge(c, uploadLimit)
+		}
 		return c.JSON(http.StatusBadRequest, Response{
 			Success: false,
 			Message: "缺少上传文件字段 file",
 		})
 	}
 
-	src, err := fileHeader.Open()
+	// 2. 在站点目录旁创建暂存目录，保证最终替换是同一文件系统内的重命名
+	stagingDir, err := deploy.NewStagingDir(rootDir)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, Response{
+		return c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
-			Message: "无法读取上传文件",
+			Message: fmt.Sprintf("创建暂存目录失败: %v", err),
 		})
 	}
-	defer src.Close()
 
-	// 1. 保存上传文件到临时文件
-	tmpFile, err := os.CreateTemp("", "deploy-archive-*")
+	if c.QueryParam("async") == "1" || c.QueryParam("async") == "true" {
+		return h.deployAsync(c, s, stagingDir, archive, filename, ifMatch, uploadLimit)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	// 3. 按文件头（或文件名后缀）识别格式，边接收边解压（解压过程受部署限制约束）
+	extractDir := filepath.Join(stagingDir, "site")
+	limits := h.deployLimits
+	limits.MaxUploadBytes = uploadLimit
+	format, err := deploy.ExtractStream(archive, filename, extractDir, stagingDir, limits)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, Response{
-			Success: false,
-			Message: "创建临时文件失败",
-		})
+		return deployFailed(c, extractFailure(err, format, uploadLimit))
+	}
+	if filename == "" {
+		filename = "upload." + string(format)
 	}
-	tmpPath := tmpFile.Name()
-	defer os.Remove(tmpPath)
 
-	if _, err := io.Copy(tmpFile, src); err != nil {
-		tmpFile.Close()
-		return c.JSON(http.StatusInternalServerError, Response{
-			Success: false,
-			Message: "保存上传文件失败",
-		})
+	// 4. 在站点锁内整理目录、创建检查点并替换站点目录
+	unlock, err := h.lockSite(s, ifMatch)
+	if err != nil {
+		return deployFailed(c, err)
 	}
-	if err := tmpFile.Close(); err != nil {
-		return c.JSON(http.StatusInternalServerError, Response{
-			Success: false,
-			Message: "关闭临时文件失败",
-		})
+	defer unlock()
+
+	before := map[string]any{"checkpoint": h.currentCheckpointID(username, id)}
+	checkpoint, err := h.installDeployment(s, rootDir, extractDir, filename, func(string, int) {})
+	if err != nil {
+		return deployFailed(c, err)
+	}
+
+	after := map[string]any{"file_name": filename}
+	if checkpoint != nil {
+		after["checkpoint"] = checkpoint.ID
+	}
+	middleware.AuditChange(c, before, after)
+	setCheckpointETag(c, h.currentCheckpointID(username, id))
+
+	result := api.DeployResult{
+		Username:   username,
+		ID:         id,
+		Checkpoint: checkpoint,
 	}
 
-	// 2. 创建临时解压目录
-	tmpExtractDir, err := os.MkdirTemp("", "deploy-extract-*")
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "站点已部署",
+		Data:    result,
+	})
+}
+
+// deployAsync 将上传的压缩包写入暂存目录后提交部署任务
+func (h *Handler) deployAsync(c echo.Context, s *site.Site, stagingDir string, archive io.Reader, filename, ifMatch string, uploadLimit int64) error {
+	archivePath := filepath.Join(stagingDir, "archive")
+	job, err := h.jobs.Create(jobTypeDeploy, s.Username, s.ID, actorName(c), map[string]string{
+		jobs.ParamWorkDir: stagingDir,
+		"archive":         archivePath,
+		"filename":        filename,
+		"if_match":        ifMatch,
+	})
 	if err != nil {
+		os.RemoveAll(stagingDir)
 		return c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
-			Message: "创建临时解压目录失败",
+			Message: fmt.Sprintf("创建部署任务失败: %v", err),
 		})
 	}
-	defer os.RemoveAll(tmpExtractDir)
 
-	// 3. 根据文件类型解压到临时目录
-	filename := strings.ToLower(fileHeader.Filename)
-	switch {
-	case strings.HasSuffix(filename, ".zip"):
-		if err := deploy.ExtractZip(tmpPath, tmpExtractDir); err != nil {
-			return c.JSON(http.StatusBadRequest, Response{
-				Success: false,
-				Message: fmt.Sprintf("解压 zip 失败: %v", err),
-			})
-		}
-	case strings.HasSuffix(filename, ".tar.gz") || strings.HasSuffix(filename, ".tgz"):
-		if err := deploy.ExtractTarGz(tmpPath, tmpExtractDir); err != nil {
-			return c.JSON(http.StatusBadRequest, Response{
-				Success: false,
-				Message: fmt.Sprintf("解压 tar.gz 失败: %v", err),
-			})
+	// 接收上传（仍在请求内完成），出错时任务标记为失败并删除暂存目录
+	total := c.Request().ContentLength
+	err = receiveArchive(archive, archivePath, uploadLimit, func(received int64) {
+		if total > 0 {
+			h.jobs.Report(job.ID, api.PhaseUpload, int(min(received*100/total, 100)))
 		}
-	case strings.HasSuffix(filename, ".tar"):
-		if err := deploy.ExtractTar(tmpPath, tmpExtractDir); err != nil {
-			return c.JSON(http.StatusBadRequest, Response{
-				Success: false,
-				Message: fmt.Sprintf("解压 tar 失败: %v", err),
-			})
-		}
-	default:
-		return c.JSON(http.StatusBadRequest, Response{
-			Success: false,
-			Message: "仅支持 zip、tar 或 tar.gz 压缩包",
-		})
+	})
+	if err == nil {
+		// 尽早拒绝无法识别的格式，而不是排队后才失败
+		_, err = deploy.DetectFormat(archivePath, filename)
+	}
+	if err != nil {
+		failure := extractFailure(err, "", uploadLimit)
+		h.jobs.Fail(job.ID, failure)
+		return deployFailed(c, failure)
 	}
 
-	// 4. 检测并整理目录结构（展平单层嵌套）
-	normalizedDir, err := deploy.NormalizeDirectory(tmpExtractDir)
+	submitted, err := h.jobs.Submit(job.ID)
 	if err != nil {
+		h.jobs.Fail(job.ID, err)
 		return c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
-			Message: fmt.Sprintf("整理目录结构失败: %v", err),
+			Message: fmt.Sprintf("提交部署任务失败: %v", err),
 		})
 	}
-	// 如果创建了新的临时目录，确保清理
-	if normalizedDir != tmpExtractDir {
-		defer os.RemoveAll(normalizedDir)
+
+	middleware.AuditChange(c, nil, map[string]any{"job": job.ID, "file_name": filename})
+	c.Response().Header().Set(echo.HeaderLocation, APIPrefix+"/jobs/"+job.ID)
+	return c.JSON(http.StatusAccepted, Response{
+		Success: true,
+		Message: "部署任务已提交",
+		Data:    submitted,
+	})
+}
+
+// RunJob 执行异步部署任务（jobs.Runner）
+func (h *Handler) RunJob(job jobs.Job, params map[string]string, report jobs.Reporter) (*api.DeployResult, error) {
+	s, err := h.siteManager.GetFullSiteByIDForUser(job.Username, job.SiteID)
+	if err != nil {
+		return nil, fmt.Errorf("获取站点失败: %w", err)
+	}
+	if s == nil {
+		return nil, errors.New(api.MsgSiteNotFound)
+	}
+	rootDir := h.initializer.SiteDir(s)
+	stagingDir := params[jobs.ParamWorkDir]
+	archivePath := params["archive"]
+	filename := params["filename"]
+
+	// 上传大小已在接收时检查
+	report(api.PhaseExtract, 0)
+	extractDir := filepath.Join(stagingDir, "site")
+	limits := h.deployLimits
+	limits.MaxUploadBytes = 0
+	format, err := extractArchiveFile(archivePath, filename, extractDir, stagingDir, limits, func(progress int) {
+		report(api.PhaseExtract, progress)
+	})
+	if err != nil {
+		return nil, extractFailure(err, format, 0)
+	}
+	os.Remove(archivePath)
+	if filename == "" {
+		filename = "upload." + string(format)
 	}
 
-	// 5. 创建检查点（如果站点目录已存在）
-	var checkpoint *deploy.Checkpoint
-	if _, err := os.Stat(rootDir); err == nil {
-		// 站点目录存在，创建检查点
-		checkpoint, err = h.checkpointManager.CreateCheckpoint(username, id, rootDir, fileHeader.Filename)
-		if err != nil {
-			// 检查点创建失败不中断部署，只记录错误
-			c.Logger().Warnf("创建检查点失败 (继续部署): %v", err)
-		}
+	// 与同步部署、检查点切换等操作互斥；If-Match 提交时已检查，执行前在锁内再次检查
+	unlock, err := h.lockSite(s, params["if_match"])
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
-	// 6. 原子性替换站点目录
-	if err := deploy.AtomicReplaceDirectory(rootDir, normalizedDir); err != nil {
-		return c.JSON(http.StatusInternalServerError, Response{
-			Success: false,
-			Message: fmt.Sprintf("部署失败: %v", err),
-		})
+	checkpoint, err := h.installDeployment(s, rootDir, extractDir, filename, report)
+	if err != nil {
+		return nil, err
 	}
+	return &api.DeployResult{
+		Username:   job.Username,
+		ID:         job.SiteID,
+		Checkpoint: checkpoint,
+	}, nil
+}
+
+// installDeployment 将解压后的内容部署为站点的新版本：
+// 整理目录结构、检查部署配额、为新版本创建检查点、替换站点目录并将该检查点设为当前检查点
+func (h *Handler) installDeployment(s *site.Site, rootDir, extractDir, filename string, report jobs.Reporter) (*deploy.Checkpoint, error) {
+	username, id := s.Username, s.ID
 
-	// 7. 重算存储使用量 (如果没有创建检查点,需要手动触发)
-	if checkpoint == nil {
-		_ = h.checkpointManager.StorageRecount(username, id, rootDir)
+	// 检测并整理目录结构（展平单层嵌套）
+	report(api.PhaseNormalize, -1)
+	normalizedDir, err := deploy.NormalizeDirectory(extractDir)
+	if err != nil {
+		return nil, &deployError{Status: http.StatusInternalServerError, Message: fmt.Sprintf("整理目录结构失败: %v", err)}
 	}
 
-	result := map[string]any{
-		"username": username,
-		"id":       id,
+	// 检查部署后的文件总大小是否超出配额
+	deployedSize, err := deploy.DirSize(normalizedDir)
+	if err != nil {
+		return nil, &deployError{Status: http.StatusInternalServerError, Message: fmt.Sprintf("计算部署文件大小失败: %v", err)}
 	}
-	if checkpoint != nil {
-		result["checkpoint"] = checkpoint
+	if err := h.quota.CheckDeploy(s, deployedSize); err != nil {
+		return nil, err
 	}
 
-	return c.JSON(http.StatusOK, Response{
-		Success: true,
-		Message: "站点已部署",
-		Data:    result,
-	})
+	// 为新版本创建检查点；站点现有内容尚无检查点时（首次接入检查点、文件被直接修改）先为其创建检查点
+	report(api.PhaseCheckpoint, -1)
+	if _, err := h.checkpointManager.CaptureLive(username, id, rootDir); err != nil {
+		var qe *quota.Error
+		if errors.As(err, &qe) {
+			// 超出检查点配额时中止部署，避免旧版本在没有备份的情况下被覆盖
+			return nil, err
+		}
+		// 现有内容的检查点创建失败不中断部署，只记录错误
+		slog.Warn("为现有站点内容创建检查点失败 (继续部署)", "tenant", username, "site", id, "error", err)
+	}
+	checkpoint, err := h.checkpointManager.CreateCheckpoint(username, id, normalizedDir, filename)
+	if err != nil {
+		var qe *quota.Error
+		if errors.As(err, &qe) {
+			return nil, err
+		}
+		return nil, &deployError{Status: http.StatusInternalServerError, Message: fmt.Sprintf("创建检查点失败: %v", err)}
+	}
+
+	// 上线新版本：切换发布链接，未启用发布目录时原子性替换站点目录
+	report(api.PhaseSwap, -1)
+	if err := h.checkpointManager.Publish(rootDir, checkpoint.ID, normalizedDir); err != nil {
+		if delErr := h.checkpointManager.DeleteCheckpoint(username, id, checkpoint.ID); delErr != nil {
+			slog.Warn("删除未生效的检查点失败", "tenant", username, "site", id, "checkpoint", checkpoint.ID, "error", delErr)
+		}
+		if errors.Is(err, deploy.ErrRollbackFailed) {
+			slog.Error("部署失败且未能恢复站点目录", "tenant", username, "site", id, "error", err)
+			return nil, &deployError{Status: http.StatusInternalServerError, Message: fmt.Sprintf("部署失败，且未能恢复原站点目录，站点可能无法访问: %v", err)}
+		}
+		return nil, &deployError{Status: http.StatusInternalServerError, Message: fmt.Sprintf("部署失败: %v", err)}
+	}
+
+	// 新版本已上线，将其检查点设为当前检查点
+	if err := h.checkpointManager.ActivateCheckpoint(username, id, checkpoint.ID, rootDir); err != nil {
+		return nil, &deployError{Status: http.StatusInternalServerError, Message: fmt.Sprintf("站点已部署，但更新当前检查点失败: %v", err)}
+	}
+	return checkpoint, nil
 }
 
 // GetSiteUsage 获取站点使用情况(磁盘空间等) - 从元数据缓存读取
@@ -186,7 +301,7 @@ func (h *Handler) GetSiteUsage(c echo.Context) error {
 	if s == nil {
 		return c.JSON(http.StatusNotFound, Response{
 			Success: false,
-			Message: "站点不存在",
+			Message: api.MsgSiteNotFound,
 		})
 	}
 
@@ -201,15 +316,6 @@ func (h *Handler) GetSiteUsage(c echo.Context) error {
 
 	// 如果缓存为空(首次查询或元数据不存在),触发一次重算
 	if usage.TotalSize == 0 && usage.DeployedSize == 0 {
-		sitesDirVal := c.Get("sitesDir")
-		baseDir, ok := sitesDirVal.(string)
-		if !ok || baseDir == "" {
-			return c.JSON(http.StatusInternalServerError, Response{
-				Success: false,
-				Message: "服务器配置缺失: sitesDir",
-			})
-		}
-
 		s, err := h.siteManager.GetFullSiteByIDForUser(username, id)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, Response{
@@ -220,11 +326,11 @@ func (h *Handler) GetSiteUsage(c echo.Context) error {
 		if s == nil {
 			return c.JSON(http.StatusNotFound, Response{
 				Success: false,
-				Message: "站点不存在",
+				Message: api.MsgSiteNotFound,
 			})
 		}
 
-		rootDir := s.GetRootDir(baseDir)
+		rootDir := h.initializer.SiteDir(s)
 
 		// 触发重算
 		if err := h.checkpointManager.StorageRecount(username, id, rootDir); err != nil {
@@ -254,28 +360,13 @@ func (h *Handler) GetSiteUsage(c echo.Context) error {
 func (h *Handler) GetUserUsage(c echo.Context) error {
 	username := c.Param("username")
 
-	// 获取用户所有站点
+	// 获取用户所有站点，汇总元数据中缓存的使用量信息
 	sites := h.siteManager.ListForUser(username)
-	
-	totalUsage := deploy.DiskUsage{}
-
+	ids := make([]string, 0, len(sites))
 	for _, s := range sites {
-		// 从元数据中读取缓存的使用量信息
-		usage, err := h.checkpointManager.GetStorageUsage(username, s.ID)
-		if err != nil {
-			continue
-		}
-		
-		totalUsage.DeployedSize += usage.DeployedSize
-		totalUsage.CheckpointsSize += usage.CheckpointsSize
-		totalUsage.TotalSize += usage.TotalSize
-		totalUsage.FileCount += usage.FileCount
-		totalUsage.CheckpointCount += usage.CheckpointCount
+		ids = append(ids, s.ID)
 	}
-
-	totalUsage.DeployedSizeHR = formatBytes(totalUsage.DeployedSize)
-	totalUsage.CheckpointsSizeHR = formatBytes(totalUsage.CheckpointsSize)
-	totalUsage.TotalSizeHR = formatBytes(totalUsage.TotalSize)
+	totalUsage := h.checkpointManager.GetTotalStorageUsage(username, ids)
 
 	return c.JSON(http.StatusOK, Response{
 		Success: true,
@@ -283,20 +374,159 @@ func (h *Handler) GetUserUsage(c echo.Context) error {
 	})
 }
 
-// formatBytes 将字节数格式化为人类可读的格式
-func formatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
+// multipartOverhead 请求体中 multipart 边界和表单字段的额外开销余量
+const multipartOverhead = 1 << 20
+
+// uploadLimit 返回站点的实际上传大小上限：部署配置与站点/租户配额中较小的一个（0 表示不限制）
+func (h *Handler) uploadLimit(s *site.Site) int64 {
+	limit := h.deployLimits.MaxUploadBytes
+	if q := h.quota.UploadLimit(s); q > 0 && (limit <= 0 || q < limit) {
+		limit = q
 	}
+	return limit
+}
+
+// jobTypeDeploy 部署任务类型
+const jobTypeDeploy = "deploy"
 
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
+// deployError 部署失败，Status 为同步部署应返回的 HTTP 状态码
+type deployError struct {
+	Status  int
+	Message string
+}
+
+func (e *deployError) Error() string {
+	return e.Message
+}
+
+// deployFailed 返回部署失败的响应
+func deployFailed(c echo.Context, err error) error {
+	var de *deployError
+	if errors.As(err, &de) {
+		return c.JSON(de.Status, Response{
+			Success: false,
+			Message: de.Message,
+		})
 	}
+	return quotaFailed(c, "部署失败", err)
+}
 
-	// KB, MB, GB, TB, PB
-	units := []string{"KB", "MB", "GB", "TB", "PB"}
-	return fmt.Sprintf("%.2f %s", float64(bytes)/float64(div), units[exp])
+// extractFailure 将接收或解压压缩包时的错误转换为 deployError
+func extractFailure(err error, format deploy.Format, uploadLimit int64) error {
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		return &deployError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("上传文件超过上限 %s", deploy.FormatBytes(uploadLimit))}
+	case errors.Is(err, deploy.ErrUnsupportedFormat):
+		return &deployError{Status: http.StatusBadRequest, Message: err.Error()}
+	}
+
+	status := http.StatusBadRequest
+	if errors.Is(err, deploy.ErrLimitExceeded) {
+		status = http.StatusRequestEntityTooLarge
+	}
+	if format == "" {
+		return &deployError{Status: status, Message: err.Error()}
+	}
+	return &deployError{Status: status, Message: fmt.Sprintf("解压 %s 失败: %v", format, err)}
+}
+
+// receiveArchive 将上传的压缩包写入 path，超过 limit（0 表示不限制）时返回 deploy.ErrLimitExceeded
+func receiveArchive(r io.Reader, path string, limit int64, progress func(received int64)) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	src := io.Reader(&countingReader{r: r, fn: progress})
+	if limit > 0 {
+		src = io.LimitReader(src, limit+1)
+	}
+	n, err := io.Copy(f, src)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if limit > 0 && n > limit {
+		return fmt.Errorf("%w: 上传文件超过上限 %s", deploy.ErrLimitExceeded, deploy.FormatBytes(limit))
+	}
+	return nil
+}
+
+// extractArchiveFile 解压已保存的压缩包，progress 报告解压进度百分比（zip 无法按读取量估算，报告 -1）
+func extractArchiveFile(archivePath, filename, dest, spoolDir string, limits deploy.Limits, progress func(int)) (deploy.Format, error) {
+	format, err := deploy.DetectFormat(archivePath, filename)
+	if err != nil {
+		return "", err
+	}
+	if format == deploy.FormatZip {
+		progress(-1)
+		return format, deploy.Extract(archivePath, dest, format, limits)
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return format, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return format, err
+	}
+	size := max(info.Size(), 1)
+	return deploy.ExtractStream(&countingReader{r: f, fn: func(read int64) {
+		progress(int(min(read*100/size, 100)))
+	}}, filename, dest, spoolDir, limits)
+}
+
+// countingReader 统计读取字节数并回调
+type countingReader struct {
+	r  io.Reader
+	n  int64
+	fn func(n int64)
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.n += int64(n)
+		r.fn(r.n)
+	}
+	return n, err
+}
+
+// uploadedArchive 返回上传的压缩包数据流和文件名
+// multipart/form-data 请求流式读取 file 字段，不缓存整个表单；
+// 其他请求（如 Content-Type: application/gzip）以请求体作为压缩包，文件名取查询参数 filename（可选）
+func uploadedArchive(c echo.Context) (io.Reader, string, error) {
+	req := c.Request()
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	if mediaType != echo.MIMEMultipartForm {
+		return req.Body, c.QueryParam("filename"), nil
+	}
+
+	mr, err := req.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() == "file" {
+			return part, part.FileName(), nil
+		}
+		part.Close()
+	}
+}
+
+// uploadTooLarge 返回上传文件超出上限的响应
+func uploadTooLarge(c echo.Context, limit int64) error {
+	return c.JSON(http.StatusRequestEntityTooLarge, Response{
+		Success: false,
+		Message: fmt.Sprintf("上传文件超过上限 %s", deploy.FormatBytes(limit)),
+	})
 }
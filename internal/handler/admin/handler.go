@@ -1,8 +1,24 @@
 package admin
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/analytics"
+	"pages/internal/auth"
 	"pages/internal/handler/deploy"
+	"pages/internal/jobs"
+	"pages/internal/quota"
 	"pages/internal/site"
+	"pages/internal/tenant"
+	"pages/internal/trash"
+	"pages/pkg/api"
 )
 
 // Handler 管理接口处理器
@@ -10,20 +26,171 @@ type Handler struct {
 	siteManager       *site.ManagerLockFree
 	initializer       *site.Initializer
 	checkpointManager *deploy.CheckpointManager
+	tenants           *tenant.Store
+	analytics         *analytics.Manager
+	trash             *trash.Bin
+	quota             *quota.Checker
+	deployLimits      deploy.Limits
+	jobs              *jobs.Manager
+	locks             *deploy.SiteLocker
+	sessions          *deploySessions
+	defaultRetention  deploy.Retention // 服务器默认的检查点保留策略
+	loginLimiter      *auth.Limiter    // 健康检查中报告登录限流统计
+	sweepStop         chan struct{}
+	sweepWG           sync.WaitGroup
 }
 
 // NewHandler 创建管理接口处理器
-func NewHandler(sm *site.ManagerLockFree, init *site.Initializer, checkpointsDir string) *Handler {
-	return &Handler{
+func NewHandler(sm *site.ManagerLockFree, init *site.Initializer, checkpointsDir string, tenants *tenant.Store, am *analytics.Manager, bin *trash.Bin, q *quota.Checker, deployLimits deploy.Limits, jm *jobs.Manager) *Handler {
+	checkpointManager := deploy.NewCheckpointManager(checkpointsDir)
+	checkpointManager.SetQuota(q.CheckCheckpoint)
+	// 彻底删除回收站中的站点时释放其检查点对共享内容的引用
+	bin.SetPurgeHook(func(kind, dir string) {
+		if kind != "checkpoints" {
+			return
+		}
+		if err := checkpointManager.ReleaseDir(dir); err != nil {
+			slog.Error("释放检查点内容引用失败", "dir", dir, "error", err)
+		}
+	})
+	h := &Handler{
 		siteManager:       sm,
 		initializer:       init,
-		checkpointManager: deploy.NewCheckpointManager(checkpointsDir),
+		checkpointManager: checkpointManager,
+		tenants:           tenants,
+		analytics:         am,
+		trash:             bin,
+		quota:             q,
+		deployLimits:      deployLimits,
+		jobs:              jm,
+		locks:             deploy.NewSiteLocker(),
+		sessions:          newDeploySessions(),
+	}
+	checkpointManager.SetRetention(h.siteRetention)
+	return h
+}
+
+// SetKeepReleases 设置每个站点保留的发布目录数量（含当前发布），不大于 0 表示部署和切换时直接替换站点目录
+func (h *Handler) SetKeepReleases(keep int) {
+	h.checkpointManager.SetKeepReleases(keep)
+}
+
+// SetLoginLimiter 设置登录限流器，健康检查接口据此报告认证失败与锁定统计
+func (h *Handler) SetLoginLimiter(l *auth.Limiter) {
+	h.loginLimiter = l
+}
+
+// Response 通用响应结构（与 pkg/client 共用）
+type Response = api.Response
+
+// siteSummary 站点的审计摘要
+func siteSummary(s *site.Site) map[string]any {
+	return map[string]any{
+		"domain":    s.Domain,
+		"index":     s.Index,
+		"enabled":   s.Enabled,
+		"limits":    s.Limits,
+		"retention": s.Retention,
+	}
+}
+
+// quotaFailed 返回配额检查失败的响应：超出配额时使用配额错误的状态码（413/429/507），其他错误返回 500
+func quotaFailed(c echo.Context, action string, err error) error {
+	var qe *quota.Error
+	if errors.As(err, &qe) {
+		return c.JSON(qe.Status, Response{
+			Success: false,
+			Message: qe.Message,
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, Response{
+		Success: false,
+		Message: fmt.Sprintf("%s: %v", action, err),
+	})
+}
+
+// siteDataDirs 站点关联数据的目录（随站点一起移入回收站或恢复）
+func (h *Handler) siteDataDirs(s *site.Site) map[string]string {
+	return map[string]string{
+		"site":        h.initializer.SiteDir(s),
+		"releases":    deploy.ReleasesDir(h.initializer.SiteDir(s)),
+		"checkpoints": h.checkpointManager.SiteDir(s.Username, s.ID),
+		"analytics":   h.analytics.SiteDir(s.Username, s.ID),
+	}
+}
+
+// SiteLocker 返回站点修改锁（供需要与部署、检查点操作互斥的其他处理器使用）
+func (h *Handler) SiteLocker() *deploy.SiteLocker {
+	return h.locks
+}
+
+// CheckpointManager 返回检查点管理器（带有保留策略、配额和发布设置，供其他处理器共用）
+func (h *Handler) CheckpointManager() *deploy.CheckpointManager {
+	return h.checkpointManager
+}
+
+// currentCheckpointID 返回站点当前激活的检查点 ID（读取失败时为空）
+func (h *Handler) currentCheckpointID(username, id string) string {
+	metadata, err := h.checkpointManager.ListCheckpoints(username, id)
+	if err != nil {
+		return ""
+	}
+	return metadata.Current
+}
+
+// lockSite 获取站点修改锁，并在锁内确认站点仍然存在、当前检查点满足 If-Match 前置条件（ifMatch 为空时不检查）
+// 成功时返回的 unlock 用于释放锁，失败时返回 deployError
+func (h *Handler) lockSite(s *site.Site, ifMatch string) (unlock func(), err error) {
+	unlock, err = h.locks.Lock(h.initializer.SiteDir(s))
+	if err != nil {
+		return nil, &deployError{Status: http.StatusInternalServerError, Message: err.Error()}
+	}
+	if cur, err := h.siteManager.GetFullSiteByIDForUser(s.Username, s.ID); err != nil || cur == nil {
+		unlock()
+		if err != nil {
+			return nil, &deployError{Status: http.StatusInternalServerError, Message: fmt.Sprintf("获取站点失败: %v", err)}
+		}
+		return nil, &deployError{Status: http.StatusNotFound, Message: api.MsgSiteNotFound}
+	}
+	if current := h.currentCheckpointID(s.Username, s.ID); !ifMatchSatisfied(ifMatch, current) {
+		unlock()
+		return nil, preconditionError(current)
+	}
+	return unlock, nil
+}
+
+// ifMatchSatisfied 判断 If-Match 请求头是否与站点当前检查点匹配
+// 支持 "id"、W/"id"、逗号分隔的多个值和 *（站点已有当前检查点时匹配）；"" 匹配尚无检查点的站点
+func ifMatchSatisfied(header, current string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			if current != "" {
+				return true
+			}
+			continue
+		}
+		tag = strings.TrimPrefix(tag, "W/")
+		if strings.Trim(tag, `"`) == current {
+			return true
+		}
+	}
+	return false
+}
+
+// preconditionError 当前检查点与 If-Match 不符
+func preconditionError(current string) error {
+	if current == "" {
+		current = "无"
 	}
+	return &deployError{Status: http.StatusPreconditionFailed, Message: fmt.Sprintf("%s（当前检查点: %s）", api.MsgPreconditionFailed, current)}
 }
 
-// Response 通用响应结构
-type Response struct {
-	Success bool   `json:"success"`
-	Message string `json:"message,omitempty"`
-	Data    any    `json:"data,omitempty"`
+// setCheckpointETag 以当前检查点 ID 作为 ETag 响应头，客户端可将其用于后续请求的 If-Match
+func setCheckpointETag(c echo.Context, current string) {
+	c.Response().Header().Set("ETag", `"`+current+`"`)
 }
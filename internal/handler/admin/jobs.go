@@ -0,0 +1,157 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/jobs"
+	"pages/internal/middleware"
+	"pages/pkg/api"
+)
+
+// jobEventsHeartbeat 事件流的心跳间隔（避免代理因空闲断开连接）
+const jobEventsHeartbeat = 15 * time.Second
+
+// JobHandler 异步任务接口处理器
+type JobHandler struct {
+	jobs *jobs.Manager
+}
+
+// NewJobHandler 创建异步任务接口处理器
+func NewJobHandler(jm *jobs.Manager) *JobHandler {
+	return &JobHandler{
+		jobs: jm,
+	}
+}
+
+// RegisterRoutes 注册异步任务路由
+func (h *JobHandler) RegisterRoutes(g *echo.Group) {
+	g.GET("/jobs", h.ListJobs)
+	g.GET("/jobs/:job_id", h.GetJob)
+	g.GET("/jobs/:job_id/events", h.StreamJobEvents)
+	g.GET("/users/:username/jobs", h.ListUserJobs)
+}
+
+// ListJobs 列出所有任务
+func (h *JobHandler) ListJobs(c echo.Context) error {
+	list := h.jobs.List(c.QueryParam("tenant"))
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: api.JobList{
+			Jobs:  list,
+			Total: len(list),
+		},
+	})
+}
+
+// ListUserJobs 列出租户的任务
+func (h *JobHandler) ListUserJobs(c echo.Context) error {
+	list := h.jobs.List(c.Param("username"))
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: api.JobList{
+			Jobs:  list,
+			Total: len(list),
+		},
+	})
+}
+
+// GetJob 获取任务状态
+func (h *JobHandler) GetJob(c echo.Context) error {
+	job, ok := h.lookup(c)
+	if !ok {
+		return jobNotFound(c)
+	}
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    job,
+	})
+}
+
+// StreamJobEvents 以 Server-Sent Events 推送任务状态，任务结束后关闭连接
+// 每个事件为 "event: job"，data 为任务的完整 JSON
+func (h *JobHandler) StreamJobEvents(c echo.Context) error {
+	job, ok := h.lookup(c)
+	if !ok {
+		return jobNotFound(c)
+	}
+
+	updates, cancel := h.jobs.Subscribe(job.ID)
+	defer cancel()
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeJobEvent(w, job); err != nil || job.Finished() {
+		return nil
+	}
+
+	heartbeat := time.NewTicker(jobEventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				// 任务已结束，推送最终状态（中间状态可能因通道已满被丢弃）
+				if final, err := h.jobs.Get(job.ID); err == nil && final.Finished() && !job.Finished() {
+					writeJobEvent(w, final)
+				}
+				return nil
+			}
+			job = update
+			if err := writeJobEvent(w, job); err != nil {
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			w.Flush()
+		case <-c.Request().Context().Done():
+			return nil
+		}
+	}
+}
+
+// lookup 查找任务，并校验当前主体可以访问任务所属的租户和站点
+func (h *JobHandler) lookup(c echo.Context) (api.Job, bool) {
+	job, err := h.jobs.Get(c.Param("job_id"))
+	if err != nil {
+		return api.Job{}, false
+	}
+	if !middleware.Permits(c, http.MethodGet, api.ScopeSiteDeploy, job.Username, job.SiteID) &&
+		!middleware.Permits(c, http.MethodGet, api.ScopeSiteRead, job.Username, job.SiteID) {
+		// 无权访问时与任务不存在的响应相同，不暴露其他租户的任务
+		return api.Job{}, false
+	}
+	return job, true
+}
+
+func jobNotFound(c echo.Context) error {
+	return c.JSON(http.StatusNotFound, Response{
+		Success: false,
+		Message: api.MsgJobNotFound,
+	})
+}
+
+// writeJobEvent 写入一个任务状态事件
+func writeJobEvent(w *echo.Response, job api.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: job\ndata: %s\n\n", data); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
@@ -0,0 +1,453 @@
+package admin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/handler/deploy"
+	"pages/internal/middleware"
+	"pages/internal/site"
+	"pages/pkg/api"
+)
+
+// deploySessionTTL 增量部署会话的有效期，每次上传后顺延
+const deploySessionTTL = time.Hour
+
+// manifestFileName 增量部署在检查点中记录的文件名
+const manifestFileName = "manifest.json"
+
+// deploySession 进行中的增量部署会话
+// 暂存目录下 site/ 为组装中的站点内容，blobs/ 为客户端上传的内容（以哈希命名）
+type deploySession struct {
+	mu         sync.Mutex
+	info       api.DeploySession
+	pending    map[string][]string // 需要客户端上传的哈希 -> 使用该内容的路径
+	received   map[string]bool     // 已上传的哈希
+	stagingDir string
+}
+
+// snapshot 返回会话信息的副本，Missing 为尚未上传的哈希（需持有 mu）
+func (s *deploySession) snapshot() api.DeploySession {
+	info := s.info
+	info.Missing = make([]string, 0, len(s.pending)-len(s.received))
+	for hash := range s.pending {
+		if !s.received[hash] {
+			info.Missing = append(info.Missing, hash)
+		}
+	}
+	sort.Strings(info.Missing)
+	return info
+}
+
+// deploySessions 增量部署会话表（仅保存在内存中，服务重启后会话失效）
+type deploySessions struct {
+	mu       sync.Mutex
+	sessions map[string]*deploySession
+}
+
+func newDeploySessions() *deploySessions {
+	return &deploySessions{
+		sessions: make(map[string]*deploySession),
+	}
+}
+
+// add 登记会话，同时清理已过期的会话
+func (d *deploySessions) add(s *deploySession) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sweepLocked()
+	d.sessions[s.info.ID] = s
+}
+
+// get 查找站点的会话，不存在或已过期时返回 nil
+func (d *deploySessions) get(username, siteID, id string) *deploySession {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sweepLocked()
+	s := d.sessions[id]
+	if s == nil || s.info.Username != username || s.info.SiteID != siteID {
+		return nil
+	}
+	return s
+}
+
+// take 取出站点的会话（提交或取消后会话不再可用）
+func (d *deploySessions) take(username, siteID, id string) *deploySession {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := d.sessions[id]
+	if s == nil || s.info.Username != username || s.info.SiteID != siteID {
+		return nil
+	}
+	delete(d.sessions, id)
+	return s
+}
+
+// sweepLocked 删除已过期的会话及其暂存目录（需持有 mu）
+func (d *deploySessions) sweepLocked() {
+	now := time.Now()
+	for id, s := range d.sessions {
+		s.mu.Lock()
+		expired := now.After(s.info.ExpiresAt)
+		s.mu.Unlock()
+		if expired {
+			delete(d.sessions, id)
+			os.RemoveAll(s.stagingDir)
+		}
+	}
+}
+
+// CreateDeploySession 提交增量部署清单，返回需要上传的内容哈希
+// 清单中与当前部署或检查点内容相同的文件直接复用，无需上传
+func (h *Handler) CreateDeploySession(c echo.Context) error {
+	username := c.Param("username")
+	id := c.Param("id")
+
+	s, err := h.siteManager.GetFullSiteByIDForUser(username, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("获取站点失败: %v", err),
+		})
+	}
+	if s == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgSiteNotFound,
+		})
+	}
+
+	var req api.DeployManifestRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: api.MsgInvalidRequest,
+		})
+	}
+	manifest, err := deploy.ValidateManifest(req.Files, h.deployLimits)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, deploy.ErrLimitExceeded) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		return c.JSON(status, Response{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	rootDir := h.initializer.SiteDir(s)
+	stagingDir, err := deploy.NewStagingDir(rootDir)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("创建暂存目录失败: %v", err),
+		})
+	}
+	if err := os.Mkdir(filepath.Join(stagingDir, "blobs"), 0755); err != nil {
+		os.RemoveAll(stagingDir)
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("创建暂存目录失败: %v", err),
+		})
+	}
+
+	needed := manifest.ByHash()
+	reused, err := h.reuseDeployedFiles(s, filepath.Join(stagingDir, "site"), needed)
+	if err != nil {
+		os.RemoveAll(stagingDir)
+		return deployFailed(c, err)
+	}
+
+	now := time.Now()
+	session := &deploySession{
+		info: api.DeploySession{
+			ID:        newDeploySessionID(),
+			Username:  username,
+			SiteID:    id,
+			Files:     len(manifest),
+			Reused:    reused,
+			CreatedAt: now,
+			ExpiresAt: now.Add(deploySessionTTL),
+		},
+		pending:    needed,
+		received:   make(map[string]bool),
+		stagingDir: stagingDir,
+	}
+	info := session.snapshot()
+	h.sessions.add(session)
+
+	middleware.AuditChange(c, nil, map[string]any{
+		"session": info.ID,
+		"files":   info.Files,
+		"reused":  info.Reused,
+		"missing": len(info.Missing),
+	})
+
+	return c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Message: "部署会话已创建",
+		Data:    info,
+	})
+}
+
+// reuseDeployedFiles 从当前部署和检查点（由新到旧）中复用 needed 需要的内容到 dest，返回复用的文件数
+func (h *Handler) reuseDeployedFiles(s *site.Site, dest string, needed map[string][]string) (int, error) {
+	// 持有站点锁，避免读取过程中站点目录被替换或检查点被删除
+	unlock, err := h.lockSite(s, "")
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	// 站点文件按当前检查点的文件树查找，不逐个计算哈希
+	reused, err := h.checkpointManager.ReuseLiveFiles(s.Username, s.ID, h.initializer.SiteDir(s), dest, needed)
+	if err != nil {
+		return 0, &deployError{Status: http.StatusInternalServerError, Message: fmt.Sprintf("读取当前部署失败: %v", err)}
+	}
+	if len(needed) == 0 {
+		return reused, nil
+	}
+
+	metadata, err := h.checkpointManager.ListCheckpoints(s.Username, s.ID)
+	if err != nil {
+		slog.Warn("读取检查点列表失败，跳过检查点内容复用", "tenant", s.Username, "site", s.ID, "error", err)
+		return reused, nil
+	}
+	for _, cp := range metadata.Checkpoints {
+		if len(needed) == 0 {
+			break
+		}
+		n, err := h.checkpointManager.ReuseCheckpointFiles(s.Username, s.ID, cp.ID, dest, needed)
+		if err != nil {
+			// 单个检查点损坏不影响部署，缺失的内容由客户端上传
+			slog.Warn("复用检查点内容失败", "tenant", s.Username, "site", s.ID, "checkpoint", cp.ID, "error", err)
+		}
+		reused += n
+	}
+	return reused, nil
+}
+
+// GetDeploySession 获取增量部署会话状态
+func (h *Handler) GetDeploySession(c echo.Context) error {
+	session := h.sessions.get(c.Param("username"), c.Param("id"), c.Param("session_id"))
+	if session == nil {
+		return sessionNotFound(c)
+	}
+
+	session.mu.Lock()
+	info := session.snapshot()
+	session.mu.Unlock()
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    info,
+	})
+}
+
+// UploadDeployBlob 上传一个清单中缺失的文件内容（请求体或 multipart 的 file 字段），服务端校验其 SHA-256
+func (h *Handler) UploadDeployBlob(c echo.Context) error {
+	username := c.Param("username")
+	id := c.Param("id")
+	hash := c.Param("hash")
+
+	session := h.sessions.get(username, id, c.Param("session_id"))
+	if session == nil {
+		return sessionNotFound(c)
+	}
+
+	session.mu.Lock()
+	_, pending := session.pending[hash]
+	done := session.received[hash]
+	info := session.snapshot()
+	session.mu.Unlock()
+	if !pending {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: fmt.Sprintf("内容 %s 不在待上传列表中", hash),
+		})
+	}
+	if done {
+		return c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "内容已上传",
+			Data:    info,
+		})
+	}
+
+	s, err := h.siteManager.GetFullSiteByIDForUser(username, id)
+	if err != nil || s == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgSiteNotFound,
+		})
+	}
+	uploadLimit := h.uploadLimit(s)
+	if uploadLimit > 0 {
+		if c.Request().ContentLength > uploadLimit+multipartOverhead {
+			return uploadTooLarge(c, uploadLimit)
+		}
+		c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, uploadLimit+multipartOverhead)
+	}
+
+	body, _, err := uploadedArchive(c)
+	if err == nil {
+		_, err = deploy.ReceiveBlob(body, filepath.Join(session.stagingDir, "blobs", hash), hash)
+	}
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return uploadTooLarge(c, uploadLimit)
+		}
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: fmt.Sprintf("上传内容失败: %v", err),
+		})
+	}
+
+	session.mu.Lock()
+	session.received[hash] = true
+	session.info.ExpiresAt = time.Now().Add(deploySessionTTL)
+	info = session.snapshot()
+	session.mu.Unlock()
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "内容已上传",
+		Data:    info,
+	})
+}
+
+// CommitDeploySession 组装增量部署的站点内容，并与 DeploySite 相同地创建检查点、替换站点目录
+// 提交后会话即失效（失败时需重新创建会话）；If-Match 请求头的含义与 DeploySite 相同
+func (h *Handler) CommitDeploySession(c echo.Context) error {
+	username := c.Param("username")
+	id := c.Param("id")
+	sessionID := c.Param("session_id")
+
+	session := h.sessions.get(username, id, sessionID)
+	if session == nil {
+		return sessionNotFound(c)
+	}
+	session.mu.Lock()
+	info := session.snapshot()
+	session.mu.Unlock()
+	if len(info.Missing) > 0 {
+		return c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Message: fmt.Sprintf("仍有 %d 个文件内容未上传", len(info.Missing)),
+			Data:    info,
+		})
+	}
+	if session = h.sessions.take(username, id, sessionID); session == nil {
+		return sessionNotFound(c)
+	}
+	defer os.RemoveAll(session.stagingDir)
+
+	s, err := h.siteManager.GetFullSiteByIDForUser(username, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("获取站点失败: %v", err),
+		})
+	}
+	if s == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgSiteNotFound,
+		})
+	}
+
+	// 将上传的内容放到清单中使用它的所有路径
+	siteDir := filepath.Join(session.stagingDir, "site")
+	blobsDir := filepath.Join(session.stagingDir, "blobs")
+	for hash, names := range session.pending {
+		if err := deploy.PlaceFile(filepath.Join(blobsDir, hash), siteDir, names); err != nil {
+			return c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: fmt.Sprintf("组装站点内容失败: %v", err),
+			})
+		}
+	}
+	if limit := h.deployLimits.MaxExtractedBytes; limit > 0 {
+		if size, err := deploy.DirSize(siteDir); err == nil && size > limit {
+			return c.JSON(http.StatusRequestEntityTooLarge, Response{
+				Success: false,
+				Message: fmt.Sprintf("%v: 部署文件总大小超过 %s", deploy.ErrLimitExceeded, deploy.FormatBytes(limit)),
+			})
+		}
+	}
+
+	unlock, err := h.lockSite(s, c.Request().Header.Get("If-Match"))
+	if err != nil {
+		return deployFailed(c, err)
+	}
+	defer unlock()
+
+	before := map[string]any{"checkpoint": h.currentCheckpointID(username, id)}
+	checkpoint, err := h.installDeployment(s, h.initializer.SiteDir(s), siteDir, manifestFileName, func(string, int) {})
+	if err != nil {
+		return deployFailed(c, err)
+	}
+
+	after := map[string]any{
+		"session":  info.ID,
+		"files":    info.Files,
+		"reused":   info.Reused,
+		"uploaded": len(session.pending),
+	}
+	if checkpoint != nil {
+		after["checkpoint"] = checkpoint.ID
+	}
+	middleware.AuditChange(c, before, after)
+	setCheckpointETag(c, h.currentCheckpointID(username, id))
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "站点已部署",
+		Data: api.DeployResult{
+			Username:   username,
+			ID:         id,
+			Checkpoint: checkpoint,
+		},
+	})
+}
+
+// CancelDeploySession 取消增量部署会话并删除已上传的内容
+func (h *Handler) CancelDeploySession(c echo.Context) error {
+	session := h.sessions.take(c.Param("username"), c.Param("id"), c.Param("session_id"))
+	if session == nil {
+		return sessionNotFound(c)
+	}
+	os.RemoveAll(session.stagingDir)
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "部署会话已取消",
+	})
+}
+
+func sessionNotFound(c echo.Context) error {
+	return c.JSON(http.StatusNotFound, Response{
+		Success: false,
+		Message: api.MsgSessionNotFound,
+	})
+}
+
+// newDeploySessionID 生成部署会话 ID
+func newDeploySessionID() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
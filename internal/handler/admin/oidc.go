@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/auth"
+	"pages/internal/middleware"
+)
+
+const (
+	// oidcStateCookie 绑定登录请求与浏览器的 state Cookie
+	oidcStateCookie = "pages_oidc_state"
+	// adminLoginPath 管理界面登录页
+	adminLoginPath = "/_admin/login"
+	// adminHomePath 登录成功后跳转的管理界面首页
+	adminHomePath = "/_admin/"
+)
+
+// OIDCLogin 跳转到身份提供方进行单点登录
+func (h *SessionHandler) OIDCLogin(c echo.Context) error {
+	if h.oidc == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: "未启用单点登录",
+		})
+	}
+
+	redirectURL := h.oidc.RedirectURL()
+	if redirectURL == "" {
+		redirectURL = c.Scheme() + "://" + c.Request().Host + APIPrefix + "/oidc/callback"
+	}
+
+	authURL, state, err := h.oidc.AuthCodeURL(c.Request().Context(), redirectURL)
+	if err != nil {
+		slog.Error("发起单点登录失败", "error", err)
+		return loginFailed(c, "单点登录暂不可用")
+	}
+
+	// 回调为身份提供方发起的跨站跳转，需使用 SameSite=Lax 才能携带该 Cookie
+	c.SetCookie(&http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     APIPrefix + "/oidc",
+		MaxAge:   600,
+		Secure:   h.secureCookie || c.Scheme() == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 处理身份提供方回调：校验 state、换取并校验 ID Token、创建登录会话
+func (h *SessionHandler) OIDCCallback(c echo.Context) error {
+	if h.oidc == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: "未启用单点登录",
+		})
+	}
+
+	c.SetCookie(&http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     APIPrefix + "/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+
+	if e := c.QueryParam("error"); e != "" {
+		slog.Warn("身份提供方拒绝登录", "error", e, "description", c.QueryParam("error_description"))
+		return loginFailed(c, "身份提供方拒绝登录")
+	}
+
+	state := c.QueryParam("state")
+	cookie, err := c.Cookie(oidcStateCookie)
+	if state == "" || err != nil || cookie.Value != state {
+		return loginFailed(c, "登录请求无效或已过期，请重试")
+	}
+
+	p, err := h.oidc.Exchange(c.Request().Context(), state, c.QueryParam("code"))
+	if err != nil {
+		slog.Warn("单点登录失败", "ip", c.RealIP(), "error", err)
+		if errors.Is(err, auth.ErrOIDCNotAllowed) {
+			return loginFailed(c, "当前账户无权访问管理界面")
+		}
+		return loginFailed(c, "单点登录失败")
+	}
+
+	sess, err := h.sessions.Create(p)
+	if err != nil {
+		return loginFailed(c, "创建会话失败")
+	}
+	middleware.SetSessionCookie(c, sess, h.secureCookie)
+
+	slog.Info("单点登录成功", "username", p.Username, "role", p.Role, "tenant", p.Tenant, "ip", c.RealIP())
+	return c.Redirect(http.StatusFound, adminHomePath)
+}
+
+// loginFailed 重定向回登录页并显示错误信息
+func loginFailed(c echo.Context, message string) error {
+	return c.Redirect(http.StatusFound, adminLoginPath+"?error="+url.QueryEscape(message))
+}
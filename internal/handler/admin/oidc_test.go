@@ -0,0 +1,278 @@
+package admin
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/auth"
+	"pages/internal/middleware"
+)
+
+// mockIdP 本地模拟的 OIDC 身份提供方：发现文档、授权、Token 与 JWKS 端点
+type mockIdP struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	groups []string
+
+	mu    sync.Mutex
+	codes map[string]mockAuthRequest
+}
+
+// mockAuthRequest 授权请求中需要在换取 Token 时校验的参数
+type mockAuthRequest struct {
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+func newMockIdP(t *testing.T, groups ...string) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, groups: groups, codes: map[string]mockAuthRequest{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("client_id") != "pages" || q.Get("code_challenge_method") != "S256" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		code := "code-" + q.Get("state")[:8]
+		idp.mu.Lock()
+		idp.codes[code] = mockAuthRequest{
+			nonce:       q.Get("nonce"),
+			challenge:   q.Get("code_challenge"),
+			redirectURI: q.Get("redirect_uri"),
+		}
+		idp.mu.Unlock()
+		target := q.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+		http.Redirect(w, r, target, http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "pages" || secret != "secret" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		idp.mu.Lock()
+		req, ok := idp.codes[r.FormValue("code")]
+		delete(idp.codes, r.FormValue("code"))
+		idp.mu.Unlock()
+
+		sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != req.challenge || r.FormValue("redirect_uri") != req.redirectURI {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"id_token": idp.sign(t, map[string]any{
+				"iss":                idp.URL,
+				"aud":                "pages",
+				"exp":                time.Now().Add(time.Minute).Unix(),
+				"nonce":              req.nonce,
+				"preferred_username": "alice",
+				"groups":             idp.groups,
+			}),
+		})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// sign 使用 RS256 签发 ID Token
+func (idp *mockIdP) sign(t *testing.T, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Error(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// newOIDCApp 启动注册了会话路由和受会话保护的管理界面首页的服务
+func newOIDCApp(t *testing.T, idp *mockIdP) (*httptest.Server, *auth.SessionStore) {
+	t.Helper()
+	provider, err := auth.NewOIDCProvider(auth.OIDCConfig{
+		Issuer:        idp.URL,
+		ClientID:      "pages",
+		ClientSecret:  "secret",
+		GroupMappings: []auth.OIDCGroupMapping{{Group: "admins", Role: auth.RoleSuperAdmin}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := auth.NewUserStore(t.TempDir())
+	sessions := auth.NewSessionStore(time.Hour, time.Hour)
+
+	e := echo.New()
+	NewSessionHandler(users, sessions, nil, provider, false).RegisterRoutes(e.Group(APIPrefix))
+	home := e.Group(adminHomePath)
+	home.Use(middleware.RequireSession(middleware.SessionConfig{Users: users, Sessions: sessions}, adminLoginPath))
+	home.GET("", func(c echo.Context) error {
+		return c.String(http.StatusOK, "admin:"+middleware.CurrentPrincipal(c).Username)
+	})
+
+	app := httptest.NewServer(e)
+	t.Cleanup(app.Close)
+	return app, sessions
+}
+
+// followLogin 从 /oidc/login 开始逐个跟随跳转，返回最终响应与回调响应写入的会话 Cookie
+func followLogin(t *testing.T, app *httptest.Server) (*http.Response, *http.Cookie) {
+	t.Helper()
+	jar := &cookieJar{cookies: map[string]*http.Cookie{}}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	var session *http.Cookie
+	next := app.URL + APIPrefix + "/oidc/login"
+	for range 10 {
+		req, _ := http.NewRequest(http.MethodGet, next, nil)
+		if strings.HasPrefix(next, app.URL) {
+			jar.apply(req)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if strings.HasPrefix(next, app.URL) {
+			for _, c := range resp.Cookies() {
+				if c.Name == middleware.SessionCookieName && c.MaxAge > 0 {
+					session = c
+				}
+				jar.store(c)
+			}
+		}
+		if resp.StatusCode != http.StatusFound {
+			return resp, session
+		}
+		loc, err := resp.Location()
+		if err != nil {
+			t.Fatal(err)
+		}
+		next = loc.String()
+	}
+	t.Fatal("跳转次数过多")
+	return nil, nil
+}
+
+// cookieJar 只记录名称和值的简易 Cookie 存储（不按 SameSite 过滤，SameSite 由测试单独断言）
+type cookieJar struct {
+	cookies map[string]*http.Cookie
+}
+
+func (j *cookieJar) store(c *http.Cookie) {
+	if c.MaxAge < 0 {
+		delete(j.cookies, c.Name)
+		return
+	}
+	j.cookies[c.Name] = c
+}
+
+func (j *cookieJar) apply(req *http.Request) {
+	for _, c := range j.cookies {
+		req.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+	}
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	idp := newMockIdP(t, "admins")
+	app, sessions := newOIDCApp(t, idp)
+
+	resp, session := followLogin(t, app)
+	if session == nil {
+		t.Fatal("回调未写入会话 Cookie")
+	}
+	// 回调处于从身份提供方开始的跳转链中，Strict 的 Cookie 不会随落地请求发送
+	if session.SameSite != http.SameSiteLaxMode {
+		t.Errorf("会话 Cookie SameSite = %v, want Lax", session.SameSite)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Request.URL.Path != adminHomePath {
+		t.Fatalf("最终响应 %d %s, want 200 %s", resp.StatusCode, resp.Request.URL.Path, adminHomePath)
+	}
+
+	sess := sessions.Touch(session.Value)
+	if sess == nil {
+		t.Fatal("会话不存在")
+	}
+	if p := sess.Principal(); p.Username != "alice" || p.Role != auth.RoleSuperAdmin || p.Method != auth.MethodOIDC {
+		t.Errorf("会话主体 = %+v", p)
+	}
+}
+
+func TestOIDCLoginRejectsUnmappedGroup(t *testing.T) {
+	idp := newMockIdP(t, "visitors")
+	app, _ := newOIDCApp(t, idp)
+
+	resp, session := followLogin(t, app)
+	if session != nil {
+		t.Fatal("未授权用户组不应获得会话")
+	}
+	if resp.Request.URL.Path != adminLoginPath || !strings.Contains(resp.Request.URL.Query().Get("error"), "无权") {
+		t.Fatalf("最终请求 %s, want 跳转到登录页并提示无权访问", resp.Request.URL)
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	idp := newMockIdP(t, "admins")
+	app, _ := newOIDCApp(t, idp)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	req, _ := http.NewRequest(http.MethodGet, app.URL+APIPrefix+"/oidc/callback?code=x&state=forged", nil)
+	req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "other"})
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	loc, _ := resp.Location()
+	if resp.StatusCode != http.StatusFound || loc == nil || loc.Path != adminLoginPath {
+		t.Fatalf("响应 %d %v, want 302 %s", resp.StatusCode, loc, adminLoginPath)
+	}
+	for _, c := range resp.Cookies() {
+		if c.Name == middleware.SessionCookieName {
+			t.Fatal("state 不匹配时不应写入会话 Cookie")
+		}
+	}
+}
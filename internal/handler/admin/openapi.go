@@ -0,0 +1,382 @@
+package admin
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/middleware"
+	"pages/pkg/api"
+)
+
+// APIPrefix 管理 API 路由前缀
+const APIPrefix = "/_api"
+
+// operationDoc 单个接口的 OpenAPI 描述
+type operationDoc struct {
+	Summary  string
+	Tag      string
+	Request  any      // 请求体类型（JSON），nil 表示无请求体
+	Upload   bool     // 请求体为上传的文件（multipart 或原始请求体）
+	Response any      // Response.Data 的类型，nil 表示无数据
+	Raw      bool     // 响应不使用 Response 包装（统计接口）
+	Query    []string // 查询参数
+	Header   []string // 请求头参数
+	Public   bool     // 无需认证
+}
+
+// operationDocs 接口文档表，键为 "METHOD /path"（相对于 APIPrefix）
+// 新增路由时必须在此登记，否则 server 包的路由测试失败、启动时告警，OpenAPI 文档中也只有占位描述
+var operationDocs = map[string]operationDoc{
+	"GET /users/:username/sites":                                          {Summary: "获取用户站点列表", Tag: "sites", Response: api.SiteList{}},
+	"POST /users/:username/sites":                                         {Summary: "创建站点", Tag: "sites", Request: api.CreateSiteRequest{}, Response: api.Site{}},
+	"GET /users/:username/sites/:id":                                      {Summary: "获取站点详情", Tag: "sites", Response: api.Site{}},
+	"PUT /users/:username/sites/:id":                                      {Summary: "更新站点", Tag: "sites", Request: api.UpdateSiteRequest{}, Response: api.Site{}},
+	"DELETE /users/:username/sites/:id":                                   {Summary: "删除站点（移入回收站，purge=true 时彻底删除）", Tag: "sites", Response: api.TrashedSite{}, Query: []string{"purge"}},
+	"GET /users/:username/trash":                                          {Summary: "列出回收站中的站点", Tag: "sites", Response: api.TrashList{}},
+	"POST /users/:username/trash/:trash_id/restore":                       {Summary: "从回收站恢复站点", Tag: "sites", Response: api.Site{}},
+	"DELETE /users/:username/trash/:trash_id":                             {Summary: "彻底删除回收站中的站点", Tag: "sites"},
+	"POST /users/:username/sites/:id/deploy":                              {Summary: "上传压缩包并部署站点（async=1 时返回 202 和异步任务）", Tag: "deploy", Upload: true, Query: []string{"filename", "async"}, Header: []string{"If-Match"}, Response: api.DeployResult{}},
+	"GET /users/:username/sites/:id/usage":                                {Summary: "获取站点磁盘用量", Tag: "deploy", Response: api.DiskUsage{}},
+	"GET /users/:username/usage":                                          {Summary: "获取用户总用量", Tag: "deploy", Response: api.DiskUsage{}},
+	"GET /users/:username/sites/:id/checkpoints":                          {Summary: "获取检查点列表", Tag: "checkpoints", Response: api.CheckpointList{}},
+	"GET /users/:username/sites/:id/checkpoints/:checkpoint_id":           {Summary: "获取检查点详情（可使用标签代替 ID）", Tag: "checkpoints", Response: api.Checkpoint{}},
+	"DELETE /users/:username/sites/:id/checkpoints/:checkpoint_id":        {Summary: "删除检查点", Tag: "checkpoints", Header: []string{"If-Match"}, Response: api.CheckpointRef{}},
+	"POST /users/:username/sites/:id/checkpoints/:checkpoint_id/checkout": {Summary: "切换到检查点（可使用标签代替 ID）", Tag: "checkpoints", Header: []string{"If-Match"}, Response: api.CheckpointRef{}},
+	"GET /users/:username/analytics":                                      {Summary: "获取用户所有站点今日统计", Tag: "analytics", Response: map[string]api.DailyStats{}, Raw: true},
+	"GET /users/:username/sites/:id/analytics":                            {Summary: "获取站点统计（scope=full 返回历史）", Tag: "analytics", Response: api.DailyStats{}, Raw: true, Query: []string{"scope"}},
+	"POST /system/reload":                                                 {Summary: "热重载站点配置", Tag: "system", Response: api.ReloadResult{}},
+	"GET /system/health":                                                  {Summary: "健康检查", Tag: "system", Response: api.HealthStatus{}},
+	"GET /system/status":                                                  {Summary: "系统运行状态", Tag: "system", Response: api.SystemStatus{}},
+	"GET /accounts":                                                       {Summary: "列出管理员账户", Tag: "accounts", Response: api.AccountList{}},
+	"POST /accounts":                                                      {Summary: "创建管理员账户", Tag: "accounts", Request: api.CreateAccountRequest{}, Response: api.Account{}},
+	"GET /accounts/:name":                                                 {Summary: "获取管理员账户", Tag: "accounts", Response: api.Account{}},
+	"PUT /accounts/:name":                                                 {Summary: "更新管理员账户", Tag: "accounts", Request: api.UpdateAccountRequest{}, Response: api.Account{}},
+	"DELETE /accounts/:name":                                              {Summary: "删除管理员账户", Tag: "accounts"},
+	"GET /users":                                                          {Summary: "列出租户（含站点数量和用量）", Tag: "tenants", Response: api.TenantList{}},
+	"POST /users":                                                         {Summary: "创建租户", Tag: "tenants", Request: api.CreateTenantRequest{}, Response: api.Tenant{}},
+	"GET /users/:username":                                                {Summary: "获取租户详情", Tag: "tenants", Response: api.Tenant{}},
+	"PUT /users/:username":                                                {Summary: "更新租户元数据", Tag: "tenants", Request: api.UpdateTenantRequest{}, Response: api.Tenant{}},
+	"DELETE /users/:username":                                             {Summary: "删除租户及其站点、文件、检查点、统计和 Token", Tag: "tenants", Response: api.DeleteTenantResult{}},
+	"POST /users/:username/suspend":                                       {Summary: "停用租户（站点立即停止服务）", Tag: "tenants", Response: api.Tenant{}},
+	"POST /users/:username/resume":                                        {Summary: "恢复租户", Tag: "tenants", Response: api.Tenant{}},
+	"GET /users/:username/tokens":                                         {Summary: "列出 API Token", Tag: "tokens", Response: api.APITokenList{}},
+	"POST /users/:username/tokens":                                        {Summary: "创建 API Token（明文仅返回一次）", Tag: "tokens", Request: api.CreateTokenRequest{}, Response: api.CreatedToken{}},
+	"DELETE /users/:username/tokens/:token_id":                            {Summary: "吊销 API Token", Tag: "tokens"},
+	"GET /users/:username/jobs":                                           {Summary: "列出租户的异步任务", Tag: "jobs", Response: api.JobList{}},
+	"GET /audit":                                                          {Summary: "查询审计日志", Tag: "audit", Response: api.AuditList{}, Query: []string{"tenant", "site", "actor", "since", "until", "limit"}},
+	"GET /openapi.json":                                                   {Summary: "OpenAPI 文档", Tag: "system", Raw: true},
+	"GET /login":                                                          {Summary: "获取可用的登录方式", Tag: "session", Response: api.LoginOptions{}, Public: true},
+	"POST /login":                                                         {Summary: "登录管理界面（写入会话 Cookie）", Tag: "session", Request: api.LoginRequest{}, Response: api.SessionInfo{}, Public: true},
+	"GET /session":                                                        {Summary: "获取当前会话信息（含 CSRF Token）", Tag: "session", Response: api.SessionInfo{}},
+	"POST /logout":                                                        {Summary: "退出登录", Tag: "session"},
+	"GET /oidc/login":                                                     {Summary: "跳转到身份提供方进行单点登录", Tag: "session", Raw: true, Public: true},
+	"GET /oidc/callback":                                                  {Summary: "单点登录回调（成功后写入会话 Cookie 并跳转管理界面）", Tag: "session", Raw: true, Query: []string{"code", "state"}, Public: true},
+	"GET /jobs":                                                           {Summary: "列出所有异步任务", Tag: "jobs", Response: api.JobList{}, Query: []string{"tenant"}},
+	"GET /jobs/:job_id":                                                   {Summary: "获取异步任务状态", Tag: "jobs", Response: api.Job{}},
+	"GET /jobs/:job_id/events":                                            {Summary: "任务状态事件流（text/event-stream，任务结束后关闭）", Tag: "jobs", Raw: true},
+	"POST /users/:username/sites/:id/deploy/sessions":                     {Summary: "提交增量部署清单，返回需要上传的内容哈希", Tag: "deploy", Request: api.DeployManifestRequest{}, Response: api.DeploySession{}},
+	"GET /users/:username/sites/:id/deploy/sessions/:session_id":          {Summary: "获取增量部署会话状态", Tag: "deploy", Response: api.DeploySession{}},
+	"PUT /users/:username/sites/:id/deploy/sessions/:session_id/blobs/:hash": {Summary: "上传增量部署缺失的文件内容（校验 SHA-256）", Tag: "deploy", Upload: true, Response: api.DeploySession{}},
+	"POST /users/:username/sites/:id/deploy/sessions/:session_id/commit":     {Summary: "提交增量部署并替换站点目录", Tag: "deploy", Header: []string{"If-Match"}, Response: api.DeployResult{}},
+	"DELETE /users/:username/sites/:id/deploy/sessions/:session_id":          {Summary: "取消增量部署会话", Tag: "deploy"},
+	"GET /users/:username/sites/:id/checkpoints/retention":                   {Summary: "预览按保留策略将被删除的检查点（不实际删除）", Tag: "checkpoints", Response: api.RetentionPlan{}},
+	"POST /users/:username/sites/:id/checkpoints":                            {Summary: "为当前部署的站点内容手动创建检查点", Tag: "checkpoints", Request: api.CreateCheckpointRequest{}, Header: []string{"If-Match"}, Response: api.Checkpoint{}},
+	"PATCH /users/:username/sites/:id/checkpoints/:checkpoint_id":            {Summary: "修改检查点的备注、标签和固定状态", Tag: "checkpoints", Request: api.UpdateCheckpointRequest{}, Response: api.Checkpoint{}},
+
+	"GET /users/:username/sites/:id/checkpoints/:checkpoint_id/diff/:to": {Summary: "比较两个检查点的文件差异（可使用标签或 current，diff=1 时附带文本差异）", Tag: "checkpoints", Query: []string{"diff"}, Response: api.CheckpointDiff{}},
+}
+
+// OpenAPI 返回根据已注册路由生成的 OpenAPI 3 文档
+func (h *Handler) OpenAPI(c echo.Context) error {
+	spec, _ := BuildOpenAPI(c.Echo().Routes())
+	return c.JSON(http.StatusOK, spec)
+}
+
+// UndocumentedRoutes 返回已注册但未在 operationDocs 中登记的管理 API 路由
+func UndocumentedRoutes(routes []*echo.Route) []string {
+	_, missing := BuildOpenAPI(routes)
+	return missing
+}
+
+// StaleRouteEntries 返回 operationDocs 与 tokenScopes 中登记了、但未注册的管理 API 路由
+func StaleRouteEntries(routes []*echo.Route) []string {
+	registered := make(map[string]bool, len(routes))
+	for _, r := range routes {
+		if path, ok := strings.CutPrefix(r.Path, APIPrefix); ok {
+			registered[r.Method+" "+path] = true
+		}
+	}
+	var stale []string
+	for key := range operationDocs {
+		if !registered[key] {
+			stale = append(stale, key)
+		}
+	}
+	for key := range tokenScopes {
+		if !registered[key] {
+			stale = append(stale, "scope: "+key)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// BuildOpenAPI 根据 Echo 路由表生成 OpenAPI 3 文档，并返回缺少文档的路由
+func BuildOpenAPI(routes []*echo.Route) (map[string]any, []string) {
+	sg := &schemaGen{schemas: map[string]any{}}
+	sg.ref(reflect.TypeOf(api.Response{}))
+
+	paths := map[string]map[string]any{}
+	var missing []string
+
+	for _, r := range routes {
+		path, ok := strings.CutPrefix(r.Path, APIPrefix)
+		if !ok || !isHTTPMethod(r.Method) || strings.HasSuffix(path, "*") || path == "" {
+			continue
+		}
+		key := r.Method + " " + path
+
+		doc, documented := operationDocs[key]
+		if !documented {
+			missing = append(missing, key)
+			doc = operationDoc{Summary: "未登记文档的接口"}
+		}
+
+		oaPath := APIPrefix + toOpenAPIPath(path)
+		if paths[oaPath] == nil {
+			paths[oaPath] = map[string]any{}
+		}
+		paths[oaPath][strings.ToLower(r.Method)] = sg.operation(doc, path, tokenScopes[key])
+	}
+	sort.Strings(missing)
+
+	spec := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Pages Admin API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": sg.schemas,
+			"securitySchemes": map[string]any{
+				"basicAuth":  map[string]any{"type": "http", "scheme": "basic"},
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+				"cookieAuth": map[string]any{"type": "apiKey", "in": "cookie", "name": middleware.SessionCookieName},
+			},
+		},
+		"security": []any{
+			map[string]any{"basicAuth": []string{}},
+			map[string]any{"cookieAuth": []string{}},
+		},
+	}
+	return spec, missing
+}
+
+// operation 生成单个接口的 OpenAPI 描述
+// scope 为 API Token 访问所需的权限范围（取自 tokenScopes），为空表示不允许 Token 访问
+func (sg *schemaGen) operation(doc operationDoc, path, scope string) map[string]any {
+	op := map[string]any{"summary": doc.Summary}
+	if doc.Tag != "" {
+		op["tags"] = []string{doc.Tag}
+	}
+	if scope != "" {
+		op["x-required-scope"] = scope
+		op["security"] = []any{
+			map[string]any{"basicAuth": []string{}},
+			map[string]any{"cookieAuth": []string{}},
+			map[string]any{"bearerAuth": []string{}},
+		}
+	}
+	if doc.Public {
+		op["security"] = []any{}
+	}
+
+	var params []any
+	for _, seg := range strings.Split(path, "/") {
+		if name, ok := strings.CutPrefix(seg, ":"); ok {
+			params = append(params, map[string]any{
+				"name": name, "in": "path", "required": true,
+				"schema": map[string]any{"type": "string"},
+			})
+		}
+	}
+	for _, q := range doc.Query {
+		params = append(params, map[string]any{
+			"name": q, "in": "query",
+			"schema": map[string]any{"type": "string"},
+		})
+	}
+	for _, h := range doc.Header {
+		params = append(params, map[string]any{
+			"name": h, "in": "header",
+			"schema": map[string]any{"type": "string"},
+		})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	switch {
+	case doc.Upload:
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"multipart/form-data": map[string]any{
+					"schema": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"file": map[string]any{"type": "string", "format": "binary"},
+						},
+					},
+				},
+				"application/octet-stream": map[string]any{
+					"schema": map[string]any{"type": "string", "format": "binary"},
+				},
+			},
+		}
+	case doc.Request != nil:
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": sg.ref(reflect.TypeOf(doc.Request))},
+			},
+		}
+	}
+
+	var schema map[string]any
+	switch {
+	case doc.Raw && doc.Response != nil:
+		schema = sg.ref(reflect.TypeOf(doc.Response))
+	case doc.Raw:
+		schema = map[string]any{"type": "object"}
+	case doc.Response != nil:
+		schema = map[string]any{
+			"allOf": []any{
+				sg.ref(reflect.TypeOf(api.Response{})),
+				map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"data": sg.ref(reflect.TypeOf(doc.Response))},
+				},
+			},
+		}
+	default:
+		schema = sg.ref(reflect.TypeOf(api.Response{}))
+	}
+
+	op["responses"] = map[string]any{
+		"200": map[string]any{
+			"description": "成功",
+			"content":     map[string]any{"application/json": map[string]any{"schema": schema}},
+		},
+		"default": map[string]any{
+			"description": "错误",
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": sg.ref(reflect.TypeOf(api.Response{}))},
+			},
+		},
+	}
+	return op
+}
+
+// schemaGen 基于反射从 Go 类型生成 JSON Schema
+type schemaGen struct {
+	schemas map[string]any // components.schemas
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ref 返回类型的 schema，具名结构体注册到 components 并返回引用
+func (sg *schemaGen) ref(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		name := t.Name()
+		if _, ok := sg.schemas[name]; !ok {
+			sg.schemas[name] = map[string]any{} // 占位，防止递归
+			sg.schemas[name] = sg.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": sg.ref(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": sg.ref(t.Elem())}
+	case reflect.Struct:
+		return sg.structSchema(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+// structSchema 根据 json 标签生成结构体 schema
+func (sg *schemaGen) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			// 内嵌结构体字段提升到上层
+			if embedded, ok := sg.structSchema(f.Type)["properties"].(map[string]any); ok {
+				for k, v := range embedded {
+					props[k] = v
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = sg.ref(f.Type)
+	}
+	return map[string]any{"type": "object", "properties": props}
+}
+
+// toOpenAPIPath 将 Echo 路径参数 :name 转换为 {name}
+func toOpenAPIPath(path string) string {
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if name, ok := strings.CutPrefix(seg, ":"); ok {
+			segs[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// isHTTPMethod 过滤 Echo 内部使用的伪方法（如 RouteNotFound）
+func isHTTPMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
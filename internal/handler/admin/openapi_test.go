@@ -0,0 +1,74 @@
+package admin
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/pkg/api"
+)
+
+// 完整路由表的文档与权限范围校验见 internal/server 的测试（使用真实的路由注册）
+
+func TestUndocumentedRoutesReportsMissing(t *testing.T) {
+	e := echo.New()
+	e.GET(APIPrefix+"/not-documented", func(c echo.Context) error { return nil })
+	e.GET("/outside-api", func(c echo.Context) error { return nil })
+
+	missing := UndocumentedRoutes(e.Routes())
+	if len(missing) != 1 || missing[0] != "GET /not-documented" {
+		t.Fatalf("UndocumentedRoutes = %v, want [GET /not-documented]", missing)
+	}
+}
+
+func TestStaleRouteEntriesReportsUnregistered(t *testing.T) {
+	e := echo.New()
+	e.GET(APIPrefix+"/users/:username/sites", func(c echo.Context) error { return nil })
+
+	stale := StaleRouteEntries(e.Routes())
+	if slices.Contains(stale, "GET /users/:username/sites") || slices.Contains(stale, "scope: GET /users/:username/sites") {
+		t.Errorf("已注册的路由被报告为未注册: %v", stale)
+	}
+	if !slices.Contains(stale, "GET /audit") || !slices.Contains(stale, "scope: GET /users/:username/sites/:id") {
+		t.Errorf("StaleRouteEntries = %v, want 包含未注册的文档和权限范围", stale)
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         string
+	}{
+		{"GET", APIPrefix + "/users/:username/sites", api.ScopeSiteRead},
+		{"POST", APIPrefix + "/users/:username/sites/:id/deploy", api.ScopeSiteDeploy},
+		{"POST", APIPrefix + "/users/:username/sites/:id/checkpoints/:checkpoint_id/checkout", api.ScopeCheckpointWrite},
+		{"GET", APIPrefix + "/users/:username/analytics", api.ScopeAnalyticsRead},
+		// 未登记的路由不允许 Token 访问，即使已登记文档
+		{"POST", APIPrefix + "/users/:username/sites", ""},
+		{"GET", APIPrefix + "/audit", ""},
+		{"GET", "/users/:username/sites", ""},
+	}
+	for _, tt := range tests {
+		if got := RequiredScope(tt.method, tt.path); got != tt.want {
+			t.Errorf("RequiredScope(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestOpenAPIScopeFromPolicyTable(t *testing.T) {
+	e := echo.New()
+	e.GET(APIPrefix+"/users/:username/sites/:id", func(c echo.Context) error { return nil })
+	e.POST(APIPrefix+"/users", func(c echo.Context) error { return nil })
+
+	spec, _ := BuildOpenAPI(e.Routes())
+	paths := spec["paths"].(map[string]map[string]any)
+	get := paths[APIPrefix+"/users/{username}/sites/{id}"]["get"].(map[string]any)
+	if get["x-required-scope"] != api.ScopeSiteRead {
+		t.Errorf("x-required-scope = %v, want %s", get["x-required-scope"], api.ScopeSiteRead)
+	}
+	post := paths[APIPrefix+"/users"]["post"].(map[string]any)
+	if _, ok := post["x-required-scope"]; ok {
+		t.Error("不允许 Token 访问的接口不应声明 x-required-scope")
+	}
+}
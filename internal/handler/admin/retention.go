@@ -0,0 +1,155 @@
+package admin
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/handler/deploy"
+	"pages/internal/site"
+	"pages/pkg/api"
+)
+
+// SetDefaultRetention 设置服务器默认的检查点保留策略（站点未单独设置时使用）
+func (h *Handler) SetDefaultRetention(policy deploy.Retention) {
+	h.defaultRetention = policy
+}
+
+// retentionFor 返回站点生效的保留策略，inherited 表示使用的是服务器默认策略
+func (h *Handler) retentionFor(s *site.Site) (policy deploy.Retention, inherited bool) {
+	if s != nil && s.Retention != nil {
+		return *s.Retention, false
+	}
+	return h.defaultRetention, true
+}
+
+// siteRetention 按租户和站点 ID 返回生效的保留策略（供检查点管理器在创建检查点后使用）
+func (h *Handler) siteRetention(username, siteID string) deploy.Retention {
+	s, err := h.siteManager.GetFullSiteByIDForUser(username, siteID)
+	if err != nil {
+		// 无法确定站点策略时不删除任何检查点
+		return deploy.Retention{}
+	}
+	policy, _ := h.retentionFor(s)
+	return policy
+}
+
+// GetRetentionPlan 预览按保留策略将被删除的检查点（不实际删除）
+func (h *Handler) GetRetentionPlan(c echo.Context) error {
+	username := c.Param("username")
+	id := c.Param("id")
+
+	s, err := h.siteManager.GetFullSiteByIDForUser(username, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("获取站点失败: %v", err),
+		})
+	}
+	if s == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgSiteNotFound,
+		})
+	}
+
+	metadata, err := h.checkpointManager.ListCheckpoints(username, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("获取检查点列表失败: %v", err),
+		})
+	}
+
+	policy, inherited := h.retentionFor(s)
+	prune := deploy.PlanRetention(metadata.Checkpoints, metadata.Current, policy, time.Now())
+	if prune == nil {
+		prune = []deploy.Checkpoint{}
+	}
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: api.RetentionPlan{
+			Policy:    policy,
+			Inherited: inherited,
+			Keep:      len(metadata.Checkpoints) - len(prune),
+			Prune:     prune,
+		},
+	})
+}
+
+// StartRetentionSweeper 启动后台清理任务，每隔 interval 按保留策略清理所有站点的检查点
+func (h *Handler) StartRetentionSweeper(interval time.Duration) {
+	h.sweepStop = make(chan struct{})
+	h.sweepWG.Add(1)
+	go func() {
+		defer h.sweepWG.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		h.SweepRetention()
+		for {
+			select {
+			case <-ticker.C:
+				h.SweepRetention()
+			case <-h.sweepStop:
+				return
+			}
+		}
+	}()
+}
+
+// StopRetentionSweeper 停止后台清理任务
+func (h *Handler) StopRetentionSweeper() {
+	if h.sweepStop == nil {
+		return
+	}
+	close(h.sweepStop)
+	h.sweepWG.Wait()
+	h.sweepStop = nil
+}
+
+// SweepRetention 按保留策略清理所有站点的检查点，返回删除的检查点数量
+func (h *Handler) SweepRetention() int {
+	sites, err := h.siteManager.ListAll()
+	if err != nil {
+		slog.Error("按保留策略清理检查点失败", "error", err)
+		return 0
+	}
+
+	count := 0
+	for _, s := range sites {
+		policy, _ := h.retentionFor(s)
+		if !policy.Enabled() {
+			continue
+		}
+		pruned, err := h.pruneSite(s, policy)
+		if err != nil {
+			slog.Error("按保留策略清理检查点失败", "tenant", s.Username, "site", s.ID, "error", err)
+		}
+		if len(pruned) > 0 {
+			slog.Info("已按保留策略删除检查点", "tenant", s.Username, "site", s.ID, "count", len(pruned))
+		}
+		count += len(pruned)
+	}
+	return count
+}
+
+// pruneSite 在站点锁内按保留策略删除检查点并重算存储用量
+func (h *Handler) pruneSite(s *site.Site, policy deploy.Retention) ([]deploy.Checkpoint, error) {
+	rootDir := h.initializer.SiteDir(s)
+	unlock, err := h.locks.Lock(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	pruned, err := h.checkpointManager.Prune(s.Username, s.ID, policy)
+	if len(pruned) > 0 {
+		h.checkpointManager.PruneReleases(s.Username, s.ID, rootDir)
+		_ = h.checkpointManager.StorageRecount(s.Username, s.ID, rootDir)
+	}
+	return pruned, err
+}
@@ -18,6 +18,11 @@ func (h *Handler) RegisterRoutes(g *echo.Group) {
 
 	// 站点部署管理
 	userGroup.POST("/sites/:id/deploy", h.DeploySite)
+	userGroup.POST("/sites/:id/deploy/sessions", h.CreateDeploySession)
+	userGroup.GET("/sites/:id/deploy/sessions/:session_id", h.GetDeploySession)
+	userGroup.PUT("/sites/:id/deploy/sessions/:session_id/blobs/:hash", h.UploadDeployBlob)
+	userGroup.POST("/sites/:id/deploy/sessions/:session_id/commit", h.CommitDeploySession)
+	userGroup.DELETE("/sites/:id/deploy/sessions/:session_id", h.CancelDeploySession)
 	userGroup.GET("/sites/:id/usage", h.GetSiteUsage)
 	
 	// 用户用量
@@ -25,12 +30,25 @@ func (h *Handler) RegisterRoutes(g *echo.Group) {
 
 	// 检查点管理
 	userGroup.GET("/sites/:id/checkpoints", h.ListCheckpoints)
+	userGroup.POST("/sites/:id/checkpoints", h.CreateCheckpoint)
+	userGroup.GET("/sites/:id/checkpoints/retention", h.GetRetentionPlan)
 	userGroup.GET("/sites/:id/checkpoints/:checkpoint_id", h.GetCheckpoint)
+	userGroup.PATCH("/sites/:id/checkpoints/:checkpoint_id", h.UpdateCheckpoint)
 	userGroup.DELETE("/sites/:id/checkpoints/:checkpoint_id", h.DeleteCheckpoint)
 	userGroup.POST("/sites/:id/checkpoints/:checkpoint_id/checkout", h.CheckoutCheckpoint)
+	userGroup.GET("/sites/:id/checkpoints/:checkpoint_id/diff/:to", h.DiffCheckpoints)
+
+	// 站点回收站
+	userGroup.GET("/trash", h.ListTrash)
+	userGroup.POST("/trash/:trash_id/restore", h.RestoreTrashedSite)
+	userGroup.DELETE("/trash/:trash_id", h.PurgeTrashedSite)
 
 	// 系统管理
 	systemGroup := g.Group("/system")
 	systemGroup.POST("/reload", h.Reload)
 	systemGroup.GET("/health", h.Health)
+	systemGroup.GET("/status", h.Status)
+
+	// OpenAPI 文档
+	g.GET("/openapi.json", h.OpenAPI)
 }
@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"strings"
+
+	"pages/pkg/api"
+)
+
+// tokenScopes API Token 可访问的路由及所需的权限范围，键为 "METHOD /path"（相对于 APIPrefix）
+// 未登记的路由不允许 Token 访问。权限校验（RequiredScope）与 OpenAPI 文档中的 x-required-scope 均取自此表，
+// 与接口文档表 operationDocs 分开维护，文档的增删不会改变访问控制
+var tokenScopes = map[string]string{
+	"GET /users/:username/sites":                                             api.ScopeSiteRead,
+	"GET /users/:username/sites/:id":                                         api.ScopeSiteRead,
+	"POST /users/:username/sites/:id/deploy":                                 api.ScopeSiteDeploy,
+	"GET /users/:username/sites/:id/usage":                                   api.ScopeSiteRead,
+	"GET /users/:username/usage":                                             api.ScopeSiteRead,
+	"GET /users/:username/sites/:id/checkpoints":                             api.ScopeSiteRead,
+	"GET /users/:username/sites/:id/checkpoints/:checkpoint_id":              api.ScopeSiteRead,
+	"DELETE /users/:username/sites/:id/checkpoints/:checkpoint_id":           api.ScopeCheckpointWrite,
+	"POST /users/:username/sites/:id/checkpoints/:checkpoint_id/checkout":    api.ScopeCheckpointWrite,
+	"GET /users/:username/analytics":                                         api.ScopeAnalyticsRead,
+	"GET /users/:username/sites/:id/analytics":                               api.ScopeAnalyticsRead,
+	"GET /users/:username/jobs":                                              api.ScopeSiteRead,
+	"POST /users/:username/sites/:id/deploy/sessions":                        api.ScopeSiteDeploy,
+	"GET /users/:username/sites/:id/deploy/sessions/:session_id":             api.ScopeSiteDeploy,
+	"PUT /users/:username/sites/:id/deploy/sessions/:session_id/blobs/:hash": api.ScopeSiteDeploy,
+	"POST /users/:username/sites/:id/deploy/sessions/:session_id/commit":     api.ScopeSiteDeploy,
+	"DELETE /users/:username/sites/:id/deploy/sessions/:session_id":          api.ScopeSiteDeploy,
+	"GET /users/:username/sites/:id/checkpoints/retention":                   api.ScopeSiteRead,
+	"POST /users/:username/sites/:id/checkpoints":                            api.ScopeCheckpointWrite,
+	"PATCH /users/:username/sites/:id/checkpoints/:checkpoint_id":            api.ScopeCheckpointWrite,
+	"GET /users/:username/sites/:id/checkpoints/:checkpoint_id/diff/:to":     api.ScopeSiteRead,
+}
+
+// RequiredScope 返回路由（Echo 路由路径）要求的 API Token 权限范围，为空表示不允许 Token 访问
+func RequiredScope(method, path string) string {
+	path, ok := strings.CutPrefix(path, APIPrefix)
+	if !ok {
+		return ""
+	}
+	return tokenScopes[method+" "+path]
+}
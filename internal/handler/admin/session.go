@@ -0,0 +1,160 @@
+package admin
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/auth"
+	"pages/internal/middleware"
+	"pages/pkg/api"
+)
+
+// SessionHandler 管理界面登录会话接口处理器
+type SessionHandler struct {
+	users        *auth.UserStore
+	sessions     *auth.SessionStore
+	limiter      *auth.Limiter
+	oidc         *auth.OIDCProvider // 未启用单点登录时为 nil
+	secureCookie bool
+}
+
+// NewSessionHandler 创建会话接口处理器
+func NewSessionHandler(users *auth.UserStore, sessions *auth.SessionStore, limiter *auth.Limiter, oidc *auth.OIDCProvider, secureCookie bool) *SessionHandler {
+	return &SessionHandler{
+		users:        users,
+		sessions:     sessions,
+		limiter:      limiter,
+		oidc:         oidc,
+		secureCookie: secureCookie,
+	}
+}
+
+// RegisterRoutes 注册会话路由（/login 与 /oidc/* 需在认证中间件中登记为公开路由）
+func (h *SessionHandler) RegisterRoutes(g *echo.Group) {
+	g.GET("/login", h.LoginOptions)
+	g.POST("/login", h.Login)
+	g.GET("/session", h.GetSession)
+	g.POST("/logout", h.Logout)
+	g.GET("/oidc/login", h.OIDCLogin)
+	g.GET("/oidc/callback", h.OIDCCallback)
+}
+
+// LoginOptions 返回可用的登录方式
+func (h *SessionHandler) LoginOptions(c echo.Context) error {
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    api.LoginOptions{OIDC: h.oidc != nil},
+	})
+}
+
+// Login 使用用户名和密码登录，成功后写入会话 Cookie
+func (h *SessionHandler) Login(c echo.Context) error {
+	if !sameOrigin(c) {
+		return c.JSON(http.StatusForbidden, Response{
+			Success: false,
+			Message: "请求来源不受信任",
+		})
+	}
+
+	var req api.LoginRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: api.MsgInvalidRequest,
+		})
+	}
+	if req.Username == "" {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: api.MsgUsernameRequired,
+		})
+	}
+
+	ip := c.RealIP()
+	if wait := h.limiter.Check(ip, req.Username); wait > 0 {
+		return middleware.TooManyAttempts(c, wait)
+	}
+
+	u, ok := h.users.Authenticate(req.Username, req.Password)
+	if !ok {
+		if wait := h.limiter.Failure(ip, req.Username); wait > 0 {
+			return middleware.TooManyAttempts(c, wait)
+		}
+		return c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Message: "用户名或密码错误",
+		})
+	}
+	h.limiter.Success(ip, req.Username)
+
+	p := auth.PrincipalFromUser(u, auth.MethodSession)
+	sess, err := h.sessions.Create(p)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: "创建会话失败",
+		})
+	}
+
+	middleware.SetSessionCookie(c, sess, h.secureCookie)
+	middleware.SetPrincipal(c, p)
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "登录成功",
+		Data:    sessionInfo(p, sess),
+	})
+}
+
+// GetSession 获取当前会话信息（含 CSRF Token）
+func (h *SessionHandler) GetSession(c echo.Context) error {
+	sess := middleware.CurrentSession(c)
+	if sess == nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: "当前请求未使用会话认证",
+		})
+	}
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    sessionInfo(middleware.CurrentPrincipal(c), sess),
+	})
+}
+
+// Logout 注销当前会话并清除 Cookie
+func (h *SessionHandler) Logout(c echo.Context) error {
+	if sess := middleware.CurrentSession(c); sess != nil {
+		h.sessions.Delete(sess.ID)
+	}
+	middleware.ClearSessionCookie(c, h.secureCookie)
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "已退出登录",
+	})
+}
+
+// sessionInfo 构建会话信息
+func sessionInfo(p *auth.Principal, sess *auth.Session) api.SessionInfo {
+	return api.SessionInfo{
+		Username:  p.Username,
+		Role:      p.Role,
+		Tenant:    p.Tenant,
+		Method:    sess.Method,
+		CSRFToken: sess.CSRFToken,
+		ExpiresAt: sess.ExpiresAt,
+	}
+}
+
+// sameOrigin 校验浏览器请求的 Origin 与 Host 一致（未携带 Origin 的非浏览器请求放行）
+func sameOrigin(c echo.Context) bool {
+	origin := c.Request().Header.Get(echo.HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == c.Request().Host
+}
@@ -2,12 +2,16 @@ package admin
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/labstack/echo/v4"
 
+	"pages/internal/middleware"
 	"pages/internal/site"
+	"pages/internal/tenant"
+	"pages/pkg/api"
 )
 
 // ListUserSites 列出指定用户的所有站点
@@ -16,7 +20,7 @@ func (h *Handler) ListUserSites(c echo.Context) error {
 	if username == "" {
 		return c.JSON(http.StatusBadRequest, Response{
 			Success: false,
-			Message: "用户名不能为空",
+			Message: api.MsgUsernameRequired,
 		})
 	}
 
@@ -38,11 +42,7 @@ func (h *Handler) ListUserSites(c echo.Context) error {
 }
 
 // CreateSiteRequest 创建站点请求
-type CreateSiteRequest struct {
-	ID     string `json:"id" validate:"required"`
-	Domain string `json:"domain" validate:"required"`
-	Index  string `json:"index"`
-}
+type CreateSiteRequest = api.CreateSiteRequest
 
 // CreateUserSite 为指定用户创建站点
 func (h *Handler) CreateUserSite(c echo.Context) error {
@@ -50,7 +50,7 @@ func (h *Handler) CreateUserSite(c echo.Context) error {
 	if username == "" {
 		return c.JSON(http.StatusBadRequest, Response{
 			Success: false,
-			Message: "用户名不能为空",
+			Message: api.MsgUsernameRequired,
 		})
 	}
 
@@ -58,7 +58,7 @@ func (h *Handler) CreateUserSite(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, Response{
 			Success: false,
-			Message: "请求参数错误",
+			Message: api.MsgInvalidRequest,
 		})
 	}
 
@@ -68,6 +68,24 @@ func (h *Handler) CreateUserSite(c echo.Context) error {
 			Message: "id 和 domain 为必填字段",
 		})
 	}
+	if err := tenant.ValidateUsername(username); err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: fmt.Sprintf("创建站点失败: %v", err),
+		})
+	}
+
+	if err := h.quota.CheckSiteCount(username); err != nil {
+		return quotaFailed(c, "创建站点失败", err)
+	}
+
+	// 首次在租户下创建站点时自动登记租户
+	if _, err := h.tenants.Ensure(username); err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("登记租户失败: %v", err),
+		})
+	}
 
 	// 创建站点（路径自动生成）
 	s := site.NewSiteForUser(req.ID, req.Domain, username)
@@ -86,6 +104,7 @@ func (h *Handler) CreateUserSite(c echo.Context) error {
 	if h.initializer != nil {
 		_ = h.initializer.InitializeSites([]*site.Site{s})
 	}
+	middleware.AuditChange(c, nil, siteSummary(s))
 
 	return c.JSON(http.StatusCreated, Response{
 		Success: true,
@@ -109,7 +128,7 @@ func (h *Handler) GetSite(c echo.Context) error {
 	if s == nil {
 		return c.JSON(http.StatusNotFound, Response{
 			Success: false,
-			Message: "站点不存在",
+			Message: api.MsgSiteNotFound,
 		})
 	}
 
@@ -120,11 +139,7 @@ func (h *Handler) GetSite(c echo.Context) error {
 }
 
 // UpdateSiteRequest 更新站点请求
-type UpdateSiteRequest struct {
-	Domain  string `json:"domain"`
-	Index   string `json:"index"`
-	Enabled *bool  `json:"enabled"`
-}
+type UpdateSiteRequest = api.UpdateSiteRequest
 
 // UpdateSite 更新站点
 func (h *Handler) UpdateSite(c echo.Context) error {
@@ -141,7 +156,7 @@ func (h *Handler) UpdateSite(c echo.Context) error {
 	if s == nil {
 		return c.JSON(http.StatusNotFound, Response{
 			Success: false,
-			Message: "站点不存在",
+			Message: api.MsgSiteNotFound,
 		})
 	}
 
@@ -149,10 +164,12 @@ func (h *Handler) UpdateSite(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, Response{
 			Success: false,
-			Message: "请求参数错误",
+			Message: api.MsgInvalidRequest,
 		})
 	}
 
+	before := siteSummary(s)
+
 	// 更新字段（不允许修改ID、Username、RootDir）
 	if req.Domain != "" {
 		s.Domain = req.Domain
@@ -163,6 +180,36 @@ func (h *Handler) UpdateSite(c echo.Context) error {
 	if req.Enabled != nil {
 		s.Enabled = *req.Enabled
 	}
+	if req.Limits != nil {
+		// 站点级配额可覆盖租户配额，仅超级管理员可修改
+		if !isSuperAdmin(c) {
+			return c.JSON(http.StatusForbidden, Response{
+				Success: false,
+				Message: "仅超级管理员可修改站点配额",
+			})
+		}
+		if *req.Limits == (site.Limits{}) {
+			s.Limits = nil
+		} else {
+			limits := *req.Limits
+			s.Limits = &limits
+		}
+	}
+	if req.Retention != nil {
+		r := req.Retention
+		if r.KeepLast < 0 || r.KeepDays < 0 || r.KeepDaily < 0 || r.KeepWeekly < 0 {
+			return c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Message: "保留策略的数值不能为负数",
+			})
+		}
+		if *r == (site.Retention{}) {
+			s.Retention = nil
+		} else {
+			retention := *r
+			s.Retention = &retention
+		}
+	}
 	s.UpdatedAt = time.Now()
 
 	if err := h.siteManager.Update(s); err != nil {
@@ -172,6 +219,8 @@ func (h *Handler) UpdateSite(c echo.Context) error {
 		})
 	}
 
+	middleware.AuditChange(c, before, siteSummary(s))
+
 	return c.JSON(http.StatusOK, Response{
 		Success: true,
 		Message: "站点更新成功",
@@ -183,16 +232,69 @@ func (h *Handler) UpdateSite(c echo.Context) error {
 func (h *Handler) DeleteSite(c echo.Context) error {
 	username := c.Param("username")
 	id := c.Param("id")
+	purge := c.QueryParam("purge") == "true"
 
-	if err := h.siteManager.RemoveForUser(username, id); err != nil {
+	s, err := h.siteManager.GetFullSiteByIDForUser(username, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("删除站点失败: %v", err),
+		})
+	}
+	if s == nil {
 		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgSiteNotFound,
+		})
+	}
+	middleware.AuditChange(c, siteSummary(s), nil)
+
+	// 等待进行中的部署或检查点切换完成，避免其在站点移入回收站后继续写入
+	unlock, err := h.lockSite(s, "")
+	if err != nil {
+		return deployFailed(c, err)
+	}
+	defer unlock()
+
+	if err := h.siteManager.RemoveForUser(username, id); err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
 			Message: fmt.Sprintf("删除站点失败: %v", err),
 		})
 	}
 
+	// 统计 Worker 停止时会写入最后一次快照，需在移动统计目录之前完成
+	h.analytics.StopSite(username, id)
+
+	entry, err := h.trash.Put(apiSite(s), actorName(c), h.siteDataDirs(s))
+	if err != nil {
+		// 数据仍在原位置，恢复站点配置
+		if addErr := h.siteManager.Add(s); addErr != nil {
+			slog.Error("删除站点失败后恢复站点配置失败", "tenant", username, "site", id, "error", addErr)
+		}
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("删除站点失败: %v", err),
+		})
+	}
+
+	if purge || h.trash.Retention() == 0 {
+		if err := h.trash.Purge(username, entry.TrashID); err != nil {
+			return c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: fmt.Sprintf("站点已移入回收站，但彻底删除失败: %v", err),
+				Data:    entry,
+			})
+		}
+		return c.JSON(http.StatusOK, Response{
+			Success: true,
+			Message: "站点已彻底删除",
+		})
+	}
+
 	return c.JSON(http.StatusOK, Response{
 		Success: true,
-		Message: "站点删除成功",
+		Message: "站点已移入回收站",
+		Data:    entry,
 	})
 }
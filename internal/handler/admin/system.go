@@ -8,6 +8,7 @@ import (
 	"github.com/labstack/echo/v4"
 
 	"pages/internal/site"
+	"pages/pkg/api"
 )
 
 // Reload 热重载站点配置
@@ -41,21 +42,42 @@ func (h *Handler) Reload(c echo.Context) error {
 	return c.JSON(http.StatusOK, Response{
 		Success: true,
 		Message: fmt.Sprintf("重载成功，当前 %d 个站点已生效", len(sites)),
-		Data: map[string]any{
-			"sites_count": len(sites),
-			"reloaded_at": time.Now(),
+		Data: api.ReloadResult{
+			SitesCount: len(sites),
+			ReloadedAt: time.Now(),
 		},
 	})
 }
 
-// Health 健康检查
+// Health 健康检查（对所有已认证主体开放，仅返回存活状态）
 func (h *Handler) Health(c echo.Context) error {
 	return c.JSON(http.StatusOK, Response{
 		Success: true,
-		Data: map[string]any{
-			"status":      "healthy",
-			"sites_count": h.siteManager.Count(),
-			"timestamp":   time.Now(),
+		Data: api.HealthStatus{
+			Status:    "healthy",
+			Timestamp: time.Now(),
 		},
 	})
 }
+
+// Status 系统运行状态（站点数量与登录限流统计），仅超级管理员可访问
+func (h *Handler) Status(c echo.Context) error {
+	if !isSuperAdmin(c) {
+		return c.JSON(http.StatusForbidden, Response{
+			Success: false,
+			Message: "仅超级管理员可执行该操作",
+		})
+	}
+
+	status := api.SystemStatus{
+		SitesCount: h.siteManager.Count(),
+		Timestamp:  time.Now(),
+	}
+	if h.loginLimiter != nil {
+		status.Login = h.loginLimiter.Stats()
+	}
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    status,
+	})
+}
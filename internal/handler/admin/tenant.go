@@ -0,0 +1,465 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/analytics"
+	"pages/internal/auth"
+	"pages/internal/handler/deploy"
+	"pages/internal/jobs"
+	"pages/internal/middleware"
+	"pages/internal/site"
+	"pages/internal/tenant"
+	"pages/internal/trash"
+	"pages/pkg/api"
+)
+
+// TenantHandler 租户管理接口处理器
+type TenantHandler struct {
+	tenants     *tenant.Store
+	sm          *site.ManagerLockFree
+	am          *analytics.Manager
+	users       *auth.UserStore
+	sessions    *auth.SessionStore
+	tokens      *auth.TokenStore
+	checkpoints *deploy.CheckpointManager
+	trash       *trash.Bin
+	jobs        *jobs.Manager
+	locks       *deploy.SiteLocker // 与 Handler 共用的站点修改锁
+	sitesDir    string
+}
+
+// NewTenantHandler 创建租户管理接口处理器
+// checkpoints 与 locks 应与 Handler 共用（Handler.CheckpointManager、Handler.SiteLocker）
+func NewTenantHandler(tenants *tenant.Store, sm *site.ManagerLockFree, am *analytics.Manager, users *auth.UserStore, sessions *auth.SessionStore, tokens *auth.TokenStore, bin *trash.Bin, jm *jobs.Manager, locks *deploy.SiteLocker, checkpoints *deploy.CheckpointManager, sitesDir string) *TenantHandler {
+	return &TenantHandler{
+		tenants:     tenants,
+		sm:          sm,
+		am:          am,
+		users:       users,
+		sessions:    sessions,
+		tokens:      tokens,
+		checkpoints: checkpoints,
+		trash:       bin,
+		jobs:        jm,
+		locks:       locks,
+		sitesDir:    sitesDir,
+	}
+}
+
+// RegisterRoutes 注册租户管理路由
+// 修改、停用、恢复和删除租户仅超级管理员可用（租户管理员只能查看自己的租户）
+func (h *TenantHandler) RegisterRoutes(g *echo.Group) {
+	g.GET("/users", h.ListTenants)
+	g.POST("/users", h.CreateTenant)
+	g.GET("/users/:username", h.GetTenant)
+	g.PUT("/users/:username", h.UpdateTenant)
+	g.DELETE("/users/:username", h.DeleteTenant)
+	g.POST("/users/:username/suspend", h.SuspendTenant)
+	g.POST("/users/:username/resume", h.ResumeTenant)
+}
+
+// ListTenants 列出所有租户及站点数量、用量
+func (h *TenantHandler) ListTenants(c echo.Context) error {
+	sites, err := h.sm.ListAll()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("获取站点列表失败: %v", err),
+		})
+	}
+
+	byUser := make(map[string][]*site.Site)
+	for _, s := range sites {
+		byUser[s.Username] = append(byUser[s.Username], s)
+	}
+
+	list := h.tenants.List()
+	tenants := make([]api.Tenant, len(list))
+	for i, t := range list {
+		tenants[i] = h.tenantInfo(t, byUser[t.Username])
+	}
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: api.TenantList{
+			Tenants: tenants,
+			Total:   len(tenants),
+		},
+	})
+}
+
+// CreateTenant 创建租户
+func (h *TenantHandler) CreateTenant(c echo.Context) error {
+	var req api.CreateTenantRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: api.MsgInvalidRequest,
+		})
+	}
+	if err := tenant.ValidateUsername(req.Username); err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: fmt.Sprintf("创建租户失败: %v", err),
+		})
+	}
+
+	t := tenant.New(req.Username)
+	t.DisplayName = req.DisplayName
+	t.Contact = req.Contact
+	t.Limits = req.Limits
+
+	if err := h.tenants.Add(t); err != nil {
+		return c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Message: fmt.Sprintf("创建租户失败: %v", err),
+		})
+	}
+	middleware.AuditChange(c, nil, tenantSummary(t))
+
+	return c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Message: "租户创建成功",
+		Data:    h.tenantInfo(t, nil),
+	})
+}
+
+// GetTenant 获取租户详情
+func (h *TenantHandler) GetTenant(c echo.Context) error {
+	t, sites, err := h.load(c.Param("username"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("获取租户失败: %v", err),
+		})
+	}
+	if t == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgTenantNotFound,
+		})
+	}
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    h.tenantInfo(t, sites),
+	})
+}
+
+// UpdateTenant 更新租户元数据（显示名、联系方式、配额）
+func (h *TenantHandler) UpdateTenant(c echo.Context) error {
+	if !isSuperAdmin(c) {
+		return c.JSON(http.StatusForbidden, Response{
+			Success: false,
+			Message: "仅超级管理员可执行该操作",
+		})
+	}
+
+	t, sites, err := h.load(c.Param("username"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("获取租户失败: %v", err),
+		})
+	}
+	if t == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgTenantNotFound,
+		})
+	}
+
+	var req api.UpdateTenantRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: api.MsgInvalidRequest,
+		})
+	}
+
+	before := tenantSummary(t)
+	if req.DisplayName != nil {
+		t.DisplayName = *req.DisplayName
+	}
+	if req.Contact != nil {
+		t.Contact = *req.Contact
+	}
+	if req.Limits != nil {
+		t.Limits = *req.Limits
+	}
+	t.UpdatedAt = time.Now()
+
+	if err := h.tenants.Update(t); err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("更新租户失败: %v", err),
+		})
+	}
+	middleware.AuditChange(c, before, tenantSummary(t))
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "租户更新成功",
+		Data:    h.tenantInfo(t, sites),
+	})
+}
+
+// SuspendTenant 停用租户：其所有站点立即停止服务，非超级管理员无法再访问该租户
+func (h *TenantHandler) SuspendTenant(c echo.Context) error {
+	return h.setSuspended(c, true)
+}
+
+// ResumeTenant 恢复租户：按各站点原有的启用状态恢复服务
+func (h *TenantHandler) ResumeTenant(c echo.Context) error {
+	return h.setSuspended(c, false)
+}
+
+// setSuspended 停用或恢复租户
+func (h *TenantHandler) setSuspended(c echo.Context, suspended bool) error {
+	if !isSuperAdmin(c) {
+		return c.JSON(http.StatusForbidden, Response{
+			Success: false,
+			Message: "仅超级管理员可执行该操作",
+		})
+	}
+
+	t, sites, err := h.load(c.Param("username"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("获取租户失败: %v", err),
+		})
+	}
+	if t == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgTenantNotFound,
+		})
+	}
+
+	before := tenantSummary(t)
+	now := time.Now()
+	t.Suspended = suspended
+	t.UpdatedAt = now
+	if suspended {
+		t.SuspendedAt = &now
+	} else {
+		t.SuspendedAt = nil
+	}
+
+	// 先持久化状态，再切换站点快照，避免重启后状态不一致
+	if err := h.tenants.Update(t); err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("更新租户失败: %v", err),
+		})
+	}
+	if suspended {
+		h.sm.SuspendUser(t.Username)
+	} else if err := h.sm.ResumeUser(t.Username); err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("恢复站点失败: %v", err),
+		})
+	}
+	middleware.AuditChange(c, before, tenantSummary(t))
+
+	message := "租户已恢复"
+	if suspended {
+		message = "租户已停用"
+	}
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: message,
+		Data:    h.tenantInfo(t, sites),
+	})
+}
+
+// DeleteTenant 删除租户及其所有站点、站点文件、检查点、统计数据、API Token、绑定的账户和会话
+func (h *TenantHandler) DeleteTenant(c echo.Context) error {
+	if !isSuperAdmin(c) {
+		return c.JSON(http.StatusForbidden, Response{
+			Success: false,
+			Message: "仅超级管理员可执行该操作",
+		})
+	}
+
+	username := c.Param("username")
+	t, sites, err := h.load(username)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("获取租户失败: %v", err),
+		})
+	}
+	if t == nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgTenantNotFound,
+		})
+	}
+	// 租户名将用于拼接删除路径
+	if err := tenant.ValidateUsername(username); err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: fmt.Sprintf("删除租户失败: %v", err),
+		})
+	}
+	middleware.AuditChange(c, tenantSummary(t), nil)
+
+	// 先停止对外服务，再移除站点配置和数据
+	h.sm.SuspendUser(username)
+
+	// 取消租户尚未执行的部署任务并等待执行中的任务结束，再持有所有站点的修改锁删除数据，
+	// 避免并发的部署重新创建站点目录或检查点
+	if n := h.jobs.CancelTenant(username, errors.New("租户已删除，任务已取消")); n > 0 {
+		slog.Info("已取消租户的部署任务", "tenant", username, "count", n)
+	}
+	for _, s := range sites {
+		unlock, err := h.locks.Lock(s.GetRootDir(h.sitesDir))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: fmt.Sprintf("锁定站点 %s 失败: %v", s.ID, err),
+			})
+		}
+		defer unlock()
+	}
+
+	for _, s := range sites {
+		if err := h.sm.RemoveForUser(username, s.ID); err != nil {
+			return c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: fmt.Sprintf("删除站点 %s 失败: %v", s.ID, err),
+			})
+		}
+	}
+
+	revoked, err := h.tokens.RevokeForTenant(username)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("吊销 API Token 失败: %v", err),
+		})
+	}
+
+	// 绑定到该租户的账户随租户删除，避免以同名重建的租户被旧账户接管
+	var accountsRemoved []string
+	for _, u := range h.users.List() {
+		if u.Tenant != username {
+			continue
+		}
+		if err := h.users.Remove(u.Username); err != nil {
+			return c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: fmt.Sprintf("删除账户 %s 失败: %v", u.Username, err),
+			})
+		}
+		h.sessions.DeleteForUser(u.Username)
+		accountsRemoved = append(accountsRemoved, u.Username)
+	}
+	// 单点登录会话的租户在登录时确定，不对应账户存储中的账户
+	h.sessions.DeleteForTenant(username)
+
+	if err := os.RemoveAll(filepath.Join(h.sitesDir, username)); err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("删除站点文件失败: %v", err),
+		})
+	}
+	if err := h.checkpoints.RemoveUser(username); err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+	if err := h.am.RemoveUser(username); err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+	if _, err := h.trash.PurgeUser(username); err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("清空回收站失败: %v", err),
+		})
+	}
+
+	if err := h.tenants.Remove(username); err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("删除租户失败: %v", err),
+		})
+	}
+	// 清除停用标记，之后以同名重建的租户可正常提供服务
+	if err := h.sm.ResumeUser(username); err != nil {
+		slog.Warn("清除租户停用标记失败", "tenant", username, "error", err)
+	}
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "租户已删除",
+		Data: api.DeleteTenantResult{
+			Username:        username,
+			SitesRemoved:    len(sites),
+			TokensRevoked:   revoked,
+			AccountsRemoved: accountsRemoved,
+		},
+	})
+}
+
+// load 获取租户及其所有站点（租户不存在时返回 nil）
+func (h *TenantHandler) load(username string) (*tenant.Tenant, []*site.Site, error) {
+	t := h.tenants.Get(username)
+	if t == nil {
+		return nil, nil, nil
+	}
+	sites, err := h.sm.ListAllForUser(username)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, sites, nil
+}
+
+// tenantInfo 构建包含站点数量和用量汇总的租户信息
+func (h *TenantHandler) tenantInfo(t *tenant.Tenant, sites []*site.Site) api.Tenant {
+	info := t.Info()
+	info.SiteCount = len(sites)
+
+	ids := make([]string, 0, len(sites))
+	for _, s := range sites {
+		if s.Enabled {
+			info.EnabledSiteCount++
+		}
+		ids = append(ids, s.ID)
+	}
+	info.Usage = h.checkpoints.GetTotalStorageUsage(t.Username, ids)
+
+	return info
+}
+
+// tenantSummary 租户的审计摘要
+func tenantSummary(t *tenant.Tenant) map[string]any {
+	return map[string]any{
+		"display_name": t.DisplayName,
+		"contact":      t.Contact,
+		"limits":       t.Limits,
+		"suspended":    t.Suspended,
+	}
+}
+
+// isSuperAdmin 判断当前主体是否为超级管理员
+func isSuperAdmin(c echo.Context) bool {
+	p := middleware.CurrentPrincipal(c)
+	return p != nil && p.Role == auth.RoleSuperAdmin
+}
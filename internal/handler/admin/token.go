@@ -0,0 +1,133 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/auth"
+	"pages/internal/middleware"
+	"pages/internal/site"
+	"pages/pkg/api"
+)
+
+// TokenHandler API Token 接口处理器
+type TokenHandler struct {
+	tokens *auth.TokenStore
+	sm     *site.ManagerLockFree
+}
+
+// NewTokenHandler 创建 Token 接口处理器
+func NewTokenHandler(tokens *auth.TokenStore, sm *site.ManagerLockFree) *TokenHandler {
+	return &TokenHandler{
+		tokens: tokens,
+		sm:     sm,
+	}
+}
+
+// RegisterRoutes 注册 Token 管理路由
+func (h *TokenHandler) RegisterRoutes(g *echo.Group) {
+	g.GET("/users/:username/tokens", h.ListTokens)
+	g.POST("/users/:username/tokens", h.CreateToken)
+	g.DELETE("/users/:username/tokens/:token_id", h.RevokeToken)
+}
+
+// ListTokens 列出租户的 API Token
+func (h *TokenHandler) ListTokens(c echo.Context) error {
+	tokens := h.tokens.ListForTenant(c.Param("username"))
+	infos := make([]api.APIToken, len(tokens))
+	for i, t := range tokens {
+		infos[i] = t.Info()
+	}
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: api.APITokenList{
+			Tokens: infos,
+			Total:  len(infos),
+		},
+	})
+}
+
+// CreateToken 为租户创建 API Token，明文仅在响应中返回一次
+func (h *TokenHandler) CreateToken(c echo.Context) error {
+	username := c.Param("username")
+
+	var req api.CreateTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: api.MsgInvalidRequest,
+		})
+	}
+
+	if req.SiteID != "" {
+		s, err := h.sm.GetFullSiteByIDForUser(username, req.SiteID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Message: fmt.Sprintf("获取站点失败: %v", err),
+			})
+		}
+		if s == nil {
+			return c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Message: api.MsgSiteNotFound,
+			})
+		}
+	}
+
+	createdBy := ""
+	if p := middleware.CurrentPrincipal(c); p != nil {
+		createdBy = p.Username
+	}
+
+	t, plain, err := auth.NewToken(req.Name, username, req.SiteID, req.Scopes, req.ExpiresAt, createdBy)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Message: fmt.Sprintf("创建 Token 失败: %v", err),
+		})
+	}
+
+	if err := h.tokens.Add(t); err != nil {
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("创建 Token 失败: %v", err),
+		})
+	}
+
+	middleware.AuditChange(c, nil, map[string]any{
+		"token_id": t.ID,
+		"name":     t.Name,
+		"site_id":  t.SiteID,
+		"scopes":   t.Scopes,
+	})
+
+	return c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Message: "Token 已创建，请立即保存，之后将无法再次查看",
+		Data: api.CreatedToken{
+			APIToken: t.Info(),
+			Token:    plain,
+		},
+	})
+}
+
+// RevokeToken 吊销 API Token
+func (h *TokenHandler) RevokeToken(c echo.Context) error {
+	middleware.AuditChange(c, map[string]any{"token_id": c.Param("token_id")}, nil)
+
+	if err := h.tokens.Revoke(c.Param("username"), c.Param("token_id")); err != nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Token 已吊销",
+	})
+}
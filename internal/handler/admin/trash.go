@@ -0,0 +1,141 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/middleware"
+	"pages/internal/site"
+	"pages/internal/trash"
+	"pages/pkg/api"
+)
+
+// ListTrash 列出租户回收站中的站点
+func (h *Handler) ListTrash(c echo.Context) error {
+	entries := h.trash.List(c.Param("username"))
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: api.TrashList{
+			Sites: entries,
+			Total: len(entries),
+		},
+	})
+}
+
+// RestoreTrashedSite 从回收站恢复站点（含站点文件、检查点和统计数据）
+func (h *Handler) RestoreTrashedSite(c echo.Context) error {
+	username := c.Param("username")
+	trashID := c.Param("trash_id")
+
+	entry, err := h.trash.Get(username, trashID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Message: api.MsgTrashNotFound,
+		})
+	}
+
+	if err := h.quota.CheckSiteCount(username); err != nil {
+		return quotaFailed(c, "恢复站点失败", err)
+	}
+
+	// 先登记站点配置，由存储检查 ID 和域名冲突
+	s := siteFromAPI(entry.Site)
+	if err := h.siteManager.Add(s); err != nil {
+		return c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Message: fmt.Sprintf("恢复站点失败: %v", err),
+		})
+	}
+
+	if _, err := h.trash.Restore(username, trashID); err != nil {
+		if rmErr := h.siteManager.RemoveForUser(username, s.ID); rmErr != nil {
+			slog.Error("恢复站点失败后移除站点配置失败", "tenant", username, "site", s.ID, "error", rmErr)
+		}
+		status := http.StatusInternalServerError
+		if errors.Is(err, trash.ErrConflict) {
+			status = http.StatusConflict
+		}
+		return c.JSON(status, Response{
+			Success: false,
+			Message: fmt.Sprintf("恢复站点失败: %v", err),
+		})
+	}
+
+	if _, err := h.tenants.Ensure(username); err != nil {
+		slog.Error("登记租户失败", "tenant", username, "error", err)
+	}
+	middleware.AuditChange(c, nil, siteSummary(s))
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "站点已恢复",
+		Data:    s,
+	})
+}
+
+// PurgeTrashedSite 立即彻底删除回收站中的站点
+func (h *Handler) PurgeTrashedSite(c echo.Context) error {
+	username := c.Param("username")
+	trashID := c.Param("trash_id")
+
+	if err := h.trash.Purge(username, trashID); err != nil {
+		if errors.Is(err, trash.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Message: api.MsgTrashNotFound,
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Message: fmt.Sprintf("彻底删除站点失败: %v", err),
+		})
+	}
+
+	return c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "站点已彻底删除",
+	})
+}
+
+// apiSite 将站点转换为 API 类型
+func apiSite(s *site.Site) api.Site {
+	return api.Site{
+		ID:        s.ID,
+		Username:  s.Username,
+		Domain:    s.Domain,
+		Index:     s.Index,
+		Enabled:   s.Enabled,
+		Limits:    s.Limits,
+		Retention: s.Retention,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+	}
+}
+
+// siteFromAPI 由 API 类型还原站点
+func siteFromAPI(s api.Site) *site.Site {
+	return &site.Site{
+		ID:        s.ID,
+		Username:  s.Username,
+		Domain:    s.Domain,
+		Index:     s.Index,
+		Enabled:   s.Enabled,
+		Limits:    s.Limits,
+		Retention: s.Retention,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+	}
+}
+
+// actorName 返回当前主体的账户名（用于记录操作人）
+func actorName(c echo.Context) string {
+	if p := middleware.CurrentPrincipal(c); p != nil {
+		return p.Username
+	}
+	return ""
+}
@@ -0,0 +1,241 @@
+package deploy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// blobStore 内容寻址的文件存储，内容保存在 <dir>/<哈希前两位>/<sha256>
+// 引用计数（引用该内容的检查点数量）保存在 <dir>/refs.json，引用归零的内容被删除
+// refs.json 由所有租户和站点共享，每次创建或删除检查点都会整体重写：写入量与存储中的内容数量成正比，
+// 与本次检查点的大小无关。检查点的创建频率受部署限制，目前可以接受；内容数量增长到重写成为瓶颈时应按哈希前缀分片
+type blobStore struct {
+	dir    string
+	mu     sync.Mutex
+	refs   map[string]int // 哈希 -> 引用数，首次使用时加载
+	pinned map[string]int // 已写入但尚未登记引用的哈希，不会被回收
+}
+
+var (
+	blobStoresMu sync.Mutex
+	blobStores   = make(map[string]*blobStore)
+)
+
+// openBlobStore 返回目录对应的存储；同一目录在进程内共享一个实例，保证引用计数一致
+func openBlobStore(dir string) *blobStore {
+	dir = filepath.Clean(dir)
+	blobStoresMu.Lock()
+	defer blobStoresMu.Unlock()
+
+	s, ok := blobStores[dir]
+	if !ok {
+		s = &blobStore{
+			dir:    dir,
+			pinned: make(map[string]int),
+		}
+		blobStores[dir] = s
+	}
+	return s
+}
+
+// path 返回内容的存储路径
+func (s *blobStore) path(hash string) string {
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+// put 将文件内容存入存储（已存在时跳过写入），返回哈希和大小
+// 返回的哈希处于固定状态，调用方随后必须调用 commit 或 abort
+func (s *blobStore) put(path string) (string, int64, error) {
+	hash, err := HashFile(path)
+	if err != nil {
+		return "", 0, err
+	}
+
+	s.mu.Lock()
+	s.pinned[hash]++
+	s.mu.Unlock()
+
+	target := s.path(hash)
+	if info, err := os.Stat(target); err == nil {
+		return hash, info.Size(), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		s.abort([]string{hash})
+		return "", 0, err
+	}
+	defer f.Close()
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		s.abort([]string{hash})
+		return "", 0, err
+	}
+	// 写入时再次校验内容，文件在哈希后被修改时放弃
+	size, err := ReceiveBlob(f, target, hash)
+	if err != nil {
+		s.abort([]string{hash})
+		return "", 0, fmt.Errorf("保存 %s 失败: %w", path, err)
+	}
+	return hash, size, nil
+}
+
+// commit 为 hashes 中的每个内容（去重后）增加一次引用，并解除 put 时的固定
+func (s *blobStore) commit(hashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		s.unpinLocked(hash)
+		if !seen[hash] {
+			seen[hash] = true
+			s.refs[hash]++
+		}
+	}
+	return s.saveLocked()
+}
+
+// abort 解除 put 时的固定，没有引用的内容被删除
+func (s *blobStore) abort(hashes []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		// 引用计数不可用时不删除任何内容
+		for _, hash := range hashes {
+			s.unpinLocked(hash)
+		}
+		return
+	}
+	for _, hash := range hashes {
+		s.unpinLocked(hash)
+		s.collectLocked(hash)
+	}
+}
+
+// release 为 hashes 中的每个内容（去重后）减少一次引用，引用归零的内容被删除
+func (s *blobStore) release(hashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		if s.refs[hash] > 1 {
+			s.refs[hash]--
+			continue
+		}
+		delete(s.refs, hash)
+		s.collectLocked(hash)
+	}
+	return s.saveLocked()
+}
+
+// refCounts 返回 hashes 中各内容的引用数（所有租户和站点的检查点）
+func (s *blobStore) refCounts(hashes map[string]int64) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(hashes))
+	for hash := range hashes {
+		counts[hash] = s.refs[hash]
+	}
+	return counts, nil
+}
+
+// unpinLocked 解除一次固定（需持有 mu）
+func (s *blobStore) unpinLocked(hash string) {
+	if s.pinned[hash] > 1 {
+		s.pinned[hash]--
+	} else {
+		delete(s.pinned, hash)
+	}
+}
+
+// collectLocked 删除没有引用且未被固定的内容（需持有 mu）
+func (s *blobStore) collectLocked(hash string) {
+	if s.refs[hash] > 0 || s.pinned[hash] > 0 {
+		return
+	}
+	os.Remove(s.path(hash))
+}
+
+// loadLocked 首次使用时加载引用计数（需持有 mu）
+func (s *blobStore) loadLocked() error {
+	if s.refs != nil {
+		return nil
+	}
+	refs := make(map[string]int)
+	data, err := os.ReadFile(filepath.Join(s.dir, "refs.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("读取内容引用计数失败: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &refs); err != nil {
+			return fmt.Errorf("解析内容引用计数失败: %w", err)
+		}
+	}
+	s.refs = refs
+	return nil
+}
+
+// saveLocked 保存引用计数（需持有 mu），先写临时文件再重命名
+func (s *blobStore) saveLocked() error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s.refs)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, "refs.json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("保存内容引用计数失败: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// copyTo 将内容复制到 dst（不使用硬链接，避免站点文件被原地修改时破坏存储）
+func (s *blobStore) copyTo(hash, dst string, mode os.FileMode) error {
+	in, err := os.Open(s.path(hash))
+	if err != nil {
+		return fmt.Errorf("读取内容 %s 失败: %w", hash, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(out, h), in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if hex.EncodeToString(h.Sum(nil)) != hash {
+		return fmt.Errorf("内容 %s 已损坏", hash)
+	}
+	return nil
+}
@@ -6,24 +6,22 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
+
+	"pages/pkg/api"
 )
 
-// Checkpoint 表示一个站点部署检查点
-type Checkpoint struct {
-	ID          string    `json:"id"`           // 检查点唯一标识（时间戳+哈希）
-	CreatedAt   time.Time `json:"created_at"`   // 创建时间
-	FileSize    int64     `json:"file_size"`    // 备份文件大小
-	FileName    string    `json:"file_name"`    // 原始上传文件名
-	Note        string    `json:"note"`         // 备注信息
-	Source      string    `json:"source"`       // 来源："deploy" 或 "manual"
-	Description string    `json:"description"`  // 描述信息
-}
+// Checkpoint 表示一个站点部署检查点（与 pkg/client 共用）
+type Checkpoint = api.Checkpoint
 
 // SiteCheckpointMetadata 站点检查点元数据
 type SiteCheckpointMetadata struct {
@@ -35,18 +33,33 @@ type SiteCheckpointMetadata struct {
 	UpdatedAt    time.Time    `json:"updated_at"`     // 最后更新时间
 }
 
+// CheckpointQuota 检查点配额检查，count 为创建后的检查点数量，size 为新检查点新增的存储大小
+// 返回非 nil 错误时放弃创建并将该错误返回给调用方
+type CheckpointQuota func(username, siteID string, count int, size int64) error
+
 // CheckpointManager 管理检查点
+// 检查点的文件内容保存在 <baseDir>/.blobs 下的内容存储中，所有租户和站点共享，相同内容只保存一份
 type CheckpointManager struct {
-	baseDir string // 检查点存储根目录
+	baseDir      string            // 检查点存储根目录
+	quota        CheckpointQuota   // 配额检查（可为空）
+	blobs        *blobStore        // 内容存储
+	retention    RetentionResolver // 保留策略（可为空）
+	keepReleases int               // 保留的发布目录数量，0 表示不使用发布目录
 }
 
 // NewCheckpointManager 创建检查点管理器
 func NewCheckpointManager(baseDir string) *CheckpointManager {
 	return &CheckpointManager{
 		baseDir: baseDir,
+		blobs:   openBlobStore(filepath.Join(baseDir, ".blobs")),
 	}
 }
 
+// SetQuota 设置创建检查点时的配额检查
+func (m *CheckpointManager) SetQuota(quota CheckpointQuota) {
+	m.quota = quota
+}
+
 // getCheckpointDir 获取指定站点的检查点目录
 func (m *CheckpointManager) getCheckpointDir(username, siteID string) string {
 	return filepath.Join(m.baseDir, username, siteID)
@@ -62,68 +75,215 @@ func (m *CheckpointManager) getCheckpointPath(username, siteID, checkpointID str
 	return filepath.Join(m.getCheckpointsSubDir(username, siteID), checkpointID+".tar.gz")
 }
 
+// getCheckpointManifestPath 获取检查点文件清单缓存路径
+func (m *CheckpointManager) getCheckpointManifestPath(username, siteID, checkpointID string) string {
+	return filepath.Join(m.getCheckpointsSubDir(username, siteID), checkpointID+".manifest.json")
+}
+
 // getSiteMetadataPath 获取站点检查点元数据文件路径 (metadata.json)
 func (m *CheckpointManager) getSiteMetadataPath(username, siteID string) string {
 	return filepath.Join(m.getCheckpointDir(username, siteID), "metadata.json")
 }
 
-// CreateCheckpoint 创建检查点（从目录打包为 tar.gz）- 仅在部署时调用
+// CreateCheckpoint 为即将部署的内容（sourceDir 为暂存目录）创建检查点
+// 新检查点不会成为当前检查点：调用方替换站点目录后调用 ActivateCheckpoint，替换失败时删除该检查点
 func (m *CheckpointManager) CreateCheckpoint(username, siteID, sourceDir, originalFileName string) (*Checkpoint, error) {
-	checkpointsDir := m.getCheckpointsSubDir(username, siteID)
-	if err := os.MkdirAll(checkpointsDir, 0755); err != nil {
-		return nil, fmt.Errorf("创建检查点目录失败: %w", err)
+	return m.createCheckpoint(username, siteID, sourceDir, Checkpoint{
+		FileName:    originalFileName,
+		Source:      "deploy",
+		Description: fmt.Sprintf("部署: %s", originalFileName),
+	}, false)
+}
+
+// CreateManualCheckpoint 为当前部署的站点目录手动创建检查点，新检查点成为当前检查点
+func (m *CheckpointManager) CreateManualCheckpoint(username, siteID, rootDir, note string) (*Checkpoint, error) {
+	return m.createCheckpoint(username, siteID, rootDir, Checkpoint{
+		Note:        note,
+		Source:      "manual",
+		Description: "手动创建",
+	}, true)
+}
+
+// CaptureLive 在站点目录被替换之前确认其内容已有检查点：
+// 当前检查点与站点目录的内容不一致（没有当前检查点、站点文件被直接修改，或是早期版本记录的 current）时，
+// 为站点目录创建检查点并设为当前检查点；内容一致或站点目录不存在时返回 nil
+func (m *CheckpointManager) CaptureLive(username, siteID, rootDir string) (*Checkpoint, error) {
+	if _, err := os.Stat(rootDir); os.IsNotExist(err) {
+		return nil, nil
 	}
 
-	// 生成检查点 ID（时间戳 + 内容哈希前8位）
-	timestamp := time.Now().Format("20060102-150405")
-	hash := m.calculateDirHash(sourceDir)
-	checkpointID := fmt.Sprintf("%s-%s", timestamp, hash[:8])
+	metadata, err := m.loadSiteMetadata(username, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("加载站点元数据失败: %w", err)
+	}
+	if metadata.Current != "" {
+		// 大小和修改时间与当前检查点文件树一致的文件沿用记录的哈希，只为变化的文件计算哈希
+		current, _ := m.loadTree(username, siteID, metadata.Current)
+		live, err := scanLive(rootDir, current)
+		if err != nil {
+			return nil, fmt.Errorf("计算站点文件哈希失败: %w", err)
+		}
+		manifest, err := m.CheckpointManifest(username, siteID, metadata.Current)
+		if err == nil && maps.Equal(manifest, live.manifest()) {
+			return nil, nil
+		}
+	}
 
-	checkpoint := &Checkpoint{
-		ID:          checkpointID,
-		CreatedAt:   time.Now(),
-		FileName:    originalFileName,
+	return m.createCheckpoint(username, siteID, rootDir, Checkpoint{
 		Source:      "deploy",
-		Description: fmt.Sprintf("部署: %s", originalFileName),
+		Description: "部署前的站点内容",
+	}, true)
+}
+
+// ActivateCheckpoint 将检查点设为当前检查点（站点目录已替换为该检查点的内容），随后按保留策略清理并重算存储用量
+func (m *CheckpointManager) ActivateCheckpoint(username, siteID, checkpointID, rootDir string) error {
+	metadata, err := m.loadSiteMetadata(username, siteID)
+	if err != nil {
+		return fmt.Errorf("加载站点元数据失败: %w", err)
+	}
+	if !checkpointExists(metadata, checkpointID) {
+		return fmt.Errorf("%w: %s", ErrCheckpointNotFound, checkpointID)
 	}
 
-	// 打包目录为 tar.gz
-	archivePath := m.getCheckpointPath(username, siteID, checkpointID)
-	if err := m.packDirectory(sourceDir, archivePath); err != nil {
-		return nil, fmt.Errorf("打包检查点失败: %w", err)
+	metadata.Current = checkpointID
+	metadata.UpdatedAt = time.Now()
+	if err := m.saveSiteMetadata(metadata); err != nil {
+		return err
+	}
+
+	m.afterActivate(username, siteID, rootDir, m.policyFor(username, siteID))
+	return nil
+}
+
+// policyFor 返回站点生效的保留策略（未设置时不清理）
+func (m *CheckpointManager) policyFor(username, siteID string) Retention {
+	if m.retention == nil {
+		return Retention{}
+	}
+	return m.retention(username, siteID)
+}
+
+// afterActivate 当前检查点变化后按保留策略删除过期检查点并重算存储用量
+func (m *CheckpointManager) afterActivate(username, siteID, rootDir string, policy Retention) {
+	// 删除失败时由后台清理重试，不影响调用方
+	if pruned, err := m.Prune(username, siteID, policy); err != nil {
+		slog.Warn("按保留策略删除检查点失败", "tenant", username, "site", siteID, "error", err)
+	} else if len(pruned) > 0 {
+		slog.Info("已按保留策略删除检查点", "tenant", username, "site", siteID, "count", len(pruned))
+	}
+
+	m.PruneReleases(username, siteID, rootDir)
+
+	// 重算存储使用量 (忽略错误,不影响检查点操作)
+	_ = m.StorageRecount(username, siteID, rootDir)
+}
+
+// createCheckpoint 创建检查点，info 提供来源、文件名、备注等描述信息，其余字段在此生成
+// activate 为 true 时 sourceDir 即站点目录，新检查点同时成为当前检查点
+func (m *CheckpointManager) createCheckpoint(username, siteID, sourceDir string, info Checkpoint, activate bool) (*Checkpoint, error) {
+	checkpointsDir := m.getCheckpointsSubDir(username, siteID)
+	if err := os.MkdirAll(checkpointsDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建检查点目录失败: %w", err)
 	}
 
-	// 获取文件大小
-	stat, err := os.Stat(archivePath)
+	tree, pinned, err := m.storeTree(sourceDir)
 	if err != nil {
-		return nil, fmt.Errorf("获取检查点文件信息失败: %w", err)
+		return nil, fmt.Errorf("保存检查点内容失败: %w", err)
 	}
-	checkpoint.FileSize = stat.Size()
+	committed := false
+	defer func() {
+		if !committed {
+			m.blobs.abort(pinned)
+		}
+	}()
 
 	// 加载元数据
 	metadata, err := m.loadSiteMetadata(username, siteID)
 	if err != nil {
-		os.Remove(archivePath) // 清理已创建的备份文件
 		return nil, fmt.Errorf("加载站点元数据失败: %w", err)
 	}
 
+	// 生成检查点 ID（时间戳 + 文件树哈希前8位），同一秒内内容相同时追加序号
+	timestamp := time.Now().Format("20060102-150405")
+	checkpointID := fmt.Sprintf("%s-%s", timestamp, tree.digest()[:8])
+	for i := 2; checkpointExists(metadata, checkpointID); i++ {
+		checkpointID = fmt.Sprintf("%s-%s-%d", timestamp, tree.digest()[:8], i)
+	}
+
+	// 新增的存储大小：本站点其他检查点尚未引用的内容
+	existing, err := m.siteBlobSizes(username, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("读取检查点文件树失败: %w", err)
+	}
+	var added int64
+	for _, e := range tree.Entries {
+		if _, ok := existing[e.Hash]; e.Hash != "" && !ok {
+			existing[e.Hash] = e.Size
+			added += e.Size
+		}
+	}
+
+	checkpoint := &info
+	checkpoint.ID = checkpointID
+	checkpoint.CreatedAt = time.Now()
+	checkpoint.FileSize = added
+	checkpoint.LogicalSize = tree.logicalSize()
+	checkpoint.Format = CheckpointFormatContent
+
+	// 按保留策略随后会删除的检查点不计入数量配额
+	policy := m.policyFor(username, siteID)
+	count := len(metadata.Checkpoints) + 1
+	count -= len(PlanRetention(append(metadata.Checkpoints, *checkpoint), checkpointID, policy, time.Now()))
+
+	if m.quota != nil {
+		if err := m.quota(username, siteID, count, checkpoint.FileSize); err != nil {
+			return nil, err
+		}
+	}
+
+	// 保存文件树并登记内容引用
+	if err := m.saveTree(username, siteID, checkpointID, tree); err != nil {
+		return nil, fmt.Errorf("保存检查点文件树失败: %w", err)
+	}
+	if err := m.blobs.commit(pinned); err != nil {
+		os.Remove(m.getCheckpointTreePath(username, siteID, checkpointID))
+		return nil, err
+	}
+	committed = true
+
 	// 添加新检查点到元数据
 	metadata.Checkpoints = append(metadata.Checkpoints, *checkpoint)
-	metadata.Current = checkpointID
+	if activate {
+		metadata.Current = checkpointID
+	}
 	metadata.UpdatedAt = time.Now()
 
 	// 保存站点元数据
 	if err := m.saveSiteMetadata(metadata); err != nil {
-		os.Remove(archivePath) // 清理已创建的备份文件
+		os.Remove(m.getCheckpointTreePath(username, siteID, checkpointID))
+		m.blobs.release(tree.hashes())
 		return nil, fmt.Errorf("保存站点元数据失败: %w", err)
 	}
 
-	// 重算存储使用量 (忽略错误,不影响检查点创建)
-	_ = m.StorageRecount(username, siteID, sourceDir)
-
+	if activate {
+		if err := adoptRelease(sourceDir, checkpointID); err != nil {
+			slog.Warn("将当前发布关联到新检查点失败", "tenant", username, "site", siteID, "checkpoint", checkpointID, "error", err)
+		}
+		m.afterActivate(username, siteID, sourceDir, policy)
+	}
 	return checkpoint, nil
 }
 
+// checkpointExists 判断元数据中是否已有该检查点
+func checkpointExists(metadata *SiteCheckpointMetadata, checkpointID string) bool {
+	for _, cp := range metadata.Checkpoints {
+		if cp.ID == checkpointID {
+			return true
+		}
+	}
+	return false
+}
+
 // CheckoutCheckpoint 切换到指定检查点（仅更新 current 指针，不创建新检查点）
 func (m *CheckpointManager) CheckoutCheckpoint(username, siteID, checkpointID, targetDir string) error {
 	// 检查检查点是否存在
@@ -132,34 +292,20 @@ func (m *CheckpointManager) CheckoutCheckpoint(username, siteID, checkpointID, t
 		return fmt.Errorf("加载站点元数据失败: %w", err)
 	}
 
-	// 验证检查点存在
-	found := false
-	for _, cp := range metadata.Checkpoints {
-		if cp.ID == checkpointID {
-			found = true
-			break
-		}
-	}
-	if !found {
-		return fmt.Errorf("检查点不存在: %s", checkpointID)
-	}
-
-	archivePath := m.getCheckpointPath(username, siteID, checkpointID)
-	if _, err := os.Stat(archivePath); os.IsNotExist(err) {
-		return fmt.Errorf("检查点文件不存在: %s", checkpointID)
-	}
-
-	// 清空目标目录
-	if err := os.RemoveAll(targetDir); err != nil {
-		return fmt.Errorf("清空目标目录失败: %w", err)
-	}
-	if err := os.MkdirAll(targetDir, 0755); err != nil {
-		return fmt.Errorf("创建目标目录失败: %w", err)
+	// 验证检查点存在（也可以使用标签）
+	i := resolveCheckpoint(metadata, checkpointID)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrCheckpointNotFound, checkpointID)
 	}
+	checkpointID = metadata.Checkpoints[i].ID
 
-	// 解压检查点到目标目录
-	if err := ExtractTarGzSimple(archivePath, targetDir); err != nil {
-		return fmt.Errorf("解压检查点失败: %w", err)
+	// 发布目录中仍保留该检查点时只需切换符号链接，否则从检查点存储还原
+	if m.keepReleases > 0 && releaseExists(targetDir, checkpointID) {
+		if err := switchRelease(targetDir, checkpointID); err != nil {
+			return err
+		}
+	} else if err := m.restoreCheckpoint(username, siteID, checkpointID, targetDir); err != nil {
+		return err
 	}
 
 	// 更新 current 指针
@@ -171,12 +317,49 @@ func (m *CheckpointManager) CheckoutCheckpoint(username, siteID, checkpointID, t
 		return err
 	}
 
+	m.PruneReleases(username, siteID, targetDir)
+
 	// 重算存储使用量 (忽略错误,不影响切换操作)
 	_ = m.StorageRecount(username, siteID, targetDir)
 
 	return nil
 }
 
+// restoreCheckpoint 将检查点还原到暂存目录并校验内容，再让其替换站点目录上线；失败时线上目录保持不变
+func (m *CheckpointManager) restoreCheckpoint(username, siteID, checkpointID, targetDir string) error {
+	tree, err := m.loadTree(username, siteID, checkpointID)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	archivePath := m.getCheckpointPath(username, siteID, checkpointID)
+	if tree == nil {
+		if _, err := os.Stat(archivePath); os.IsNotExist(err) {
+			return fmt.Errorf("检查点文件不存在: %s", checkpointID)
+		}
+	}
+
+	stagingDir, err := NewStagingDir(targetDir)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stagingDir)
+	if err := os.Chmod(stagingDir, 0755); err != nil {
+		return fmt.Errorf("设置暂存目录权限失败: %w", err)
+	}
+
+	// 还原检查点到暂存目录（内容寻址的检查点逐个校验哈希，早期格式的检查点解压 tar.gz）
+	if tree != nil {
+		if err := m.restoreTree(tree, stagingDir); err != nil {
+			return fmt.Errorf("还原检查点失败: %w", err)
+		}
+	} else if err := ExtractTarGzSimple(archivePath, stagingDir); err != nil {
+		return fmt.Errorf("解压检查点失败: %w", err)
+	}
+
+	// 一次重命名切换线上目录，访问者不会看到不完整的站点
+	return m.Publish(targetDir, checkpointID, stagingDir)
+}
+
 // ListCheckpoints 列出指定站点的所有检查点
 func (m *CheckpointManager) ListCheckpoints(username, siteID string) (*SiteCheckpointMetadata, error) {
 	metadata, err := m.loadSiteMetadata(username, siteID)
@@ -205,6 +388,13 @@ func (m *CheckpointManager) DeleteCheckpoint(username, siteID, checkpointID stri
 		return fmt.Errorf("无法删除当前激活的检查点")
 	}
 
+	// 固定的检查点不能删除
+	for _, cp := range metadata.Checkpoints {
+		if cp.ID == checkpointID && cp.Pinned {
+			return ErrCheckpointPinned
+		}
+	}
+
 	// 从元数据中移除检查点
 	newCheckpoints := make([]Checkpoint, 0, len(metadata.Checkpoints))
 	found := false
@@ -217,22 +407,62 @@ func (m *CheckpointManager) DeleteCheckpoint(username, siteID, checkpointID stri
 	}
 
 	if !found {
-		return fmt.Errorf("检查点不存在: %s", checkpointID)
+		return fmt.Errorf("%w: %s", ErrCheckpointNotFound, checkpointID)
 	}
 
 	metadata.Checkpoints = newCheckpoints
 	metadata.UpdatedAt = time.Now()
 
-	// 删除备份文件
+	// 先保存元数据再删除文件，失败时检查点仍然完整
+	metadata.StorageUsage = nil
+	if err := m.saveSiteMetadata(metadata); err != nil {
+		return err
+	}
+
+	// 删除文件树并释放内容引用，或删除早期格式的备份文件
+	tree, err := m.loadTree(username, siteID, checkpointID)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if tree != nil {
+		if err := os.Remove(m.getCheckpointTreePath(username, siteID, checkpointID)); err != nil {
+			return fmt.Errorf("删除检查点文件树失败: %w", err)
+		}
+		if err := m.blobs.release(tree.hashes()); err != nil {
+			return fmt.Errorf("释放检查点内容失败: %w", err)
+		}
+	}
 	archivePath := m.getCheckpointPath(username, siteID, checkpointID)
 	if err := os.Remove(archivePath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("删除检查点文件失败: %w", err)
 	}
+	os.Remove(m.getCheckpointManifestPath(username, siteID, checkpointID))
+	return nil
+}
 
-	metadata.StorageUsage = nil
+// SiteDir 返回站点的检查点目录（含检查点文件和元数据）
+func (m *CheckpointManager) SiteDir(username, siteID string) string {
+	return m.getCheckpointDir(username, siteID)
+}
 
-	// 保存更新后的元数据
-	return m.saveSiteMetadata(metadata)
+// RemoveUser 删除租户所有站点的检查点及元数据
+func (m *CheckpointManager) RemoveUser(username string) error {
+	userDir := filepath.Join(m.baseDir, username)
+	entries, err := os.ReadDir(userDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("读取检查点目录失败: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := m.ReleaseDir(filepath.Join(userDir, entry.Name())); err != nil {
+				return fmt.Errorf("释放检查点内容失败: %w", err)
+			}
+		}
+	}
+	if err := os.RemoveAll(filepath.Join(m.baseDir, username)); err != nil {
+		return fmt.Errorf("删除检查点失败: %w", err)
+	}
+	return nil
 }
 
 // GetCheckpoint 获取指定检查点信息
@@ -248,7 +478,7 @@ func (m *CheckpointManager) GetCheckpoint(username, siteID, checkpointID string)
 		}
 	}
 
-	return nil, fmt.Errorf("检查点不存在: %s", checkpointID)
+	return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, checkpointID)
 }
 
 // loadSiteMetadata 加载站点检查点元数据
@@ -295,87 +525,225 @@ func (m *CheckpointManager) saveSiteMetadata(metadata *SiteCheckpointMetadata) e
 	return os.WriteFile(metadataPath, data, 0644)
 }
 
-// packDirectory 将目录打包为 tar.gz
-func (m *CheckpointManager) packDirectory(sourceDir, targetPath string) error {
-	file, err := os.Create(targetPath)
-	if err != nil {
-		return err
+// CheckpointManifest 返回检查点的文件清单（路径 -> SHA-256）
+// 内容寻址检查点直接读取文件树；早期格式的检查点读取归档计算一次并缓存（检查点内容不会改变）
+func (m *CheckpointManager) CheckpointManifest(username, siteID, checkpointID string) (Manifest, error) {
+	if tree, err := m.loadTree(username, siteID, checkpointID); err == nil {
+		return tree.manifest(), nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
 	}
-	defer file.Close()
-
-	gzw := gzip.NewWriter(file)
-	defer gzw.Close()
 
-	tw := tar.NewWriter(gzw)
-	defer tw.Close()
+	if data, err := os.ReadFile(m.getCheckpointManifestPath(username, siteID, checkpointID)); err == nil {
+		var manifest Manifest
+		if err := json.Unmarshal(data, &manifest); err == nil {
+			return manifest, nil
+		}
+	}
 
-	return filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
+	manifest := make(Manifest)
+	err := m.walkCheckpoint(username, siteID, checkpointID, func(name string, r io.Reader) error {
+		h := sha256.New()
+		if _, err := io.Copy(h, r); err != nil {
 			return err
 		}
+		manifest[name] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	_ = m.saveCheckpointManifest(username, siteID, checkpointID, manifest)
+	return manifest, nil
+}
 
-		// 计算相对路径
-		relPath, err := filepath.Rel(sourceDir, path)
+// ReuseLiveFiles 按当前检查点的文件树在站点目录 rootDir 中查找 needed 需要的内容，不计算站点文件的哈希：
+// 大小和修改时间与文件树一致的站点文件复制到 dest 下使用该内容的第一个路径并校验哈希，其余路径链接到该副本，并从 needed 中删除；
+// 站点文件可能被原地修改，不能与发布共享 inode，因此始终复制。其余内容留给 ReuseCheckpointFiles 从内容存储中复制，返回复用的文件数
+func (m *CheckpointManager) ReuseLiveFiles(username, siteID, rootDir, dest string, needed map[string][]string) (int, error) {
+	metadata, err := m.loadSiteMetadata(username, siteID)
+	if err != nil {
+		return 0, fmt.Errorf("加载站点元数据失败: %w", err)
+	}
+	if metadata.Current == "" {
+		return 0, nil
+	}
+	tree, err := m.loadTree(username, siteID, metadata.Current)
+	if os.IsNotExist(err) {
+		// 早期格式的检查点没有文件树
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	liveDir := resolveDir(rootDir)
+	reused := 0
+	for _, e := range tree.Entries {
+		names, ok := needed[e.Hash]
+		if e.Hash == "" || !ok {
+			continue
+		}
+		src := filepath.Join(liveDir, filepath.FromSlash(e.Path))
+		if info, err := os.Lstat(src); err != nil || !e.unchanged(info) {
+			continue
+		}
+		ok, err := receiveLiveFile(src, dest, names[0], e.Hash)
 		if err != nil {
-			return err
+			return reused, err
+		}
+		if !ok {
+			continue
 		}
+		if err := PlaceFile(filepath.Join(dest, filepath.FromSlash(names[0])), dest, names[1:]); err != nil {
+			return reused, err
+		}
+		delete(needed, e.Hash)
+		reused += len(names)
+	}
+	return reused, nil
+}
 
-		// 跳过根目录本身
-		if relPath == "." {
-			return nil
+// receiveLiveFile 将站点文件 src 复制到 dest 下的 name 并校验哈希
+// 大小和修改时间不变但内容已不同（或读取时被修改）时返回 false，该内容改从内容存储中获取
+func receiveLiveFile(src, dest, name, hash string) (bool, error) {
+	target := filepath.Join(dest, filepath.FromSlash(name))
+	if !isWithinRoot(dest, target) {
+		return false, fmt.Errorf("非法路径: %s", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return false, err
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return false, nil
+	}
+	defer f.Close()
+	if _, err := ReceiveBlob(f, target, hash); err != nil {
+		if errors.Is(err, ErrHashMismatch) {
+			return false, nil
 		}
+		return false, fmt.Errorf("复制 %s 失败: %w", name, err)
+	}
+	return true, nil
+}
 
-		// 创建 tar header
-		header, err := tar.FileInfoHeader(info, "")
-		if err != nil {
-			return err
+// ReuseCheckpointFiles 从检查点中提取 needed 需要的内容到 dest 下使用该内容的所有路径，并从 needed 中删除
+// needed 为哈希 -> 相对路径列表，返回复用的文件数
+func (m *CheckpointManager) ReuseCheckpointFiles(username, siteID, checkpointID, dest string, needed map[string][]string) (int, error) {
+	if tree, err := m.loadTree(username, siteID, checkpointID); err == nil {
+		return m.reuseTreeFiles(tree, dest, needed)
+	} else if !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	manifest, err := m.CheckpointManifest(username, siteID, checkpointID)
+	if err != nil {
+		return 0, err
+	}
+	wanted := make(map[string]bool)
+	for name, hash := range manifest {
+		if _, ok := needed[hash]; ok {
+			wanted[name] = true
 		}
-		header.Name = filepath.ToSlash(relPath)
+	}
+	if len(wanted) == 0 {
+		return 0, nil
+	}
 
-		if err := tw.WriteHeader(header); err != nil {
+	reused := 0
+	err = m.walkCheckpoint(username, siteID, checkpointID, func(name string, r io.Reader) error {
+		hash := manifest[name]
+		names, ok := needed[hash]
+		if !wanted[name] || !ok {
+			return nil
+		}
+		// 先写入第一个路径并校验内容，再链接到其余路径
+		first := filepath.Join(dest, filepath.FromSlash(names[0]))
+		if !isWithinRoot(dest, first) {
+			return fmt.Errorf("非法路径: %s", names[0])
+		}
+		if err := os.MkdirAll(filepath.Dir(first), 0755); err != nil {
 			return err
 		}
+		if _, err := ReceiveBlob(r, first, hash); err != nil {
+			return fmt.Errorf("提取 %s 失败: %w", name, err)
+		}
+		if err := PlaceFile(first, dest, names[1:]); err != nil {
+			return err
+		}
+		delete(needed, hash)
+		reused += len(names)
+		return nil
+	})
+	return reused, err
+}
 
-		// 如果是文件，写入内容
-		if !info.IsDir() {
-			f, err := os.Open(path)
-			if err != nil {
-				return err
+// reuseTreeFiles 从内容存储中复制 needed 需要的内容到 dest
+func (m *CheckpointManager) reuseTreeFiles(tree *checkpointTree, dest string, needed map[string][]string) (int, error) {
+	reused := 0
+	for _, e := range tree.Entries {
+		names, ok := needed[e.Hash]
+		if e.Hash == "" || !ok {
+			continue
+		}
+		for _, name := range names {
+			target := filepath.Join(dest, filepath.FromSlash(name))
+			if !isWithinRoot(dest, target) {
+				return reused, fmt.Errorf("非法路径: %s", name)
 			}
-			defer f.Close()
-
-			if _, err := io.Copy(tw, f); err != nil {
-				return err
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return reused, err
+			}
+			if err := m.blobs.copyTo(e.Hash, target, e.Mode); err != nil {
+				return reused, err
 			}
 		}
-
-		return nil
-	})
+		delete(needed, e.Hash)
+		reused += len(names)
+	}
+	return reused, nil
 }
 
-// calculateDirHash 计算目录内容的哈希值
-func (m *CheckpointManager) calculateDirHash(dir string) string {
-	hash := sha256.New()
+// walkCheckpoint 依次读取早期格式检查点归档中的普通文件
+func (m *CheckpointManager) walkCheckpoint(username, siteID, checkpointID string, fn func(name string, r io.Reader) error) error {
+	f, err := os.Open(m.getCheckpointPath(username, siteID, checkpointID))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
 
-	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
+	tr := tar.NewReader(gzr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
 			return nil
 		}
-
-		relPath, _ := filepath.Rel(dir, path)
-		hash.Write([]byte(relPath))
-
-		f, err := os.Open(path)
 		if err != nil {
-			return nil
+			return err
 		}
-		defer f.Close()
-
-		io.Copy(hash, f)
-		return nil
-	})
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
 
-	return hex.EncodeToString(hash.Sum(nil))
+// saveCheckpointManifest 保存检查点的文件清单
+func (m *CheckpointManager) saveCheckpointManifest(username, siteID, checkpointID string, manifest Manifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.getCheckpointManifestPath(username, siteID, checkpointID), data, 0644)
 }
 
 // ExtractTarGzSimple 简化版的 tar.gz 解压（不做展平处理）
@@ -434,29 +802,66 @@ func ExtractTarGzSimple(archivePath, dest string) error {
 }
 
 // GetCheckpointsUsage 获取指定站点的检查点使用情况
-func (m *CheckpointManager) GetCheckpointsUsage(username, siteID string) (totalSize int64, count int, err error) {
+// size 为站点独占的存储：早期格式的归档大小加上仅被该站点检查点引用的内容大小（同一内容只计一次），
+// 与其他站点共享的内容不计入，避免同一份存储在多个站点的用量和配额中重复计算；
+// logicalSize 为各检查点文件总大小之和
+func (m *CheckpointManager) GetCheckpointsUsage(username, siteID string) (size, logicalSize int64, count int, err error) {
 	checkpointsDir := m.getCheckpointsSubDir(username, siteID)
 	
 	if _, err := os.Stat(checkpointsDir); os.IsNotExist(err) {
-		return 0, 0, nil
+		return 0, 0, 0, nil
 	}
 
-	err = filepath.Walk(checkpointsDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // 忽略错误,继续统计
+	entries, err := os.ReadDir(checkpointsDir)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("遍历检查点目录失败: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
 		}
-		if !info.IsDir() && filepath.Ext(path) == ".gz" {
-			totalSize += info.Size()
+		switch {
+		case strings.HasSuffix(entry.Name(), ".tar.gz"):
+			info, err := entry.Info()
+			if err != nil {
+				continue // 忽略错误,继续统计
+			}
+			size += info.Size()
+			count++
+		case strings.HasSuffix(entry.Name(), ".tree.json"):
 			count++
 		}
-		return nil
-	})
+	}
 
+	blobSizes, siteRefs, err := m.siteBlobRefs(username, siteID)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("读取检查点文件树失败: %w", err)
+	}
+	globalRefs, err := m.blobs.refCounts(blobSizes)
 	if err != nil {
-		return 0, 0, fmt.Errorf("遍历检查点目录失败: %w", err)
+		return 0, 0, 0, err
+	}
+	for hash, blobSize := range blobSizes {
+		if globalRefs[hash] > siteRefs[hash] {
+			// 其他站点的检查点也引用该内容
+			continue
+		}
+		size += blobSize
+	}
+
+	metadata, err := m.loadSiteMetadata(username, siteID)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("加载元数据失败: %w", err)
+	}
+	for _, cp := range metadata.Checkpoints {
+		if cp.Format == CheckpointFormatContent {
+			logicalSize += cp.LogicalSize
+		} else {
+			logicalSize += cp.FileSize
+		}
 	}
 
-	return totalSize, count, nil
+	return size, logicalSize, count, nil
 }
 
 // StorageRecount 重新计算并更新站点的存储使用量到元数据
@@ -478,22 +883,29 @@ func (m *CheckpointManager) StorageRecount(username, siteID, rootDir string) err
 	}
 
 	// 计算检查点使用量
-	checkpointsSize, checkpointCount, err := m.GetCheckpointsUsage(username, siteID)
+	checkpointsSize, checkpointsLogicalSize, checkpointCount, err := m.GetCheckpointsUsage(username, siteID)
 	if err != nil {
 		return fmt.Errorf("计算检查点大小失败: %w", err)
 	}
 
+	// 当前发布以外保留的发布目录
+	releasesSize := releasesSize(rootDir)
+
 	// 构建存储使用量对象
-	totalSize := deployedSize + checkpointsSize
+	totalSize := deployedSize + checkpointsSize + releasesSize
 	metadata.StorageUsage = &DiskUsage{
-		DeployedSize:      deployedSize,
-		CheckpointsSize:   checkpointsSize,
-		TotalSize:         totalSize,
-		DeployedSizeHR:    formatBytes(deployedSize),
-		CheckpointsSizeHR: formatBytes(checkpointsSize),
-		TotalSizeHR:       formatBytes(totalSize),
-		FileCount:         fileCount,
-		CheckpointCount:   checkpointCount,
+		DeployedSize:             deployedSize,
+		CheckpointsSize:          checkpointsSize,
+		CheckpointsLogicalSize:   checkpointsLogicalSize,
+		ReleasesSize:             releasesSize,
+		TotalSize:                totalSize,
+		DeployedSizeHR:           formatBytes(deployedSize),
+		CheckpointsSizeHR:        formatBytes(checkpointsSize),
+		CheckpointsLogicalSizeHR: formatBytes(checkpointsLogicalSize),
+		ReleasesSizeHR:           formatBytes(releasesSize),
+		TotalSizeHR:              formatBytes(totalSize),
+		FileCount:                fileCount,
+		CheckpointCount:          checkpointCount,
 	}
 	metadata.UpdatedAt = time.Now()
 
@@ -521,3 +933,28 @@ func (m *CheckpointManager) GetStorageUsage(username, siteID string) (*DiskUsage
 }
 
 
+
+// GetTotalStorageUsage 汇总租户多个站点缓存的存储使用量（读取失败的站点不计入）
+func (m *CheckpointManager) GetTotalStorageUsage(username string, siteIDs []string) DiskUsage {
+	var total DiskUsage
+	for _, id := range siteIDs {
+		usage, err := m.GetStorageUsage(username, id)
+		if err != nil {
+			continue
+		}
+		total.DeployedSize += usage.DeployedSize
+		total.CheckpointsSize += usage.CheckpointsSize
+		total.CheckpointsLogicalSize += usage.CheckpointsLogicalSize
+		total.ReleasesSize += usage.ReleasesSize
+		total.TotalSize += usage.TotalSize
+		total.FileCount += usage.FileCount
+		total.CheckpointCount += usage.CheckpointCount
+	}
+
+	total.DeployedSizeHR = formatBytes(total.DeployedSize)
+	total.CheckpointsSizeHR = formatBytes(total.CheckpointsSize)
+	total.CheckpointsLogicalSizeHR = formatBytes(total.CheckpointsLogicalSize)
+	total.ReleasesSizeHR = formatBytes(total.ReleasesSize)
+	total.TotalSizeHR = formatBytes(total.TotalSize)
+	return total
+}
@@ -0,0 +1,163 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCaptureLive(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(t *testing.T, rootDir string)
+		wantNew bool
+	}{
+		{
+			name:   "站点未变化",
+			modify: func(*testing.T, string) {},
+		},
+		{
+			name: "文件被直接修改",
+			modify: func(t *testing.T, rootDir string) {
+				path := filepath.Join(rootDir, "index.html")
+				os.WriteFile(path, []byte("edited"), 0644)
+				later := time.Now().Add(time.Minute)
+				os.Chtimes(path, later, later)
+			},
+			wantNew: true,
+		},
+		{
+			name: "新增文件",
+			modify: func(t *testing.T, rootDir string) {
+				os.WriteFile(filepath.Join(rootDir, "new.html"), []byte("new"), 0644)
+			},
+			wantNew: true,
+		},
+		{
+			name: "大小和修改时间不变时沿用记录的哈希",
+			modify: func(t *testing.T, rootDir string) {
+				path := filepath.Join(rootDir, "index.html")
+				info, _ := os.Stat(path)
+				os.WriteFile(path, []byte("HOME"), 0644)
+				os.Chtimes(path, info.ModTime(), info.ModTime())
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewCheckpointManager(t.TempDir())
+			rootDir := filepath.Join(t.TempDir(), "blog")
+			os.MkdirAll(filepath.Join(rootDir, "assets"), 0755)
+			os.WriteFile(filepath.Join(rootDir, "index.html"), []byte("home"), 0644)
+			os.WriteFile(filepath.Join(rootDir, "assets", "app.js"), []byte("js"), 0644)
+			first, err := m.CreateManualCheckpoint("alice", "blog", rootDir, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.modify(t, rootDir)
+			cp, err := m.CaptureLive("alice", "blog", rootDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cp != nil; got != tt.wantNew {
+				t.Fatalf("创建了检查点 = %v, want %v", got, tt.wantNew)
+			}
+			metadata, _ := m.ListCheckpoints("alice", "blog")
+			if tt.wantNew && metadata.Current != cp.ID || !tt.wantNew && metadata.Current != first.ID {
+				t.Errorf("Current = %s", metadata.Current)
+			}
+		})
+	}
+}
+
+func TestCaptureLiveAfterCheckout(t *testing.T) {
+	m := NewCheckpointManager(t.TempDir())
+	rootDir := filepath.Join(t.TempDir(), "blog")
+	os.MkdirAll(rootDir, 0755)
+	os.WriteFile(filepath.Join(rootDir, "index.html"), []byte("home"), 0644)
+	first, err := m.CreateManualCheckpoint("alice", "blog", rootDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 检出时还原记录的修改时间，检出后的站点与检查点一致
+	target := filepath.Join(t.TempDir(), "restored")
+	if err := m.CheckoutCheckpoint("alice", "blog", first.ID, target); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := os.Stat(filepath.Join(rootDir, "index.html"))
+	got, err := os.Stat(filepath.Join(target, "index.html"))
+	if err != nil || !got.ModTime().Equal(want.ModTime()) {
+		t.Fatalf("检出文件的修改时间 = %v, want %v (%v)", got.ModTime(), want.ModTime(), err)
+	}
+	if cp, err := m.CaptureLive("alice", "blog", target); err != nil || cp != nil {
+		t.Errorf("CaptureLive = %v, %v, want 无需创建检查点", cp, err)
+	}
+}
+
+func TestGetCheckpointsUsageExcludesSharedContent(t *testing.T) {
+	m := NewCheckpointManager(t.TempDir())
+	newSite := func(username, siteID string, files map[string]string) {
+		t.Helper()
+		rootDir := filepath.Join(t.TempDir(), siteID)
+		os.MkdirAll(rootDir, 0755)
+		for name, content := range files {
+			os.WriteFile(filepath.Join(rootDir, name), []byte(content), 0644)
+		}
+		if _, err := m.CreateManualCheckpoint(username, siteID, rootDir, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newSite("alice", "blog", map[string]string{"lib.js": "shared-library", "index.html": "alice"})
+	newSite("bob", "docs", map[string]string{"lib.js": "shared-library", "index.html": "bob!"})
+
+	usage := func(username, siteID string) (int64, int64) {
+		t.Helper()
+		size, logical, _, err := m.GetCheckpointsUsage(username, siteID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return size, logical
+	}
+	// 共享内容不计入任何站点的独占存储，逻辑大小仍包含全部文件
+	if size, logical := usage("alice", "blog"); size != int64(len("alice")) || logical != int64(len("shared-library")+len("alice")) {
+		t.Errorf("alice usage = %d, %d", size, logical)
+	}
+	if size, _ := usage("bob", "docs"); size != int64(len("bob!")) {
+		t.Errorf("bob usage = %d", size)
+	}
+
+	// 其他站点不再引用后计入最后一个引用它的站点
+	if err := m.RemoveUser("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if size, _ := usage("alice", "blog"); size != int64(len("shared-library")+len("alice")) {
+		t.Errorf("bob 删除后 alice usage = %d", size)
+	}
+}
+
+func TestGetTotalStorageUsage(t *testing.T) {
+	m := NewCheckpointManager(t.TempDir())
+	for id, usage := range map[string]*DiskUsage{
+		"blog": {DeployedSize: 1024, CheckpointsSize: 512, TotalSize: 1536, FileCount: 3, CheckpointCount: 2},
+		"docs": {DeployedSize: 2048, ReleasesSize: 100, TotalSize: 2148, FileCount: 5},
+	} {
+		if err := m.saveSiteMetadata(&SiteCheckpointMetadata{Username: "alice", SiteID: id, StorageUsage: usage}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 没有元数据的站点按零用量计入
+	got := m.GetTotalStorageUsage("alice", []string{"blog", "docs", "empty"})
+	if got.DeployedSize != 3072 || got.CheckpointsSize != 512 || got.ReleasesSize != 100 || got.TotalSize != 3684 {
+		t.Errorf("sizes = %+v", got)
+	}
+	if got.FileCount != 8 || got.CheckpointCount != 2 {
+		t.Errorf("FileCount/CheckpointCount = %d/%d, want 8/2", got.FileCount, got.CheckpointCount)
+	}
+	if got.DeployedSizeHR != formatBytes(3072) || got.TotalSizeHR != formatBytes(3684) {
+		t.Errorf("HR = %q/%q", got.DeployedSizeHR, got.TotalSizeHR)
+	}
+}
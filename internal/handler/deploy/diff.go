@@ -0,0 +1,378 @@
+package deploy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"pages/pkg/api"
+)
+
+// CheckpointDiff 两个检查点之间的文件差异（与 pkg/api 共用）
+type CheckpointDiff = api.CheckpointDiff
+
+// CurrentCheckpointRef 比较检查点时代表站点当前检查点的别名（不能用作标签）
+const CurrentCheckpointRef = "current"
+
+// 文本差异的限制
+const (
+	maxTextDiffSize  = 64 << 10 // 单个文件超过该大小时不生成文本差异
+	maxTextDiffTotal = 1 << 20  // 一次比较中用于生成文本差异的内容总大小上限
+	maxDiffCells     = 4 << 20  // 逐行比较的计算量上限（两端不同行数之积），超过时整段替换
+	diffContext      = 3        // 差异上下文行数
+)
+
+// DiffCheckpoints 比较两个检查点（ID、标签或 current）的文件，text 为 true 时为较小的文本文件生成统一格式的差异
+// 早期格式的检查点直接流式读取归档，不解压到磁盘
+func (m *CheckpointManager) DiffCheckpoints(username, siteID, fromRef, toRef string, text bool) (*CheckpointDiff, error) {
+	metadata, err := m.loadSiteMetadata(username, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("加载站点元数据失败: %w", err)
+	}
+	from, err := resolveDiffRef(metadata, fromRef)
+	if err != nil {
+		return nil, err
+	}
+	to, err := resolveDiffRef(metadata, toRef)
+	if err != nil {
+		return nil, err
+	}
+
+	fromFiles, err := m.checkpointFiles(username, siteID, from)
+	if err != nil {
+		return nil, fmt.Errorf("读取检查点 %s 失败: %w", from, err)
+	}
+	toFiles, err := m.checkpointFiles(username, siteID, to)
+	if err != nil {
+		return nil, fmt.Errorf("读取检查点 %s 失败: %w", to, err)
+	}
+
+	diff := &CheckpointDiff{
+		From:     from,
+		To:       to,
+		Added:    []api.FileDiff{},
+		Removed:  []api.FileDiff{},
+		Modified: []api.FileDiff{},
+	}
+	for name, f := range fromFiles {
+		t, ok := toFiles[name]
+		switch {
+		case !ok:
+			diff.Removed = append(diff.Removed, api.FileDiff{Path: name, From: &f})
+		case t.Hash != f.Hash:
+			diff.Modified = append(diff.Modified, api.FileDiff{Path: name, From: &f, To: &t})
+		default:
+			diff.Unchanged++
+		}
+	}
+	for name, t := range toFiles {
+		if _, ok := fromFiles[name]; !ok {
+			diff.Added = append(diff.Added, api.FileDiff{Path: name, To: &t})
+		}
+	}
+	for _, list := range [][]api.FileDiff{diff.Added, diff.Removed, diff.Modified} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	}
+
+	if text {
+		if err := m.addTextDiffs(username, siteID, diff); err != nil {
+			return nil, err
+		}
+	}
+	return diff, nil
+}
+
+// resolveDiffRef 将 ID、标签或 current 解析为检查点 ID
+func resolveDiffRef(metadata *SiteCheckpointMetadata, ref string) (string, error) {
+	if ref == CurrentCheckpointRef {
+		if metadata.Current == "" {
+			return "", fmt.Errorf("%w: 站点没有当前检查点", ErrCheckpointNotFound)
+		}
+		return metadata.Current, nil
+	}
+	i := resolveCheckpoint(metadata, ref)
+	if i < 0 {
+		return "", fmt.Errorf("%w: %s", ErrCheckpointNotFound, ref)
+	}
+	return metadata.Checkpoints[i].ID, nil
+}
+
+// checkpointFiles 返回检查点中的文件（路径 -> 内容哈希和大小）
+func (m *CheckpointManager) checkpointFiles(username, siteID, checkpointID string) (map[string]api.FileVersion, error) {
+	files := make(map[string]api.FileVersion)
+	if tree, err := m.loadTree(username, siteID, checkpointID); err == nil {
+		for _, e := range tree.Entries {
+			if e.Hash != "" {
+				files[e.Path] = api.FileVersion{Hash: e.Hash, Size: e.Size}
+			}
+		}
+		return files, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	err := m.walkCheckpoint(username, siteID, checkpointID, func(name string, r io.Reader) error {
+		h := sha256.New()
+		n, err := io.Copy(h, r)
+		if err != nil {
+			return err
+		}
+		files[path.Clean(name)] = api.FileVersion{Hash: hex.EncodeToString(h.Sum(nil)), Size: n}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// readCheckpointFiles 读取检查点中指定路径的文件内容
+func (m *CheckpointManager) readCheckpointFiles(username, siteID, checkpointID string, files map[string]api.FileVersion) (map[string][]byte, error) {
+	contents := make(map[string][]byte, len(files))
+	if len(files) == 0 {
+		return contents, nil
+	}
+	if _, err := os.Stat(m.getCheckpointTreePath(username, siteID, checkpointID)); err == nil {
+		for name, f := range files {
+			data, err := os.ReadFile(m.blobs.path(f.Hash))
+			if err != nil {
+				return nil, fmt.Errorf("读取内容 %s 失败: %w", f.Hash, err)
+			}
+			contents[name] = data
+		}
+		return contents, nil
+	}
+
+	err := m.walkCheckpoint(username, siteID, checkpointID, func(name string, r io.Reader) error {
+		name = path.Clean(name)
+		if _, ok := files[name]; !ok {
+			return nil
+		}
+		data, err := io.ReadAll(io.LimitReader(r, maxTextDiffSize+1))
+		if err != nil {
+			return err
+		}
+		contents[name] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return contents, nil
+}
+
+// addTextDiffs 为两端均为较小文本文件的差异生成统一格式的文本差异
+func (m *CheckpointManager) addTextDiffs(username, siteID string, diff *CheckpointDiff) error {
+	fromWanted := make(map[string]api.FileVersion)
+	toWanted := make(map[string]api.FileVersion)
+	budget := int64(maxTextDiffTotal)
+	for _, list := range [][]api.FileDiff{diff.Modified, diff.Added, diff.Removed} {
+		for _, fd := range list {
+			var size int64
+			if fd.From != nil {
+				size += fd.From.Size
+			}
+			if fd.To != nil {
+				size += fd.To.Size
+			}
+			if (fd.From != nil && fd.From.Size > maxTextDiffSize) || (fd.To != nil && fd.To.Size > maxTextDiffSize) || size > budget {
+				continue
+			}
+			budget -= size
+			if fd.From != nil {
+				fromWanted[fd.Path] = *fd.From
+			}
+			if fd.To != nil {
+				toWanted[fd.Path] = *fd.To
+			}
+		}
+	}
+
+	fromContents, err := m.readCheckpointFiles(username, siteID, diff.From, fromWanted)
+	if err != nil {
+		return fmt.Errorf("读取检查点 %s 失败: %w", diff.From, err)
+	}
+	toContents, err := m.readCheckpointFiles(username, siteID, diff.To, toWanted)
+	if err != nil {
+		return fmt.Errorf("读取检查点 %s 失败: %w", diff.To, err)
+	}
+
+	for _, list := range [][]api.FileDiff{diff.Modified, diff.Added, diff.Removed} {
+		for i := range list {
+			fd := &list[i]
+			a, aok := fromContents[fd.Path]
+			b, bok := toContents[fd.Path]
+			if (fd.From != nil && (!aok || !isText(a))) || (fd.To != nil && (!bok || !isText(b))) {
+				continue
+			}
+			fromName, toName := "a/"+fd.Path, "b/"+fd.Path
+			if fd.From == nil {
+				fromName = "/dev/null"
+			}
+			if fd.To == nil {
+				toName = "/dev/null"
+			}
+			fd.Diff = unifiedDiff(fromName, toName, a, b)
+		}
+	}
+	return nil
+}
+
+// isText 判断内容是否为适合生成文本差异的 UTF-8 文本
+func isText(data []byte) bool {
+	return len(data) <= maxTextDiffSize && utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
+
+// diffOp 逐行比较的一步：' ' 相同、'-' 删除、'+' 新增；x、y 为该步之前两端已处理的行数
+type diffOp struct {
+	kind byte
+	x, y int
+}
+
+// unifiedDiff 生成统一格式的文本差异，没有差异（如新增空文件）时返回空字符串
+func unifiedDiff(fromName, toName string, a, b []byte) string {
+	x, y := splitLines(a), splitLines(b)
+	ops := diffLines(x, y)
+	if !slices.ContainsFunc(ops, func(op diffOp) bool { return op.kind != ' ' }) {
+		return ""
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", fromName, toName)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// 相邻变化之间的相同行不超过两倍上下文时合并为一个区块
+		start := max(i-diffContext, 0)
+		end := i + 1
+		for j := end; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+		end = min(end+diffContext, len(ops))
+
+		var aLen, bLen int
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aLen++
+			}
+			if op.kind != '-' {
+				bLen++
+			}
+		}
+		aStart, bStart := ops[start].x+1, ops[start].y+1
+		if aLen == 0 {
+			aStart--
+		}
+		if bLen == 0 {
+			bStart--
+		}
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+		for _, op := range ops[start:end] {
+			line := ""
+			if op.kind == '+' {
+				line = y[op.y]
+			} else {
+				line = x[op.x]
+			}
+			buf.WriteByte(op.kind)
+			buf.WriteString(line)
+			if !strings.HasSuffix(line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return buf.String()
+}
+
+// splitLines 按行拆分文本，每行保留结尾的换行符
+func splitLines(data []byte) []string {
+	var lines []string
+	s := string(data)
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			lines = append(lines, s)
+			break
+		}
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+	return lines
+}
+
+// diffLines 按最长公共子序列逐行比较，返回编辑步骤
+func diffLines(x, y []string) []diffOp {
+	// 去掉相同的开头和结尾，只比较中间部分
+	pre := 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+
+	var ops []diffOp
+	for i := 0; i < pre; i++ {
+		ops = append(ops, diffOp{' ', i, i})
+	}
+
+	n, m := len(x)-pre-suf, len(y)-pre-suf
+	i, j := 0, 0
+	if n*m <= maxDiffCells {
+		// lcs[i*(m+1)+j] 为 x[pre+i:] 与 y[pre+j:] 中间部分的最长公共子序列长度
+		lcs := make([]int32, (n+1)*(m+1))
+		at := func(i, j int) int32 { return lcs[i*(m+1)+j] }
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if x[pre+i] == y[pre+j] {
+					lcs[i*(m+1)+j] = at(i+1, j+1) + 1
+				} else {
+					lcs[i*(m+1)+j] = max(at(i+1, j), at(i, j+1))
+				}
+			}
+		}
+		for i < n && j < m {
+			switch {
+			case x[pre+i] == y[pre+j]:
+				ops = append(ops, diffOp{' ', pre + i, pre + j})
+				i++
+				j++
+			case at(i+1, j) >= at(i, j+1):
+				ops = append(ops, diffOp{'-', pre + i, pre + j})
+				i++
+			default:
+				ops = append(ops, diffOp{'+', pre + i, pre + j})
+				j++
+			}
+		}
+	}
+	// 剩余部分（或超过计算量上限时的整个中间部分）整段删除再新增
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', pre + i, pre + j})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', pre + n, pre + j})
+	}
+
+	for k := 0; k < suf; k++ {
+		ops = append(ops, diffOp{' ', len(x) - suf + k, len(y) - suf + k})
+	}
+	return ops
+}
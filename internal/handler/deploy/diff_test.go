@@ -0,0 +1,124 @@
+package deploy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(s ...string) []byte { return []byte(strings.Join(s, "\n") + "\n") }
+	tests := []struct {
+		name     string
+		from, to string
+		a, b     []byte
+		want     string
+	}{
+		{
+			name: "内容相同",
+			from: "a/f", to: "b/f",
+			a: lines("a", "b"), b: lines("a", "b"),
+			want: "",
+		},
+		{
+			name: "修改一行",
+			from: "a/f", to: "b/f",
+			a: lines("a", "b", "c"), b: lines("a", "B", "c"),
+			want: "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "新增文件",
+			from: "/dev/null", to: "b/f",
+			b:    lines("x"),
+			want: "--- /dev/null\n+++ b/f\n@@ -0,0 +1,1 @@\n+x\n",
+		},
+		{
+			name: "删除文件",
+			from: "a/f", to: "/dev/null",
+			a:    lines("x", "y"),
+			want: "--- a/f\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-x\n-y\n",
+		},
+		{
+			name: "新增空文件",
+			from: "/dev/null", to: "b/f",
+			want: "",
+		},
+		{
+			name: "结尾没有换行",
+			from: "a/f", to: "b/f",
+			a: []byte("a"), b: []byte("b"),
+			want: "--- a/f\n+++ b/f\n@@ -1,1 +1,1 @@\n-a\n\\ No newline at end of file\n+b\n\\ No newline at end of file\n",
+		},
+		{
+			name: "相距较远的变化分为两个区块",
+			from: "a/f", to: "b/f",
+			a: lines("1", "2", "3", "4", "5", "6", "7", "8", "9", "10"),
+			b: lines("one", "2", "3", "4", "5", "6", "7", "8", "9", "ten"),
+			want: "--- a/f\n+++ b/f\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff(tt.from, tt.to, tt.a, tt.b); got != tt.want {
+				t.Errorf("unifiedDiff =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffCheckpoints(t *testing.T) {
+	m := NewCheckpointManager(t.TempDir())
+	rootDir := filepath.Join(t.TempDir(), "blog")
+	os.MkdirAll(rootDir, 0755)
+	write := func(name, content string) {
+		os.WriteFile(filepath.Join(rootDir, name), []byte(content), 0644)
+	}
+	write("index.html", "home\n")
+	write("about.html", "about\n")
+	write("logo.png", "\x89PNG\x00")
+	first, err := m.CreateManualCheckpoint("alice", "blog", rootDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("index.html", "HOME\n")
+	write("logo.png", "\x89PNG\x00\x01")
+	os.Remove(filepath.Join(rootDir, "about.html"))
+	write("new.html", "new\n")
+	if _, err := m.CreateManualCheckpoint("alice", "blog", rootDir, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := m.DiffCheckpoints("alice", "blog", first.ID, CurrentCheckpointRef, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Path != "new.html" || diff.Added[0].From != nil {
+		t.Errorf("Added = %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Path != "about.html" || diff.Removed[0].To != nil {
+		t.Errorf("Removed = %+v", diff.Removed)
+	}
+	if len(diff.Modified) != 2 || diff.Modified[0].Path != "index.html" || diff.Modified[1].Path != "logo.png" {
+		t.Fatalf("Modified = %+v", diff.Modified)
+	}
+	if diff.Unchanged != 0 {
+		t.Errorf("Unchanged = %d", diff.Unchanged)
+	}
+	if want := "--- a/index.html\n+++ b/index.html\n@@ -1,1 +1,1 @@\n-home\n+HOME\n"; diff.Modified[0].Diff != want {
+		t.Errorf("index.html diff = %q", diff.Modified[0].Diff)
+	}
+	if diff.Modified[1].Diff != "" {
+		t.Error("二进制文件不应生成文本差异")
+	}
+	if diff.Modified[1].From.Size != 5 || diff.Modified[1].To.Size != 6 {
+		t.Errorf("logo.png 大小 = %d -> %d", diff.Modified[1].From.Size, diff.Modified[1].To.Size)
+	}
+
+	if _, err := m.DiffCheckpoints("alice", "blog", "missing", CurrentCheckpointRef, false); !errors.Is(err, ErrCheckpointNotFound) {
+		t.Errorf("不存在的检查点 err = %v, want ErrCheckpointNotFound", err)
+	}
+}
@@ -4,19 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"pages/pkg/api"
 )
 
-// DiskUsage 磁盘使用情况统计
-type DiskUsage struct {
-	DeployedSize    int64  `json:"deployed_size"`     // 当前部署的站点大小(字节)
-	CheckpointsSize int64  `json:"checkpoints_size"`  // 所有检查点归档大小(字节)
-	TotalSize       int64  `json:"total_size"`        // 总使用大小(字节)
-	DeployedSizeHR  string `json:"deployed_size_h"`  // 人类可读格式
-	CheckpointsSizeHR string `json:"checkpoints_size_h"`
-	TotalSizeHR     string `json:"total_size_h"`
-	FileCount       int64  `json:"file_count"`        // 文件总数
-	CheckpointCount int    `json:"checkpoint_count"`  // 检查点数量
-}
+// DiskUsage 磁盘使用情况统计（与 pkg/client 共用）
+type DiskUsage = api.DiskUsage
 
 // GetDirectoryUsage 获取指定站点的完整磁盘使用情况
 func GetDirectoryUsage(rootDir string) (*DiskUsage, error) {
@@ -74,7 +67,7 @@ func calculateDirSize(path string) (int64, int64, error) {
 	var totalSize int64
 	var fileCount int64
 
-	err := filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+	err := filepath.Walk(resolveDir(path), func(filePath string, info os.FileInfo, err error) error {
 		if err != nil {
 			// 忽略无法访问的文件,继续统计其他文件
 			return nil
@@ -93,6 +86,17 @@ func calculateDirSize(path string) (int64, int64, error) {
 	return totalSize, fileCount, nil
 }
 
+// DirSize 计算目录中所有文件的总大小
+func DirSize(path string) (int64, error) {
+	size, _, err := calculateDirSize(path)
+	return size, err
+}
+
+// FormatBytes 将字节数格式化为人类可读的格式
+func FormatBytes(bytes int64) string {
+	return formatBytes(bytes)
+}
+
 // formatBytes 将字节数格式化为人类可读的格式
 func formatBytes(bytes int64) string {
 	const unit = 1024
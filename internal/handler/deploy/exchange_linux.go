@@ -0,0 +1,20 @@
+//go:build linux
+
+package deploy
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// exchangePaths 原子地交换两个路径（renameat2 RENAME_EXCHANGE），两者必须都存在
+// 内核或文件系统不支持时返回 errExchangeUnsupported
+func exchangePaths(a, b string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, a, unix.AT_FDCWD, b, unix.RENAME_EXCHANGE)
+	if errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EINVAL) || errors.Is(err, unix.EOPNOTSUPP) {
+		return fmt.Errorf("%w: %v", errExchangeUnsupported, err)
+	}
+	return err
+}
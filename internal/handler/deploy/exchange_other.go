@@ -0,0 +1,8 @@
+//go:build !linux
+
+package deploy
+
+// exchangePaths 仅 Linux 支持原子交换，其他平台由调用方使用先备份再重命名的方式
+func exchangePaths(a, b string) error {
+	return errExchangeUnsupported
+}
@@ -3,185 +3,391 @@ package deploy
 import (
 	"archive/tar"
 	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Format 压缩包格式
+type Format string
+
+// 支持的压缩包格式
+const (
+	FormatZip    Format = "zip"
+	FormatTar    Format = "tar"
+	FormatTarGz  Format = "tar.gz"
+	FormatTarZst Format = "tar.zst"
+	FormatTarXz  Format = "tar.xz"
+	FormatTarBz2 Format = "tar.bz2"
+)
+
+// ErrUnsupportedFormat 无法识别的压缩包格式
+var ErrUnsupportedFormat = errors.New("仅支持 zip、tar、tar.gz、tar.zst、tar.xz 或 tar.bz2 压缩包")
+
+// 文件头魔数
+var (
+	magicZip      = []byte("PK\x03\x04")
+	magicZipEmpty = []byte("PK\x05\x06")
+	magicGzip     = []byte{0x1f, 0x8b}
+	magicZstd     = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicXz       = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	magicBzip2    = []byte("BZh")
+	magicTar      = []byte("ustar") // 位于第 257 字节
 )
 
-// ExtractZip 解压 zip 文件到目标目录
-func ExtractZip(zipPath, dest string) error {
-	f, err := os.Open(zipPath)
+// tarMagicOffset tar 头中 magic 字段的偏移
+const tarMagicOffset = 257
+
+// extensionFormats 文件名后缀到格式的映射（按顺序匹配，长后缀在前）
+var extensionFormats = []struct {
+	suffix string
+	format Format
+}{
+	{".tar.gz", FormatTarGz},
+	{".tgz", FormatTarGz},
+	{".tar.zst", FormatTarZst},
+	{".tzst", FormatTarZst},
+	{".tar.xz", FormatTarXz},
+	{".txz", FormatTarXz},
+	{".tar.bz2", FormatTarBz2},
+	{".tbz2", FormatTarBz2},
+	{".tbz", FormatTarBz2},
+	{".tar", FormatTar},
+	{".zip", FormatZip},
+}
+
+// DetectFormat 按文件头识别压缩包格式，无法识别时按文件名后缀判断
+// 压缩流（gzip、zstd、xz、bzip2）均视为压缩的 tar 包
+func DetectFormat(archivePath, filename string) (Format, error) {
+	f, err := os.Open(archivePath)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer f.Close()
 
+	header := make([]byte, headerSize)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return detectFormat(header[:n], filename)
+}
+
+// headerSize 识别格式所需的文件头长度
+const headerSize = tarMagicOffset + 5
+
+func detectFormat(header []byte, filename string) (Format, error) {
+	switch {
+	case bytes.HasPrefix(header, magicZip), bytes.HasPrefix(header, magicZipEmpty):
+		return FormatZip, nil
+	case bytes.HasPrefix(header, magicGzip):
+		return FormatTarGz, nil
+	case bytes.HasPrefix(header, magicZstd):
+		return FormatTarZst, nil
+	case bytes.HasPrefix(header, magicXz):
+		return FormatTarXz, nil
+	case bytes.HasPrefix(header, magicBzip2):
+		return FormatTarBz2, nil
+	case len(header) >= headerSize && bytes.Equal(header[tarMagicOffset:headerSize], magicTar):
+		return FormatTar, nil
+	}
+
+	// 旧式 tar（无 ustar 标识）等无法从文件头识别的格式按后缀判断
+	name := strings.ToLower(filename)
+	for _, ext := range extensionFormats {
+		if strings.HasSuffix(name, ext.suffix) {
+			return ext.format, nil
+		}
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Extract 将压缩包文件解压到目标目录
+// 所有格式共用路径安全检查、符号链接检查和部署限制
+func Extract(archivePath, dest string, format Format, limits Limits) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
 	info, err := f.Stat()
 	if err != nil {
+		f.Close()
 		return err
 	}
 
-	zr, err := zip.NewReader(f, info.Size())
+	guard := newExtractGuard(limits)
+	if err := guard.consume(info.Size()); err != nil {
+		f.Close()
+		return err
+	}
+
+	var ar archiveReader
+	if format == FormatZip {
+		ar, err = newZipArchive(f, info.Size())
+	} else {
+		ar, err = newTarArchive(f, format, f.Close)
+	}
 	if err != nil {
+		f.Close()
 		return err
 	}
+	return extractAll(ar, dest, guard)
+}
 
-	for _, file := range zr.File {
-		targetPath := filepath.Join(dest, file.Name)
-		if !isWithinRoot(dest, targetPath) {
-			return fmt.Errorf("非法路径: %s", file.Name)
-		}
+// ExtractStream 从数据流识别格式并解压到目标目录，返回识别出的格式
+// tar 系列格式边读边解压，不落临时文件；zip 需要随机访问，先写入 spoolDir 下的临时文件再解压。
+// filename 仅在无法从文件头识别格式时用于按后缀判断，可为空。
+func ExtractStream(r io.Reader, filename, dest, spoolDir string, limits Limits) (Format, error) {
+	guard := newExtractGuard(limits)
+	br := bufio.NewReaderSize(guard.source(r), 64*1024)
 
-		if file.FileInfo().IsDir() {
-			if err := os.MkdirAll(targetPath, 0755); err != nil {
-				return err
-			}
-			continue
-		}
+	header, err := br.Peek(headerSize)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	format, err := detectFormat(header, filename)
+	if err != nil {
+		return "", err
+	}
 
-		if file.FileInfo().Mode()&os.ModeSymlink != 0 {
-			return fmt.Errorf("不支持压缩包内的符号链接: %s", file.Name)
+	if format != FormatZip {
+		ar, err := newTarArchive(br, format, func() error { return nil })
+		if err != nil {
+			return format, err
 		}
+		return format, extractAll(ar, dest, guard)
+	}
 
-		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-			return err
-		}
+	spool, err := os.CreateTemp(spoolDir, "upload-*.zip")
+	if err != nil {
+		return format, err
+	}
+	defer os.Remove(spool.Name())
 
-		rc, err := file.Open()
-		if err != nil {
-			return err
-		}
+	size, err := io.Copy(spool, br)
+	if err != nil {
+		spool.Close()
+		return format, err
+	}
+	ar, err := newZipArchive(spool, size)
+	if err != nil {
+		spool.Close()
+		return format, fmt.Errorf("读取 %s 失败: %w", format, err)
+	}
+	return format, extractAll(ar, dest, guard)
+}
+
+// extractAll 依次解压所有条目
+func extractAll(ar archiveReader, dest string, guard *extractGuard) error {
+	defer ar.Close()
 
-		mode := file.Mode()
-		out, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	for {
+		entry, err := ar.Next()
+		if err == io.EOF {
+			return nil
+		}
 		if err != nil {
-			rc.Close()
 			return err
 		}
-		if _, err := io.Copy(out, rc); err != nil {
-			out.Close()
-			rc.Close()
+		if err := extractEntry(entry, dest, guard); err != nil {
 			return err
 		}
-		out.Close()
-		rc.Close()
 	}
-
-	return nil
 }
 
-// ExtractTar 解压 tar 文件到目标目录
-func ExtractTar(archivePath, dest string) error {
-	f, err := os.Open(archivePath)
-	if err != nil {
+// extractEntry 解压单个条目
+func extractEntry(entry *archiveEntry, dest string, guard *extractGuard) error {
+	targetPath := filepath.Join(dest, entry.name)
+	if !isWithinRoot(dest, targetPath) {
+		return fmt.Errorf("非法路径: %s", entry.name)
+	}
+	if err := guard.entry(entry.name); err != nil {
 		return err
 	}
-	defer f.Close()
 
-	tr := tar.NewReader(f)
-	for {
-		hdr, err := tr.Next()
-		if err == io.EOF {
-			break
+	switch entry.kind {
+	case entryDir:
+		return os.MkdirAll(targetPath, 0755)
+	case entryLink:
+		return fmt.Errorf("不支持压缩包内的符号链接: %s", entry.name)
+	case entryFile:
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+			return err
 		}
+		rc, err := entry.open()
 		if err != nil {
 			return err
 		}
+		defer rc.Close()
 
-		targetPath := filepath.Join(dest, hdr.Name)
-		if !isWithinRoot(dest, targetPath) {
-			return fmt.Errorf("非法路径: %s", hdr.Name)
+		out, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, entry.mode.Perm())
+		if err != nil {
+			return err
 		}
-
-		switch hdr.Typeflag {
-		case tar.TypeDir:
-			if err := os.MkdirAll(targetPath, 0755); err != nil {
-				return err
-			}
-		case tar.TypeReg:
-			if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-				return err
-			}
-			out, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode))
-			if err != nil {
-				return err
-			}
-			if _, err := io.Copy(out, tr); err != nil {
-				out.Close()
-				return err
-			}
-			if err := out.Close(); err != nil {
-				return err
-			}
-		case tar.TypeSymlink, tar.TypeLink:
-			return fmt.Errorf("不支持压缩包内的符号链接: %s", hdr.Name)
-		default:
-			// 忽略其他类型
+		if _, err := io.Copy(out, guard.reader(rc, entry.name)); err != nil {
+			out.Close()
+			return err
 		}
+		return out.Close()
+	default:
+		// 忽略其他类型（设备文件、FIFO 等）
+		return nil
 	}
+}
+
+// entryKind 条目类型
+type entryKind int
+
+const (
+	entryFile entryKind = iota
+	entryDir
+	entryLink
+	entryOther
+)
 
-	return nil
+// archiveEntry 压缩包中的一个条目
+type archiveEntry struct {
+	name string
+	kind entryKind
+	mode os.FileMode
+	open func() (io.ReadCloser, error) // 打开文件内容（仅 entryFile）
 }
 
-// ExtractTarGz 解压 tar.gz 文件到目标目录
-func ExtractTarGz(archivePath, dest string) error {
-	f, err := os.Open(archivePath)
+// archiveReader 按顺序遍历压缩包条目的流式读取器
+type archiveReader interface {
+	// Next 返回下一个条目，遍历结束时返回 io.EOF
+	Next() (*archiveEntry, error)
+	Close() error
+}
+
+// newZipArchive 打开 zip 压缩包
+func newZipArchive(f *os.File, size int64) (archiveReader, error) {
+	zr, err := zip.NewReader(f, size)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer f.Close()
+	return &zipArchive{file: f, files: zr.File}, nil
+}
 
-	gzr, err := gzip.NewReader(f)
+// newTarArchive 打开（压缩的）tar 数据流，closeSource 在读取结束时关闭底层数据源
+func newTarArchive(r io.Reader, format Format, closeSource func() error) (archiveReader, error) {
+	dr, closeStream, err := decompressor(r, format)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("读取 %s 失败: %w", format, err)
 	}
-	defer gzr.Close()
+	return &tarArchive{tr: tar.NewReader(dr), closeStream: closeStream, closeSource: closeSource}, nil
+}
 
-	tr := tar.NewReader(gzr)
-	for {
-		hdr, err := tr.Next()
-		if err == io.EOF {
-			break
+// decompressor 返回 tar 包外层压缩流的解压读取器
+func decompressor(r io.Reader, format Format) (io.Reader, func(), error) {
+	switch format {
+	case FormatTar:
+		return r, func() {}, nil
+	case FormatTarGz:
+		gzr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
 		}
+		return gzr, func() { gzr.Close() }, nil
+	case FormatTarZst:
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
-
-		targetPath := filepath.Join(dest, hdr.Name)
-		if !isWithinRoot(dest, targetPath) {
-			return fmt.Errorf("非法路径: %s", hdr.Name)
+		return zr, zr.Close, nil
+	case FormatTarXz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, nil, err
 		}
+		return xr, func() {}, nil
+	case FormatTarBz2:
+		return bzip2.NewReader(r), func() {}, nil
+	default:
+		return nil, nil, ErrUnsupportedFormat
+	}
+}
 
-		switch hdr.Typeflag {
-		case tar.TypeDir:
-			if err := os.MkdirAll(targetPath, 0755); err != nil {
-				return err
-			}
-		case tar.TypeReg:
-			if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-				return err
-			}
-			out, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode))
-			if err != nil {
-				return err
-			}
-			if _, err := io.Copy(out, tr); err != nil {
-				out.Close()
-				return err
-			}
-			if err := out.Close(); err != nil {
-				return err
-			}
-		case tar.TypeSymlink, tar.TypeLink:
-			return fmt.Errorf("不支持压缩包内的符号链接: %s", hdr.Name)
-		default:
-			// 忽略其他类型
-		}
+// zipArchive zip 格式的 archiveReader
+type zipArchive struct {
+	file  *os.File
+	files []*zip.File
+	next  int
+}
+
+func (a *zipArchive) Next() (*archiveEntry, error) {
+	if a.next >= len(a.files) {
+		return nil, io.EOF
 	}
+	file := a.files[a.next]
+	a.next++
+
+	mode := file.Mode()
+	entry := &archiveEntry{name: file.Name, mode: mode, open: file.Open}
+	switch {
+	case mode.IsDir():
+		entry.kind = entryDir
+	case mode&os.ModeSymlink != 0:
+		entry.kind = entryLink
+	case mode.IsRegular():
+		entry.kind = entryFile
+	default:
+		entry.kind = entryOther
+	}
+	return entry, nil
+}
+
+func (a *zipArchive) Close() error {
+	return a.file.Close()
+}
+
+// tarArchive tar 及压缩 tar 格式的 archiveReader
+type tarArchive struct {
+	tr          *tar.Reader
+	closeStream func()
+	closeSource func() error
+}
+
+func (a *tarArchive) Next() (*archiveEntry, error) {
+	hdr, err := a.tr.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &archiveEntry{
+		name: hdr.Name,
+		mode: os.FileMode(hdr.Mode),
+		open: func() (io.ReadCloser, error) { return io.NopCloser(a.tr), nil },
+	}
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		entry.kind = entryDir
+	case tar.TypeReg:
+		entry.kind = entryFile
+	case tar.TypeSymlink, tar.TypeLink:
+		entry.kind = entryLink
+	default:
+		entry.kind = entryOther
+	}
+	return entry, nil
+}
 
-	return nil
+func (a *tarArchive) Close() error {
+	a.closeStream()
+	return a.closeSource()
 }
 
 // isWithinRoot 检查路径是否在根目录内（防止路径遍历攻击）
@@ -195,8 +401,7 @@ func isWithinRoot(root, path string) bool {
 
 // isPathTraversal 检查路径是否包含路径遍历
 func isPathTraversal(path string) bool {
-	parts := filepath.SplitList(path)
-	for _, part := range parts {
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
 		if part == ".." {
 			return true
 		}
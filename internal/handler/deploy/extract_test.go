@@ -0,0 +1,206 @@
+package deploy
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+func TestDetectFormat(t *testing.T) {
+	ustar := make([]byte, headerSize)
+	copy(ustar[tarMagicOffset:], magicTar)
+	tests := []struct {
+		name     string
+		header   []byte
+		filename string
+		want     Format
+		wantErr  bool
+	}{
+		{name: "zip", header: []byte("PK\x03\x04rest"), filename: "site.tar.gz", want: FormatZip},
+		{name: "空 zip", header: []byte("PK\x05\x06"), want: FormatZip},
+		{name: "gzip", header: []byte{0x1f, 0x8b, 8}, filename: "site.zip", want: FormatTarGz},
+		{name: "zstd", header: []byte{0x28, 0xb5, 0x2f, 0xfd, 0}, want: FormatTarZst},
+		{name: "xz", header: []byte{0xfd, '7', 'z', 'X', 'Z', 0x00, 0}, want: FormatTarXz},
+		{name: "bzip2", header: []byte("BZh91AY"), want: FormatTarBz2},
+		{name: "ustar", header: ustar, want: FormatTar},
+		{name: "文件头无法识别时按后缀", header: []byte("old tar"), filename: "Site.TAR", want: FormatTar},
+		{name: "后缀 tgz", header: nil, filename: "site.tgz", want: FormatTarGz},
+		{name: "后缀 tbz", header: nil, filename: "site.tbz", want: FormatTarBz2},
+		{name: "无法识别", header: []byte("hello"), filename: "site.rar", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectFormat(tt.header, tt.filename)
+			if tt.wantErr {
+				if err != ErrUnsupportedFormat {
+					t.Fatalf("err = %v, want ErrUnsupportedFormat", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("detectFormat = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// testEntry 构造压缩包使用的条目，link 非空时为符号链接
+type testEntry struct {
+	name, content, link string
+}
+
+// buildTar 生成 tar 包并按 format 压缩
+func buildTar(t *testing.T, format Format, entries []testEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch format {
+	case FormatTar:
+		w = nopWriteCloser{&buf}
+	case FormatTarGz:
+		w = gzip.NewWriter(&buf)
+	case FormatTarZst:
+		w, err = zstd.NewWriter(&buf)
+	case FormatTarXz:
+		w, err = xz.NewWriter(&buf)
+	default:
+		t.Fatalf("不支持生成 %s", format)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		if e.link != "" {
+			hdr = &tar.Header{Name: e.name, Linkname: e.link, Typeflag: tar.TypeSymlink}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(e.content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func buildZip(t *testing.T, entries []testEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(e.content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestExtractStreamFormats(t *testing.T) {
+	entries := []testEntry{{name: "index.html", content: "home"}, {name: "assets/app.js", content: "js"}}
+	tests := []struct {
+		format Format
+		data   []byte
+	}{
+		{FormatZip, buildZip(t, entries)},
+		{FormatTar, buildTar(t, FormatTar, entries)},
+		{FormatTarGz, buildTar(t, FormatTarGz, entries)},
+		{FormatTarZst, buildTar(t, FormatTarZst, entries)},
+		{FormatTarXz, buildTar(t, FormatTarXz, entries)},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			dest := t.TempDir()
+			// 文件名故意使用错误的后缀，格式应从文件头识别
+			format, err := ExtractStream(bytes.NewReader(tt.data), "site.bin", dest, t.TempDir(), Limits{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != tt.format {
+				t.Errorf("format = %q, want %q", format, tt.format)
+			}
+			for _, e := range entries {
+				data, err := os.ReadFile(filepath.Join(dest, e.name))
+				if err != nil || string(data) != e.content {
+					t.Errorf("%s = %q, %v, want %q", e.name, data, err, e.content)
+				}
+			}
+
+			// 从文件解压得到相同结果
+			archive := filepath.Join(t.TempDir(), "upload")
+			os.WriteFile(archive, tt.data, 0644)
+			dest = t.TempDir()
+			if err := Extract(archive, dest, tt.format, Limits{}); err != nil {
+				t.Fatal(err)
+			}
+			if data, _ := os.ReadFile(filepath.Join(dest, "assets", "app.js")); string(data) != "js" {
+				t.Errorf("Extract: assets/app.js = %q", data)
+			}
+		})
+	}
+}
+
+func TestExtractRejectsUnsafeEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    func(t *testing.T) []byte
+		wantErr string
+	}{
+		{
+			name: "tar 路径穿越",
+			data: func(t *testing.T) []byte {
+				return buildTar(t, FormatTarGz, []testEntry{{name: "../evil", content: "x"}})
+			},
+			wantErr: "非法路径",
+		},
+		{
+			name:    "zip 路径穿越",
+			data:    func(t *testing.T) []byte { return buildZip(t, []testEntry{{name: "a/../../evil", content: "x"}}) },
+			wantErr: "非法路径",
+		},
+		{
+			name: "符号链接",
+			data: func(t *testing.T) []byte {
+				return buildTar(t, FormatTar, []testEntry{{name: "passwd", link: "/etc/passwd"}})
+			},
+			wantErr: "符号链接",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dest := filepath.Join(root, "site")
+			_, err := ExtractStream(bytes.NewReader(tt.data(t)), "", dest, root, Limits{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if _, err := os.Lstat(filepath.Join(root, "evil")); !os.IsNotExist(err) {
+				t.Error("条目被写到了目标目录之外")
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"pages/pkg/api"
+)
+
+// maxCheckpointLabels 单个检查点的标签数量上限
+const maxCheckpointLabels = 16
+
+// 可通过 errors.Is 判断的检查点错误
+var (
+	ErrCheckpointNotFound = errors.New(api.MsgCheckpointNotFound)
+	ErrCheckpointPinned   = errors.New("检查点已固定，取消固定后才能删除")
+	ErrInvalidLabel       = errors.New("标签无效")
+	ErrLabelInUse         = errors.New("标签已被其他检查点使用")
+)
+
+// labelPattern 标签格式：字母或数字开头，可包含字母、数字和 . _ + -，最长 64 个字符
+var labelPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]{0,63}$`)
+
+// ResolveCheckpoint 按 ID 或标签查找检查点，ID 优先
+func (m *CheckpointManager) ResolveCheckpoint(username, siteID, ref string) (*Checkpoint, error) {
+	metadata, err := m.loadSiteMetadata(username, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("加载站点元数据失败: %w", err)
+	}
+	if i := resolveCheckpoint(metadata, ref); i >= 0 {
+		cp := metadata.Checkpoints[i]
+		return &cp, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, ref)
+}
+
+// resolveCheckpoint 返回 ID 或标签为 ref 的检查点下标，不存在时返回 -1
+func resolveCheckpoint(metadata *SiteCheckpointMetadata, ref string) int {
+	for i, cp := range metadata.Checkpoints {
+		if cp.ID == ref {
+			return i
+		}
+	}
+	for i, cp := range metadata.Checkpoints {
+		if slices.Contains(cp.Labels, ref) {
+			return i
+		}
+	}
+	return -1
+}
+
+// UpdateCheckpoint 修改检查点的备注、标签和固定状态（请求中省略的字段保持不变）
+func (m *CheckpointManager) UpdateCheckpoint(username, siteID, checkpointID string, req api.UpdateCheckpointRequest) (*Checkpoint, error) {
+	metadata, err := m.loadSiteMetadata(username, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("加载站点元数据失败: %w", err)
+	}
+
+	index := -1
+	for i, cp := range metadata.Checkpoints {
+		if cp.ID == checkpointID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, checkpointID)
+	}
+	cp := &metadata.Checkpoints[index]
+
+	if req.Labels != nil {
+		labels, err := normalizeLabels(*req.Labels)
+		if err != nil {
+			return nil, err
+		}
+		// 标签在站点内唯一，且不能与其他检查点的 ID 相同，保证按标签查找的结果唯一
+		for i, other := range metadata.Checkpoints {
+			if i == index {
+				continue
+			}
+			for _, label := range labels {
+				if label == other.ID {
+					return nil, fmt.Errorf("%w: %s 与检查点 ID 相同", ErrLabelInUse, label)
+				}
+				if slices.Contains(other.Labels, label) {
+					return nil, fmt.Errorf("%w: %s（%s）", ErrLabelInUse, label, other.ID)
+				}
+			}
+		}
+		cp.Labels = labels
+	}
+	if req.Note != nil {
+		cp.Note = *req.Note
+	}
+	if req.Pinned != nil {
+		cp.Pinned = *req.Pinned
+	}
+
+	metadata.UpdatedAt = time.Now()
+	if err := m.saveSiteMetadata(metadata); err != nil {
+		return nil, err
+	}
+	updated := *cp
+	return &updated, nil
+}
+
+// normalizeLabels 校验标签格式并去除重复，为空时返回 nil
+func normalizeLabels(labels []string) ([]string, error) {
+	if len(labels) > maxCheckpointLabels {
+		return nil, fmt.Errorf("%w: 标签数量超过 %d", ErrInvalidLabel, maxCheckpointLabels)
+	}
+	var out []string
+	for _, label := range labels {
+		if label == CurrentCheckpointRef {
+			return nil, fmt.Errorf("%w: %s 为保留名称", ErrInvalidLabel, label)
+		}
+		if !labelPattern.MatchString(label) {
+			return nil, fmt.Errorf("%w: %q（须以字母或数字开头，只能包含字母、数字和 . _ + -，最长 64 个字符）", ErrInvalidLabel, label)
+		}
+		if !slices.Contains(out, label) {
+			out = append(out, label)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,123 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ErrLimitExceeded 超出上传或解压限制，可通过 errors.Is 判断
+var ErrLimitExceeded = errors.New("超出部署限制")
+
+// ratioGraceBytes 解压总大小未超过该值时不检查压缩比（小文件的压缩比波动较大）
+const ratioGraceBytes = 1 << 20
+
+// Limits 部署上传与解压限制（0 表示不限制）
+type Limits struct {
+	MaxUploadBytes      int64 // 上传压缩包大小
+	MaxExtractedBytes   int64 // 解压后文件总大小
+	MaxFiles            int   // 压缩包内条目数量（含目录）
+	MaxPathDepth        int   // 条目路径深度
+	MaxCompressionRatio int   // 解压后总大小与压缩包大小之比
+}
+
+// extractGuard 在解压过程中累计并检查限制，超出时立即中止
+type extractGuard struct {
+	limits  Limits
+	read    int64 // 已读取的压缩包字节数
+	written int64 // 已解压的字节数
+	entries int
+}
+
+func newExtractGuard(limits Limits) *extractGuard {
+	return &extractGuard{limits: limits}
+}
+
+// source 包装压缩包数据流，读取时累计压缩包大小并检查上传上限
+func (g *extractGuard) source(r io.Reader) io.Reader {
+	return &sourceReader{guard: g, r: r}
+}
+
+// consume 累计已读取的压缩包字节数
+func (g *extractGuard) consume(n int64) error {
+	g.read += n
+	if limit := g.limits.MaxUploadBytes; limit > 0 && g.read > limit {
+		return fmt.Errorf("%w: 上传文件超过上限 %s", ErrLimitExceeded, formatBytes(limit))
+	}
+	return nil
+}
+
+// entry 登记一个条目，检查条目数量和路径深度
+func (g *extractGuard) entry(name string) error {
+	g.entries++
+	if limit := g.limits.MaxFiles; limit > 0 && g.entries > limit {
+		return fmt.Errorf("%w: 压缩包条目数量超过 %d", ErrLimitExceeded, limit)
+	}
+	if limit := g.limits.MaxPathDepth; limit > 0 {
+		if depth := pathDepth(name); depth > limit {
+			return fmt.Errorf("%w: 路径 %s 的深度 %d 超过 %d", ErrLimitExceeded, name, depth, limit)
+		}
+	}
+	return nil
+}
+
+// reader 包装条目内容，读取时累计解压总大小
+func (g *extractGuard) reader(r io.Reader, name string) io.Reader {
+	return &guardedReader{guard: g, r: r, name: name}
+}
+
+// add 累计解压字节数并检查总大小与压缩比
+func (g *extractGuard) add(n int, name string) error {
+	g.written += int64(n)
+	if limit := g.limits.MaxExtractedBytes; limit > 0 && g.written > limit {
+		return fmt.Errorf("%w: 解压后总大小超过 %s（在 %s 处中止）", ErrLimitExceeded, formatBytes(limit), name)
+	}
+	// 流式解压时 read 为已读取的部分，可能因缓冲略大于实际解压进度，比值只会偏小
+	if ratio := int64(g.limits.MaxCompressionRatio); ratio > 0 && g.written > ratioGraceBytes && g.written > g.read*ratio {
+		return fmt.Errorf("%w: 压缩比超过 %d:1（压缩包 %s，已解压 %s，在 %s 处中止）",
+			ErrLimitExceeded, ratio, formatBytes(g.read), formatBytes(g.written), name)
+	}
+	return nil
+}
+
+type guardedReader struct {
+	guard *extractGuard
+	r     io.Reader
+	name  string
+}
+
+func (r *guardedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if limitErr := r.guard.add(n, r.name); limitErr != nil {
+			return 0, limitErr
+		}
+	}
+	return n, err
+}
+
+type sourceReader struct {
+	guard *extractGuard
+	r     io.Reader
+}
+
+func (r *sourceReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if limitErr := r.guard.consume(int64(n)); limitErr != nil {
+			return 0, limitErr
+		}
+	}
+	return n, err
+}
+
+// pathDepth 返回条目路径的层级数（a/b/c.txt 为 3）
+func pathDepth(name string) int {
+	clean := strings.Trim(filepath.ToSlash(filepath.Clean(name)), "/")
+	if clean == "" || clean == "." {
+		return 0
+	}
+	return strings.Count(clean, "/") + 1
+}
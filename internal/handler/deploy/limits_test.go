@@ -0,0 +1,87 @@
+package deploy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// tarGz 生成包含给定文件（路径 -> 内容）的 tar.gz
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractLimits(t *testing.T) {
+	small := map[string]string{"index.html": "home", "a/b/c.txt": "abc"}
+	bomb := map[string]string{"zeros.bin": strings.Repeat("\x00", 4<<20)}
+	tests := []struct {
+		name    string
+		files   map[string]string
+		limits  Limits
+		wantErr string
+	}{
+		{name: "未超出限制", files: small, limits: Limits{MaxUploadBytes: 1 << 20, MaxExtractedBytes: 7, MaxFiles: 2, MaxPathDepth: 3}},
+		{name: "不限制", files: bomb},
+		{name: "上传大小", files: small, limits: Limits{MaxUploadBytes: 16}, wantErr: "上传文件超过上限"},
+		{name: "解压总大小", files: small, limits: Limits{MaxExtractedBytes: 6}, wantErr: "解压后总大小超过"},
+		{name: "条目数量", files: small, limits: Limits{MaxFiles: 1}, wantErr: "条目数量超过 1"},
+		{name: "路径深度", files: small, limits: Limits{MaxPathDepth: 2}, wantErr: "a/b/c.txt 的深度 3 超过 2"},
+		{name: "压缩比", files: bomb, limits: Limits{MaxCompressionRatio: 100}, wantErr: "压缩比超过 100:1"},
+		{name: "小文件不检查压缩比", files: map[string]string{"a": strings.Repeat("a", 64<<10)}, limits: Limits{MaxCompressionRatio: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := t.TempDir()
+			_, err := ExtractStream(bytes.NewReader(tarGz(t, tt.files)), "", dest, t.TempDir(), tt.limits)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrLimitExceeded) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want ErrLimitExceeded %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPathDepth(t *testing.T) {
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"", 0},
+		{".", 0},
+		{"index.html", 1},
+		{"a/b/c.txt", 3},
+		{"./a/b/", 2},
+		{"/a/b", 2},
+	}
+	for _, tt := range tests {
+		if got := pathDepth(tt.name); got != tt.want {
+			t.Errorf("pathDepth(%q) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
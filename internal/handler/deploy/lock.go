@@ -0,0 +1,68 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SiteLocker 站点修改锁：同一站点的部署、检查点切换和删除等操作互斥执行
+// 进程内使用互斥锁；跨进程（如直接操作站点目录的命令行工具）使用站点目录旁的锁文件
+type SiteLocker struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex // 站点根目录 -> 进程内互斥锁
+}
+
+// NewSiteLocker 创建站点修改锁
+func NewSiteLocker() *SiteLocker {
+	return &SiteLocker{
+		locks: make(map[string]*sync.Mutex),
+	}
+}
+
+// LockFilePath 返回站点的锁文件路径（<sitesDir>/<username>/.<id>.lock）
+// 外部工具修改站点目录或检查点前应对该文件加排他锁（flock）
+func LockFilePath(rootDir string) string {
+	return filepath.Join(filepath.Dir(rootDir), "."+filepath.Base(rootDir)+".lock")
+}
+
+// Lock 获取站点的修改锁，阻塞直到其他持有者释放；返回的 unlock 用于释放锁
+func (l *SiteLocker) Lock(rootDir string) (unlock func(), err error) {
+	rootDir = filepath.Clean(rootDir)
+	l.mu.Lock()
+	mu, ok := l.locks[rootDir]
+	if !ok {
+		mu = &sync.Mutex{}
+		l.locks[rootDir] = mu
+	}
+	l.mu.Unlock()
+
+	mu.Lock()
+	f, err := openLockFile(LockFilePath(rootDir))
+	if err != nil {
+		mu.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+		mu.Unlock()
+	}, nil
+}
+
+// openLockFile 打开（必要时创建）锁文件并加排他锁
+func openLockFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建锁文件目录失败: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开锁文件失败: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("锁定站点失败: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package deploy
+
+import "os"
+
+// lockFile 非 Unix 平台不支持 flock，仅依赖进程内互斥
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile 释放文件锁
+func unlockFile(f *os.File) error {
+	return nil
+}
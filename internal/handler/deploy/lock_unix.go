@@ -0,0 +1,23 @@
+//go:build unix
+
+package deploy
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile 对文件加排他锁，阻塞直到成功
+func lockFile(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+// unlockFile 释放文件锁
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,174 @@
+package deploy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Manifest 部署清单：站点内相对路径（以 / 分隔）-> 文件内容的 SHA-256（小写十六进制）
+type Manifest map[string]string
+
+// ErrHashMismatch 上传内容与声明的哈希不符
+var ErrHashMismatch = errors.New("上传内容与哈希不符")
+
+// ValidateManifest 校验并规范化部署清单：路径须为站点内的相对路径，哈希须为 SHA-256，
+// 文件数量和路径深度受部署限制约束
+func ValidateManifest(files map[string]string, limits Limits) (Manifest, error) {
+	if len(files) == 0 {
+		return nil, errors.New("清单为空")
+	}
+	if limits.MaxFiles > 0 && len(files) > limits.MaxFiles {
+		return nil, fmt.Errorf("%w: 清单文件数量超过 %d", ErrLimitExceeded, limits.MaxFiles)
+	}
+
+	manifest := make(Manifest, len(files))
+	dirs := make(map[string]bool)
+	for name, hash := range files {
+		clean := path.Clean(strings.TrimPrefix(name, "./"))
+		if name == "" || strings.Contains(name, "\\") || path.IsAbs(clean) || clean == "." || isPathTraversal(clean) {
+			return nil, fmt.Errorf("非法路径: %s", name)
+		}
+		if limits.MaxPathDepth > 0 && pathDepth(clean) > limits.MaxPathDepth {
+			return nil, fmt.Errorf("%w: 路径 %s 的深度 %d 超过 %d", ErrLimitExceeded, name, pathDepth(clean), limits.MaxPathDepth)
+		}
+		hash = strings.ToLower(hash)
+		if !ValidHash(hash) {
+			return nil, fmt.Errorf("路径 %s 的哈希不是有效的 SHA-256: %s", name, hash)
+		}
+		if _, dup := manifest[clean]; dup {
+			return nil, fmt.Errorf("路径重复: %s", clean)
+		}
+		manifest[clean] = hash
+		for dir := path.Dir(clean); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	for name := range manifest {
+		if dirs[name] {
+			return nil, fmt.Errorf("路径 %s 既是文件又是目录", name)
+		}
+	}
+	return manifest, nil
+}
+
+// ValidHash 判断是否为小写十六进制的 SHA-256
+func ValidHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil && strings.ToLower(hash) == hash
+}
+
+// ByHash 按内容分组：哈希 -> 使用该内容的路径（已排序）
+func (m Manifest) ByHash() map[string][]string {
+	groups := make(map[string][]string)
+	for name, hash := range m {
+		groups[hash] = append(groups[hash], name)
+	}
+	for _, names := range groups {
+		sort.Strings(names)
+	}
+	return groups
+}
+
+// HashFile 计算文件内容的 SHA-256
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// PlaceFile 将 src 链接（或复制）到 dest 下的各个相对路径（src 应为暂存目录中的文件）
+func PlaceFile(src, dest string, names []string) error {
+	for _, name := range names {
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		if !isWithinRoot(dest, target) {
+			return fmt.Errorf("非法路径: %s", name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := linkOrCopy(src, target); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ReceiveBlob 将 r 写入 path，内容的 SHA-256 与 hash 不符时删除文件并返回 ErrHashMismatch
+func ReceiveBlob(r io.Reader, path, hash string) (int64, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".part-*")
+	if err != nil {
+		return 0, err
+	}
+	tmp := f.Name()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), r)
+	if err == nil {
+		// CreateTemp 以 0600 创建文件，部署后的站点文件使用常规权限
+		err = f.Chmod(0644)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && hex.EncodeToString(h.Sum(nil)) != hash {
+		err = ErrHashMismatch
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return n, err
+	}
+	return n, os.Rename(tmp, path)
+}
+
+// linkOrCopy 优先创建硬链接，失败时复制
+// 仅用于暂存目录内部或来自内容存储之外的暂存文件：站点目录中的文件可能被原地修改，必须使用 copyFile
+func linkOrCopy(src, dst string) error {
+	os.Remove(dst)
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	return copyFile(src, dst)
+}
+
+// copyFile 复制 src 的内容和权限到 dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	os.Remove(dst)
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,165 @@
+package deploy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sha(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestValidateManifest(t *testing.T) {
+	h := sha("x")
+	tests := []struct {
+		name    string
+		files   map[string]string
+		limits  Limits
+		want    Manifest
+		wantErr string
+	}{
+		{name: "规范化路径和哈希", files: map[string]string{"./a/b.html": strings.ToUpper(h)}, want: Manifest{"a/b.html": h}},
+		{name: "空清单", files: map[string]string{}, wantErr: "清单为空"},
+		{name: "路径穿越", files: map[string]string{"../etc/passwd": h}, wantErr: "非法路径"},
+		{name: "绝对路径", files: map[string]string{"/etc/passwd": h}, wantErr: "非法路径"},
+		{name: "反斜杠", files: map[string]string{`a\b`: h}, wantErr: "非法路径"},
+		{name: "无效哈希", files: map[string]string{"a": "abc"}, wantErr: "SHA-256"},
+		{name: "规范化后重复", files: map[string]string{"a": h, "./a": h}, wantErr: "路径重复"},
+		{name: "既是文件又是目录", files: map[string]string{"a": h, "a/b": h}, wantErr: "既是文件又是目录"},
+		{name: "文件数量超限", files: map[string]string{"a": h, "b": h}, limits: Limits{MaxFiles: 1}, wantErr: ErrLimitExceeded.Error()},
+		{name: "路径深度超限", files: map[string]string{"a/b/c": h}, limits: Limits{MaxPathDepth: 2}, wantErr: ErrLimitExceeded.Error()},
+		{name: "深度恰好等于限制", files: map[string]string{"a/b": h}, limits: Limits{MaxPathDepth: 2}, want: Manifest{"a/b": h}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateManifest(tt.files, tt.limits)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("manifest = %v, want %v", got, tt.want)
+			}
+			for name, hash := range tt.want {
+				if got[name] != hash {
+					t.Errorf("manifest[%s] = %q, want %q", name, got[name], hash)
+				}
+			}
+		})
+	}
+}
+
+func TestReceiveBlob(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		hash    string
+		wantErr error
+	}{
+		{name: "内容与哈希一致", content: "hello", hash: sha("hello")},
+		{name: "内容与哈希不符", content: "hello", hash: sha("world"), wantErr: ErrHashMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "blob")
+			n, err := ReceiveBlob(strings.NewReader(tt.content), path, tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Error("哈希不符时不应留下文件")
+				}
+			} else if data, err := os.ReadFile(path); err != nil || string(data) != tt.content || n != int64(len(tt.content)) {
+				t.Errorf("写入 %q (%d), %v", data, n, err)
+			}
+			if parts, _ := filepath.Glob(filepath.Join(dir, "*.part-*")); len(parts) > 0 {
+				t.Errorf("残留临时文件 %v", parts)
+			}
+		})
+	}
+}
+
+func TestReuseLiveFilesVerifiesContent(t *testing.T) {
+	m := NewCheckpointManager(t.TempDir())
+	rootDir := filepath.Join(t.TempDir(), "blog")
+	os.MkdirAll(rootDir, 0755)
+	path := filepath.Join(rootDir, "a.txt")
+	os.WriteFile(path, []byte("aaa"), 0644)
+	if _, err := m.CreateManualCheckpoint("alice", "blog", rootDir, ""); err != nil {
+		t.Fatal(err)
+	}
+	// 修改内容但保持大小和修改时间不变：复制时校验哈希，不能复用
+	info, _ := os.Stat(path)
+	os.WriteFile(path, []byte("AAA"), 0644)
+	os.Chtimes(path, info.ModTime(), info.ModTime())
+
+	dest := t.TempDir()
+	needed := map[string][]string{sha("aaa"): {"a.txt"}}
+	if n, err := m.ReuseLiveFiles("alice", "blog", rootDir, dest, needed); err != nil || n != 0 {
+		t.Fatalf("ReuseLiveFiles = %d, %v, want 0", n, err)
+	}
+	if len(needed) != 1 {
+		t.Errorf("needed = %v, 内容不符的文件应留给内容存储", needed)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("内容不符的文件不应留在暂存目录: %v", err)
+	}
+}
+
+func TestReuseLiveFilesSkipsChangedFiles(t *testing.T) {
+	m := NewCheckpointManager(t.TempDir())
+	rootDir := filepath.Join(t.TempDir(), "blog")
+	os.MkdirAll(rootDir, 0755)
+	os.WriteFile(filepath.Join(rootDir, "a.txt"), []byte("aaa"), 0644)
+	os.WriteFile(filepath.Join(rootDir, "b.txt"), []byte("bbb"), 0644)
+	if _, err := m.CreateManualCheckpoint("alice", "blog", rootDir, ""); err != nil {
+		t.Fatal(err)
+	}
+	// 检查点之后被直接修改的文件（大小不变，修改时间不同）不能按文件树复用
+	os.WriteFile(filepath.Join(rootDir, "b.txt"), []byte("BBB"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(rootDir, "b.txt"), later, later)
+
+	dest := t.TempDir()
+	needed := map[string][]string{sha("aaa"): {"x/a.txt"}, sha("bbb"): {"b.txt"}}
+	n, err := m.ReuseLiveFiles("alice", "blog", rootDir, dest, needed)
+	if err != nil || n != 1 {
+		t.Fatalf("ReuseLiveFiles = %d, %v, want 1", n, err)
+	}
+	if _, ok := needed[sha("bbb")]; !ok || len(needed) != 1 {
+		t.Fatalf("needed = %v, want 只剩 b.txt 的内容", needed)
+	}
+	// 站点文件可能被原地修改，复用时必须复制而不是与站点共享 inode
+	live, _ := os.Stat(filepath.Join(rootDir, "a.txt"))
+	reused, err := os.Stat(filepath.Join(dest, "x", "a.txt"))
+	if err != nil || os.SameFile(live, reused) {
+		t.Errorf("复用的站点文件不应与站点目录共享 inode: %v", err)
+	}
+	os.WriteFile(filepath.Join(rootDir, "a.txt"), []byte("AAA"), 0644)
+	if data, _ := os.ReadFile(filepath.Join(dest, "x", "a.txt")); string(data) != "aaa" {
+		t.Errorf("原地修改站点文件影响了暂存目录: %q", data)
+	}
+
+	// 剩余内容从内容存储中复制
+	metadata, _ := m.ListCheckpoints("alice", "blog")
+	if n, err := m.ReuseCheckpointFiles("alice", "blog", metadata.Current, dest, needed); err != nil || n != 1 {
+		t.Fatalf("ReuseCheckpointFiles = %d, %v, want 1", n, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "b.txt")); string(data) != "bbb" {
+		t.Errorf("b.txt = %q, want bbb", data)
+	}
+}
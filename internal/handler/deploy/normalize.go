@@ -1,8 +1,9 @@
 package deploy
 
 import (
+	"errors"
 	"fmt"
-	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,8 +11,8 @@ import (
 )
 
 // NormalizeDirectory 检测并整理目录结构
-// 如果目录中只有一个顶层文件夹且没有顶层文件，将该文件夹的内容提升到上层
-// 返回整理后的目录路径（可能是原目录或新创建的临时目录）
+// 如果目录中只有一个顶层文件夹且没有顶层文件，直接返回该文件夹（不移动、不复制文件）
+// 返回整理后的目录路径（原目录或其中的嵌套目录），调用方清理 extractDir 即可
 func NormalizeDirectory(extractDir string) (string, error) {
 	entries, err := os.ReadDir(extractDir)
 	if err != nil {
@@ -29,69 +30,39 @@ func NormalizeDirectory(extractDir string) (string, error) {
 		visibleEntries = append(visibleEntries, entry)
 	}
 
-	// 如果没有可见内容，返回原目录
-	if len(visibleEntries) == 0 {
-		return extractDir, nil
-	}
-
-	// 如果只有一个条目且是文件夹，需要展平
+	// 如果只有一个条目且是文件夹，以该文件夹作为站点根目录
 	if len(visibleEntries) == 1 && visibleEntries[0].IsDir() {
-		nestedDir := filepath.Join(extractDir, visibleEntries[0].Name())
-		
-		// 创建临时目录用于存放展平后的内容
-		normalizedDir, err := os.MkdirTemp("", "deploy-normalized-*")
-		if err != nil {
-			return "", fmt.Errorf("创建临时目录失败: %w", err)
-		}
-
-		// 移动嵌套目录的内容到新的临时目录
-		if err := moveDirectoryContents(nestedDir, normalizedDir); err != nil {
-			os.RemoveAll(normalizedDir)
-			return "", fmt.Errorf("展平目录失败: %w", err)
-		}
-
-		return normalizedDir, nil
+		return filepath.Join(extractDir, visibleEntries[0].Name()), nil
 	}
 
-	// 多个顶层条目或包含顶层文件，无需展平
+	// 没有可见内容、多个顶层条目或包含顶层文件，无需展平
 	return extractDir, nil
 }
 
-// moveDirectoryContents 将 src 目录中的所有内容移动到 dst 目录
-func moveDirectoryContents(src, dst string) error {
-	entries, err := os.ReadDir(src)
+// NewStagingDir 在站点根目录的同级位置创建部署暂存目录
+// 暂存目录与站点目录位于同一文件系统，最终替换只需一次重命名
+func NewStagingDir(rootDir string) (string, error) {
+	parent := filepath.Dir(rootDir)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", fmt.Errorf("创建父目录失败: %w", err)
+	}
+	dir, err := os.MkdirTemp(parent, "."+filepath.Base(rootDir)+".staging-*")
 	if err != nil {
-		return err
+		return "", fmt.Errorf("创建暂存目录失败: %w", err)
 	}
+	return dir, nil
+}
 
-	for _, entry := range entries {
-		srcPath := filepath.Join(src, entry.Name())
-		dstPath := filepath.Join(dst, entry.Name())
-
-		// 尝试重命名（同一文件系统内是原子操作）
-		if err := os.Rename(srcPath, dstPath); err != nil {
-			// 重命名失败（可能跨文件系统），使用复制+删除
-			if entry.IsDir() {
-				if err := copyDirectory(srcPath, dstPath); err != nil {
-					return fmt.Errorf("复制目录 %s 失败: %w", entry.Name(), err)
-				}
-			} else {
-				if err := copyFile(srcPath, dstPath); err != nil {
-					return fmt.Errorf("复制文件 %s 失败: %w", entry.Name(), err)
-				}
-			}
-			// 复制成功后删除源文件
-			if err := os.RemoveAll(srcPath); err != nil {
-				return fmt.Errorf("删除源文件 %s 失败: %w", entry.Name(), err)
-			}
-		}
-	}
+// ErrRollbackFailed 替换目录失败后未能恢复原目录，站点目录可能缺失，原内容保留在备份目录中
+var ErrRollbackFailed = errors.New("替换目录失败且未能恢复原目录")
 
-	return nil
-}
+// errExchangeUnsupported 当前平台或文件系统不支持原子交换两个路径
+var errExchangeUnsupported = errors.New("不支持原子交换")
 
-// AtomicReplaceDirectory 原子性地替换目标目录
-// 支持 Windows 兼容性和重试机制
+// AtomicReplaceDirectory 原子性地替换目标目录（目标和新目录均可为符号链接），成功后 newDir 不再存在
+// Linux 上使用 renameat2(RENAME_EXCHANGE) 一步交换，目标路径始终存在；
+// 其他平台或文件系统不支持时退化为先将目标移到备份位置、再重命名新目录（两步之间目标短暂缺失），
+// 替换失败时恢复原目录，恢复也失败时返回 ErrRollbackFailed 并保留备份
 func AtomicReplaceDirectory(oldDir, newDir string) error {
 	// 确保目标目录的父目录存在
 	parentDir := filepath.Dir(oldDir)
@@ -99,29 +70,47 @@ func AtomicReplaceDirectory(oldDir, newDir string) error {
 		return fmt.Errorf("创建父目录失败: %w", err)
 	}
 
+	if _, err := os.Lstat(oldDir); err == nil {
+		err := exchangePaths(newDir, oldDir)
+		if err == nil {
+			// 交换后 newDir 中是原内容
+			if err := os.RemoveAll(newDir); err != nil {
+				slog.Warn("删除替换前的目录失败", "dir", newDir, "error", err)
+			}
+			return nil
+		}
+		if !errors.Is(err, errExchangeUnsupported) {
+			return fmt.Errorf("替换目录失败: %w", err)
+		}
+	}
+
 	// 生成备份目录名
 	backupDir := oldDir + ".backup." + fmt.Sprintf("%d", time.Now().Unix())
 
 	// 1. 如果旧目录存在，先重命名为备份
-	if _, err := os.Stat(oldDir); err == nil {
+	hasBackup := false
+	if _, err := os.Lstat(oldDir); err == nil {
 		if err := renameWithRetry(oldDir, backupDir, 5); err != nil {
 			return fmt.Errorf("备份旧目录失败: %w", err)
 		}
-		defer func() {
-			// 清理备份目录
-			os.RemoveAll(backupDir)
-		}()
+		hasBackup = true
 	}
 
 	// 2. 将新目录重命名为目标目录
 	if err := renameWithRetry(newDir, oldDir, 5); err != nil {
-		// 失败时尝试恢复备份
-		if _, statErr := os.Stat(backupDir); statErr == nil {
-			renameWithRetry(backupDir, oldDir, 3)
+		// 失败时尝试恢复备份，恢复失败时保留备份供人工处理
+		if hasBackup {
+			if rbErr := renameWithRetry(backupDir, oldDir, 3); rbErr != nil {
+				return fmt.Errorf("%w（原内容保留在 %s）: %v; 恢复: %v", ErrRollbackFailed, backupDir, err, rbErr)
+			}
 		}
 		return fmt.Errorf("替换目录失败: %w", err)
 	}
 
+	// 3. 清理备份目录
+	if hasBackup {
+		os.RemoveAll(backupDir)
+	}
 	return nil
 }
 
@@ -142,63 +131,3 @@ func renameWithRetry(src, dst string, maxRetries int) error {
 	}
 	return fmt.Errorf("重命名失败（已重试 %d 次）: %w", maxRetries, lastErr)
 }
-
-// copyFile 复制单个文件
-func copyFile(src, dst string) error {
-	srcFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer srcFile.Close()
-
-	srcInfo, err := srcFile.Stat()
-	if err != nil {
-		return err
-	}
-
-	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, srcInfo.Mode())
-	if err != nil {
-		return err
-	}
-	defer dstFile.Close()
-
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
-		return err
-	}
-
-	return dstFile.Close()
-}
-
-// copyDirectory 递归复制目录
-func copyDirectory(src, dst string) error {
-	srcInfo, err := os.Stat(src)
-	if err != nil {
-		return err
-	}
-
-	if err := os.MkdirAll(dst, srcInfo.Mode()); err != nil {
-		return err
-	}
-
-	entries, err := os.ReadDir(src)
-	if err != nil {
-		return err
-	}
-
-	for _, entry := range entries {
-		srcPath := filepath.Join(src, entry.Name())
-		dstPath := filepath.Join(dst, entry.Name())
-
-		if entry.IsDir() {
-			if err := copyDirectory(srcPath, dstPath); err != nil {
-				return err
-			}
-		} else {
-			if err := copyFile(srcPath, dstPath); err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
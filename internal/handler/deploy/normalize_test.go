@@ -0,0 +1,67 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicReplaceDirectory(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldKind string // "", "dir" 或 "symlink"
+		newKind string // "dir" 或 "symlink"
+	}{
+		{name: "目标不存在", oldKind: "", newKind: "dir"},
+		{name: "目录替换目录", oldKind: "dir", newKind: "dir"},
+		{name: "目录转换为符号链接", oldKind: "dir", newKind: "symlink"},
+		{name: "符号链接替换为目录", oldKind: "symlink", newKind: "dir"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			oldDir := filepath.Join(base, "site")
+			newDir := filepath.Join(base, "incoming")
+			makeEntry(t, base, oldDir, tt.oldKind, "old")
+			makeEntry(t, base, newDir, tt.newKind, "new")
+
+			if err := AtomicReplaceDirectory(oldDir, newDir); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(filepath.Join(oldDir, "index.html"))
+			if err != nil || string(data) != "new" {
+				t.Fatalf("替换后内容 = %q, %v, want new", data, err)
+			}
+			if _, err := os.Lstat(newDir); !os.IsNotExist(err) {
+				t.Errorf("替换后 newDir 仍存在: %v", err)
+			}
+			// 不应残留备份目录
+			if backups, _ := filepath.Glob(oldDir + ".backup.*"); len(backups) > 0 {
+				t.Errorf("残留备份目录 %v", backups)
+			}
+		})
+	}
+}
+
+// makeEntry 在 path 处创建含 index.html 的目录，或指向同级目录的符号链接
+func makeEntry(t *testing.T, base, path, kind, content string) {
+	t.Helper()
+	dir := path
+	switch kind {
+	case "":
+		return
+	case "symlink":
+		dir = filepath.Join(base, "target-"+content)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if kind == "symlink" {
+		if err := os.Symlink(filepath.Base(dir), path); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package deploy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 发布目录：每个上线过的检查点解包后保存在 <站点目录同级>/.<站点 ID>.releases/<检查点 ID>，
+// 站点目录本身是指向当前发布的符号链接。切换到仍保留的发布只需原子地替换符号链接。
+
+// SetKeepReleases 设置保留的发布目录数量（含当前发布），不大于 0 表示不使用发布目录、直接替换站点目录
+func (m *CheckpointManager) SetKeepReleases(keep int) {
+	m.keepReleases = keep
+}
+
+// ReleasesDir 返回站点的发布目录
+func ReleasesDir(rootDir string) string {
+	return filepath.Join(filepath.Dir(rootDir), "."+filepath.Base(rootDir)+".releases")
+}
+
+// releaseDir 返回检查点的发布目录
+func releaseDir(rootDir, checkpointID string) string {
+	return filepath.Join(ReleasesDir(rootDir), checkpointID)
+}
+
+// releaseExists 判断检查点的发布目录是否存在
+func releaseExists(rootDir, checkpointID string) bool {
+	info, err := os.Stat(releaseDir(rootDir, checkpointID))
+	return err == nil && info.IsDir()
+}
+
+// ActiveRelease 返回站点目录当前指向的发布（检查点 ID），站点目录不是发布链接时返回空
+func ActiveRelease(rootDir string) string {
+	target, err := os.Readlink(rootDir)
+	if err != nil || filepath.Dir(target) != filepath.Base(ReleasesDir(rootDir)) {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+// resolveDir 返回目录的实际路径（站点目录为发布链接时返回链接目标），解析失败时原样返回
+func resolveDir(dir string) string {
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		return real
+	}
+	return dir
+}
+
+// Publish 让 dir 中的内容作为检查点 checkpointID 上线，成功后 dir 不再存在
+// 启用发布目录时将 dir 移入发布目录并切换站点目录的符号链接，否则用 dir 直接替换站点目录
+func (m *CheckpointManager) Publish(rootDir, checkpointID, dir string) error {
+	if m.keepReleases <= 0 {
+		if err := AtomicReplaceDirectory(rootDir, dir); err != nil {
+			return err
+		}
+		// 停用发布目录后清理此前保留的发布
+		os.RemoveAll(ReleasesDir(rootDir))
+		return nil
+	}
+
+	if ActiveRelease(rootDir) == checkpointID {
+		return fmt.Errorf("检查点 %s 的发布正在使用中", checkpointID)
+	}
+	release := releaseDir(rootDir, checkpointID)
+	if err := os.MkdirAll(ReleasesDir(rootDir), 0755); err != nil {
+		return fmt.Errorf("创建发布目录失败: %w", err)
+	}
+	if err := os.RemoveAll(release); err != nil {
+		return fmt.Errorf("清理发布目录失败: %w", err)
+	}
+	if err := renameWithRetry(dir, release, 5); err != nil {
+		return fmt.Errorf("移入发布目录失败: %w", err)
+	}
+	if err := switchRelease(rootDir, checkpointID); err != nil {
+		os.RemoveAll(release)
+		return err
+	}
+	return nil
+}
+
+// switchRelease 将站点目录指向检查点的发布：在同级位置创建新符号链接，再重命名覆盖站点目录
+// 站点目录仍是普通目录时（启用发布目录之前部署的站点）先移到备份位置，替换成功后删除
+func switchRelease(rootDir, checkpointID string) error {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Errorf("生成发布链接名称失败: %w", err)
+	}
+	link := filepath.Join(filepath.Dir(rootDir), "."+filepath.Base(rootDir)+".link-"+hex.EncodeToString(buf))
+	target := filepath.Join(filepath.Base(ReleasesDir(rootDir)), checkpointID)
+	if err := os.Symlink(target, link); err != nil {
+		return fmt.Errorf("创建发布链接失败: %w", err)
+	}
+
+	info, err := os.Lstat(rootDir)
+	if err != nil || info.Mode()&os.ModeSymlink != 0 {
+		// 符号链接（或不存在）直接被新链接覆盖，访问者始终能看到完整的旧版本或新版本
+		if err := renameWithRetry(link, rootDir, 5); err != nil {
+			os.Remove(link)
+			return fmt.Errorf("切换发布失败: %w", err)
+		}
+	} else if err := AtomicReplaceDirectory(rootDir, link); err != nil {
+		os.Remove(link)
+		return err
+	}
+
+	// 修改时间记录发布最近一次上线的时间，清理时优先保留最近使用的发布
+	// 失败时不影响本次切换，但清理时可能误判发布的新旧
+	now := time.Now()
+	if err := os.Chtimes(releaseDir(rootDir, checkpointID), now, now); err != nil {
+		slog.Warn("记录发布上线时间失败", "release", releaseDir(rootDir, checkpointID), "error", err)
+	}
+	return nil
+}
+
+// adoptRelease 站点目录的内容刚被保存为检查点 checkpointID 时，将当前发布改名为该检查点的发布，
+// 保证每个发布的内容与同名检查点一致（站点文件可能在发布上线后被直接修改）
+// 先复制出新发布并切换符号链接，再删除旧名称，切换前后站点链接始终指向完整的发布
+// 当前发布即站点目录，其中的文件可能被原地修改，因此复制而不是硬链接，避免修改同时影响新发布
+func adoptRelease(rootDir, checkpointID string) error {
+	active := ActiveRelease(rootDir)
+	if active == "" || active == checkpointID {
+		return nil
+	}
+	from, to := releaseDir(rootDir, active), releaseDir(rootDir, checkpointID)
+	tmp := filepath.Join(ReleasesDir(rootDir), "."+checkpointID+".adopt")
+	os.RemoveAll(tmp)
+	if err := copyTree(from, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("复制发布失败: %w", err)
+	}
+	if err := os.Rename(tmp, to); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("重命名发布失败: %w", err)
+	}
+	if err := switchRelease(rootDir, checkpointID); err != nil {
+		os.RemoveAll(to)
+		return err
+	}
+	// 旧名称下的内容与检查点 active 不一致，不再保留
+	if err := os.RemoveAll(from); err != nil {
+		slog.Warn("删除发布目录失败", "release", from, "error", err)
+	}
+	return nil
+}
+
+// copyTree 将 src 目录树复制到 dst，保留文件的修改时间（检查点文件树按修改时间判断文件是否变化），符号链接原样复制
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, 0755)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			if err := copyFile(p, target); err != nil {
+				return err
+			}
+			return os.Chtimes(target, info.ModTime(), info.ModTime())
+		}
+		return nil
+	})
+}
+
+// PruneReleases 删除多余的发布：当前发布之外按最近上线时间保留，总数不超过 keep_releases，
+// 检查点已被删除的发布一并删除
+func (m *CheckpointManager) PruneReleases(username, siteID, rootDir string) {
+	entries, err := os.ReadDir(ReleasesDir(rootDir))
+	if err != nil {
+		return
+	}
+	metadata, err := m.loadSiteMetadata(username, siteID)
+	if err != nil {
+		return
+	}
+
+	active := ActiveRelease(rootDir)
+	type release struct {
+		id   string
+		used time.Time
+	}
+	var releases []release
+	for _, entry := range entries {
+		id := entry.Name()
+		if strings.HasPrefix(id, ".") && strings.HasSuffix(id, ".adopt") {
+			// 中断的 adoptRelease 留下的临时目录
+			m.removeRelease(username, siteID, rootDir, id)
+			continue
+		}
+		if !entry.IsDir() || strings.HasPrefix(id, ".") || id == active {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !checkpointExists(metadata, id) {
+			m.removeRelease(username, siteID, rootDir, id)
+			continue
+		}
+		releases = append(releases, release{id: id, used: info.ModTime()})
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i].used.After(releases[j].used) })
+
+	keep := m.keepReleases
+	if active != "" {
+		keep--
+	}
+	for i, r := range releases {
+		if i >= keep {
+			m.removeRelease(username, siteID, rootDir, r.id)
+		}
+	}
+}
+
+// removeRelease 删除一个发布目录（失败时只记录日志，下次清理时重试）
+func (m *CheckpointManager) removeRelease(username, siteID, rootDir, checkpointID string) {
+	if err := os.RemoveAll(releaseDir(rootDir, checkpointID)); err != nil {
+		slog.Warn("删除发布目录失败", "tenant", username, "site", siteID, "release", checkpointID, "error", err)
+	}
+}
+
+// releasesSize 返回当前发布以外的发布目录总大小
+func releasesSize(rootDir string) int64 {
+	entries, err := os.ReadDir(ReleasesDir(rootDir))
+	if err != nil {
+		return 0
+	}
+	active := ActiveRelease(rootDir)
+	var size int64
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == active {
+			continue
+		}
+		n, _, _ := calculateDirSize(filepath.Join(ReleasesDir(rootDir), entry.Name()))
+		size += n
+	}
+	return size
+}
@@ -0,0 +1,76 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAdoptReleaseKeepsSiteLinked(t *testing.T) {
+	rootDir := filepath.Join(t.TempDir(), "blog")
+	old := releaseDir(rootDir, "cp-old")
+	if err := os.MkdirAll(filepath.Join(old, "assets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(old, "index.html"), []byte("edited"), 0644)
+	os.WriteFile(filepath.Join(old, "assets", "app.js"), []byte("js"), 0644)
+	os.Symlink("index.html", filepath.Join(old, "home.html"))
+	if err := switchRelease(rootDir, "cp-old"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := adoptRelease(rootDir, "cp-new"); err != nil {
+		t.Fatal(err)
+	}
+	if got := ActiveRelease(rootDir); got != "cp-new" {
+		t.Fatalf("ActiveRelease = %q, want cp-new", got)
+	}
+	for name, want := range map[string]string{"index.html": "edited", "assets/app.js": "js", "home.html": "edited"} {
+		data, err := os.ReadFile(filepath.Join(rootDir, name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v, want %q", name, data, err, want)
+		}
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("旧名称的发布仍存在: %v", err)
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(ReleasesDir(rootDir), ".*")); len(tmp) > 0 {
+		t.Errorf("残留临时目录 %v", tmp)
+	}
+}
+
+func TestAdoptReleaseWithoutReleases(t *testing.T) {
+	rootDir := filepath.Join(t.TempDir(), "blog")
+	if err := os.MkdirAll(rootDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 站点目录不是发布链接时无需处理
+	if err := adoptRelease(rootDir, "cp-new"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(rootDir); err != nil || !info.IsDir() {
+		t.Fatalf("站点目录应保持为普通目录: %v", err)
+	}
+}
+
+func TestCopyTreeDoesNotShareFiles(t *testing.T) {
+	src, dst := t.TempDir(), filepath.Join(t.TempDir(), "copy")
+	path := filepath.Join(src, "index.html")
+	os.WriteFile(path, []byte("v1"), 0644)
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	os.Chtimes(path, mtime, mtime)
+
+	if err := copyTree(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dst, "index.html"))
+	if err != nil || !info.ModTime().Equal(mtime) {
+		t.Fatalf("副本应保留修改时间: %v, %v", info, err)
+	}
+	// 原地修改当前发布中的文件不能影响副本
+	os.WriteFile(path, []byte("v2"), 0644)
+	if data, _ := os.ReadFile(filepath.Join(dst, "index.html")); string(data) != "v1" {
+		t.Errorf("副本内容 = %q, want v1", data)
+	}
+}
@@ -0,0 +1,93 @@
+package deploy
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"pages/pkg/api"
+)
+
+// Retention 检查点保留策略（与 pkg/api 共用）
+type Retention = api.CheckpointRetention
+
+// RetentionResolver 返回站点生效的保留策略
+type RetentionResolver func(username, siteID string) Retention
+
+// SetRetention 设置保留策略，设置后每次创建检查点都会按站点的策略删除过期检查点
+func (m *CheckpointManager) SetRetention(resolver RetentionResolver) {
+	m.retention = resolver
+}
+
+// PlanRetention 按保留策略计算需要删除的检查点（从新到旧）
+// 固定的检查点和 current 指向的检查点始终保留；策略未启用时不删除任何检查点
+func PlanRetention(checkpoints []Checkpoint, current string, policy Retention, now time.Time) []Checkpoint {
+	if !policy.Enabled() {
+		return nil
+	}
+
+	sorted := make([]Checkpoint, len(checkpoints))
+	copy(sorted, checkpoints)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreatedAt.After(sorted[j].CreatedAt) })
+
+	keep := make([]bool, len(sorted))
+	for i, cp := range sorted {
+		keep[i] = cp.Pinned || cp.ID == current || i < policy.KeepLast
+	}
+
+	// keep_days 之内的检查点全部保留，之前的按日、按周各保留最新的一个
+	cutoff := now.AddDate(0, 0, -policy.KeepDays)
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	for i, cp := range sorted {
+		created := cp.CreatedAt.In(now.Location())
+		if policy.KeepDays > 0 && created.After(cutoff) {
+			keep[i] = true
+			continue
+		}
+		if day := created.Format("2006-01-02"); !days[day] && len(days) < policy.KeepDaily {
+			days[day] = true
+			keep[i] = true
+		}
+		year, week := created.ISOWeek()
+		if key := fmt.Sprintf("%d-%02d", year, week); !weeks[key] && len(weeks) < policy.KeepWeekly {
+			weeks[key] = true
+			keep[i] = true
+		}
+	}
+
+	var prune []Checkpoint
+	for i, cp := range sorted {
+		if !keep[i] {
+			prune = append(prune, cp)
+		}
+	}
+	return prune
+}
+
+// PlanPrune 返回按保留策略将被删除的检查点（不删除）
+func (m *CheckpointManager) PlanPrune(username, siteID string, policy Retention) ([]Checkpoint, error) {
+	metadata, err := m.loadSiteMetadata(username, siteID)
+	if err != nil {
+		return nil, fmt.Errorf("加载站点元数据失败: %w", err)
+	}
+	return PlanRetention(metadata.Checkpoints, metadata.Current, policy, time.Now()), nil
+}
+
+// Prune 按保留策略删除过期检查点，返回已删除的检查点
+// 调用方需保证期间没有对同一站点的其他修改（持有站点锁）
+func (m *CheckpointManager) Prune(username, siteID string, policy Retention) ([]Checkpoint, error) {
+	plan, err := m.PlanPrune(username, siteID, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []Checkpoint
+	for _, cp := range plan {
+		if err := m.DeleteCheckpoint(username, siteID, cp.ID); err != nil {
+			return pruned, fmt.Errorf("删除检查点 %s 失败: %w", cp.ID, err)
+		}
+		pruned = append(pruned, cp)
+	}
+	return pruned, nil
+}
@@ -0,0 +1,48 @@
+package deploy
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPlanRetention(t *testing.T) {
+	// 2026-06-15 为周一，06-14 及之前属于上一个 ISO 周
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	checkpoints := []Checkpoint{
+		{ID: "c8", CreatedAt: now.AddDate(0, 0, -40), Pinned: true},
+		{ID: "c7", CreatedAt: now.AddDate(0, 0, -20)},
+		{ID: "c6", CreatedAt: now.AddDate(0, 0, -10)},
+		{ID: "c5", CreatedAt: now.AddDate(0, 0, -3)},
+		{ID: "c4", CreatedAt: now.AddDate(0, 0, -1).Add(-time.Hour)},
+		{ID: "c3", CreatedAt: now.AddDate(0, 0, -1)},
+		{ID: "c2", CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "c1", CreatedAt: now.Add(-time.Hour)},
+	}
+	tests := []struct {
+		name    string
+		policy  Retention
+		current string
+		want    []string
+	}{
+		{name: "未启用", policy: Retention{}},
+		{name: "keep_all 覆盖其他规则", policy: Retention{KeepLast: 1, KeepAll: true}},
+		{name: "保留最近 N 个", policy: Retention{KeepLast: 2}, want: []string{"c3", "c4", "c5", "c6", "c7"}},
+		{name: "current 始终保留", policy: Retention{KeepLast: 1}, current: "c6", want: []string{"c2", "c3", "c4", "c5", "c7"}},
+		{name: "保留 N 天内", policy: Retention{KeepDays: 2}, want: []string{"c5", "c6", "c7"}},
+		{name: "每天保留最新一个", policy: Retention{KeepDaily: 2}, want: []string{"c2", "c4", "c5", "c6", "c7"}},
+		{name: "每周保留最新一个", policy: Retention{KeepWeekly: 2}, want: []string{"c2", "c4", "c5", "c6", "c7"}},
+		{name: "N 天之前按周保留", policy: Retention{KeepDays: 2, KeepWeekly: 2}, want: []string{"c7"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, cp := range PlanRetention(checkpoints, tt.current, tt.policy, now) {
+				got = append(got, cp.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("prune = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package deploy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 检查点存储格式
+const (
+	CheckpointFormatTarGz   = "tar.gz"  // 早期格式：整个站点打包为 <id>.tar.gz
+	CheckpointFormatContent = "content" // 内容寻址：<id>.tree.json 记录文件树，文件内容保存在共享的内容存储中
+)
+
+// checkpointTree 内容寻址检查点的文件树
+type checkpointTree struct {
+	Entries []treeEntry `json:"entries"`
+}
+
+// treeEntry 文件树条目
+type treeEntry struct {
+	Path string      `json:"path"`           // 以 / 分隔的相对路径
+	Hash string      `json:"hash,omitempty"` // 文件内容的 SHA-256，目录为空
+	Size int64       `json:"size,omitempty"`
+	Mode os.FileMode `json:"mode"`
+	// 生成文件树时站点文件的修改时间，早期记录的文件树为零值
+	ModTime time.Time `json:"mtime,omitzero"`
+}
+
+// unchanged 报告站点文件的大小和修改时间是否与条目一致，一致时视为内容未变，无需重新计算哈希
+func (e treeEntry) unchanged(info os.FileInfo) bool {
+	return !e.ModTime.IsZero() && info.Mode().IsRegular() &&
+		info.Size() == e.Size && info.ModTime().Equal(e.ModTime)
+}
+
+// hashes 返回文件树引用的所有内容哈希（去重）
+func (t *checkpointTree) hashes() []string {
+	seen := make(map[string]bool)
+	var hashes []string
+	for _, e := range t.Entries {
+		if e.Hash != "" && !seen[e.Hash] {
+			seen[e.Hash] = true
+			hashes = append(hashes, e.Hash)
+		}
+	}
+	return hashes
+}
+
+// logicalSize 返回文件树中所有文件的总大小
+func (t *checkpointTree) logicalSize() int64 {
+	var size int64
+	for _, e := range t.Entries {
+		size += e.Size
+	}
+	return size
+}
+
+// manifest 返回文件树的清单（路径 -> 哈希）
+func (t *checkpointTree) manifest() Manifest {
+	manifest := make(Manifest)
+	for _, e := range t.Entries {
+		if e.Hash != "" {
+			manifest[e.Path] = e.Hash
+		}
+	}
+	return manifest
+}
+
+// digest 返回文件树的哈希，用于生成检查点 ID
+func (t *checkpointTree) digest() string {
+	h := sha256.New()
+	for _, e := range t.Entries {
+		fmt.Fprintf(h, "%s\x00%s\x00%o\n", e.Path, e.Hash, e.Mode)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// getCheckpointTreePath 获取内容寻址检查点的文件树路径
+func (m *CheckpointManager) getCheckpointTreePath(username, siteID, checkpointID string) string {
+	return filepath.Join(m.getCheckpointsSubDir(username, siteID), checkpointID+".tree.json")
+}
+
+// storeTree 将目录中的文件存入内容存储并返回文件树
+// 返回的哈希列表处于固定状态，调用方随后必须调用 blobs.commit 或 blobs.abort
+func (m *CheckpointManager) storeTree(sourceDir string) (*checkpointTree, []string, error) {
+	var pinned []string
+	tree, err := walkTree(sourceDir, func(path string, _ os.FileInfo) (string, int64, error) {
+		hash, size, err := m.blobs.put(path)
+		if err == nil {
+			pinned = append(pinned, hash)
+		}
+		return hash, size, err
+	})
+	if err != nil {
+		m.blobs.abort(pinned)
+		return nil, nil, err
+	}
+	return tree, pinned, nil
+}
+
+// scanTree 计算目录的文件树，不写入内容存储
+func scanTree(sourceDir string) (*checkpointTree, error) {
+	return walkTree(sourceDir, func(path string, info os.FileInfo) (string, int64, error) {
+		hash, err := HashFile(path)
+		return hash, info.Size(), err
+	})
+}
+
+// scanLive 计算站点目录的文件树，大小和修改时间与 known 中同一路径的条目一致的文件沿用其哈希
+// known 为 nil（早期格式的检查点）时计算所有文件的哈希
+func scanLive(rootDir string, known *checkpointTree) (*checkpointTree, error) {
+	if known == nil {
+		return scanTree(rootDir)
+	}
+	entries := make(map[string]treeEntry, len(known.Entries))
+	for _, e := range known.Entries {
+		entries[e.Path] = e
+	}
+	root := resolveDir(rootDir)
+	return walkTree(rootDir, func(path string, info os.FileInfo) (string, int64, error) {
+		if rel, err := filepath.Rel(root, path); err == nil {
+			if e, ok := entries[filepath.ToSlash(rel)]; ok && e.Hash != "" && e.unchanged(info) {
+				return e.Hash, e.Size, nil
+			}
+		}
+		hash, err := HashFile(path)
+		return hash, info.Size(), err
+	})
+}
+
+// walkTree 遍历目录生成文件树，普通文件的哈希和大小由 file 给出
+func walkTree(sourceDir string, file func(path string, info os.FileInfo) (string, int64, error)) (*checkpointTree, error) {
+	tree := &checkpointTree{}
+	sourceDir = resolveDir(sourceDir)
+	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+
+		entry := treeEntry{Path: filepath.ToSlash(relPath), Mode: info.Mode() & (os.ModeDir | os.ModePerm)}
+		switch {
+		case info.IsDir():
+		case info.Mode().IsRegular():
+			hash, size, err := file(path, info)
+			if err != nil {
+				return err
+			}
+			entry.Hash, entry.Size, entry.ModTime = hash, size, info.ModTime()
+		default:
+			// 忽略符号链接等特殊文件（部署时已拒绝）
+			return nil
+		}
+		tree.Entries = append(tree.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tree.Entries, func(i, j int) bool { return tree.Entries[i].Path < tree.Entries[j].Path })
+	return tree, nil
+}
+
+// loadTree 读取内容寻址检查点的文件树，早期格式的检查点返回 os.ErrNotExist
+func (m *CheckpointManager) loadTree(username, siteID, checkpointID string) (*checkpointTree, error) {
+	return readTree(m.getCheckpointTreePath(username, siteID, checkpointID))
+}
+
+func readTree(path string) (*checkpointTree, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tree checkpointTree
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("解析检查点文件树失败: %w", err)
+	}
+	return &tree, nil
+}
+
+// saveTree 保存检查点文件树
+func (m *CheckpointManager) saveTree(username, siteID, checkpointID string, tree *checkpointTree) error {
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.getCheckpointTreePath(username, siteID, checkpointID), data, 0644)
+}
+
+// restoreTree 将文件树还原到 dest
+func (m *CheckpointManager) restoreTree(tree *checkpointTree, dest string) error {
+	for _, e := range tree.Entries {
+		target := filepath.Join(dest, filepath.FromSlash(e.Path))
+		if !isWithinRoot(dest, target) {
+			return fmt.Errorf("非法路径: %s", e.Path)
+		}
+		if e.Mode.IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := m.blobs.copyTo(e.Hash, target, e.Mode); err != nil {
+			return err
+		}
+		// 还原记录的修改时间，之后可按大小和修改时间判断站点文件是否变化
+		if !e.ModTime.IsZero() {
+			if err := os.Chtimes(target, e.ModTime, e.ModTime); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// siteBlobSizes 返回站点所有内容寻址检查点引用的内容（哈希 -> 大小）
+func (m *CheckpointManager) siteBlobSizes(username, siteID string) (map[string]int64, error) {
+	return dirBlobSizes(m.getCheckpointsSubDir(username, siteID))
+}
+
+// dirBlobSizes 返回检查点文件目录中所有文件树引用的内容（哈希 -> 大小）
+func dirBlobSizes(checkpointsDir string) (map[string]int64, error) {
+	sizes := make(map[string]int64)
+	err := forEachTree(checkpointsDir, func(_ string, tree *checkpointTree) error {
+		for _, e := range tree.Entries {
+			if e.Hash != "" {
+				sizes[e.Hash] = e.Size
+			}
+		}
+		return nil
+	})
+	return sizes, err
+}
+
+// siteBlobRefs 返回站点内容寻址检查点引用的内容大小（哈希 -> 大小）和引用该内容的检查点数量（哈希 -> 数量）
+func (m *CheckpointManager) siteBlobRefs(username, siteID string) (map[string]int64, map[string]int, error) {
+	sizes := make(map[string]int64)
+	refs := make(map[string]int)
+	err := forEachTree(m.getCheckpointsSubDir(username, siteID), func(_ string, tree *checkpointTree) error {
+		seen := make(map[string]bool)
+		for _, e := range tree.Entries {
+			if e.Hash == "" || seen[e.Hash] {
+				continue
+			}
+			seen[e.Hash] = true
+			sizes[e.Hash] = e.Size
+			refs[e.Hash]++
+		}
+		return nil
+	})
+	return sizes, refs, err
+}
+
+// forEachTree 遍历检查点文件目录中的所有文件树
+func forEachTree(checkpointsDir string, fn func(checkpointID string, tree *checkpointTree) error) error {
+	entries, err := os.ReadDir(checkpointsDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		checkpointID, ok := strings.CutSuffix(entry.Name(), ".tree.json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		tree, err := readTree(filepath.Join(checkpointsDir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err := fn(checkpointID, tree); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReleaseDir 释放站点检查点目录（SiteDir 返回的目录，可能已被移到其他位置）中的检查点对内容存储的引用
+// 在彻底删除该目录之前调用，引用归零的内容随之删除
+func (m *CheckpointManager) ReleaseDir(dir string) error {
+	return forEachTree(filepath.Join(dir, "checkpoints"), func(_ string, tree *checkpointTree) error {
+		return m.blobs.release(tree.hashes())
+	})
+}
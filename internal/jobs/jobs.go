@@ -0,0 +1,486 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"pages/pkg/api"
+)
+
+// Job 异步任务（与 pkg/client 共用）
+type Job = api.Job
+
+// ErrNotFound 任务不存在
+var ErrNotFound = errors.New(api.MsgJobNotFound)
+
+// ParamWorkDir 任务工作目录参数，任务结束或被中断后由管理器删除
+const ParamWorkDir = "work_dir"
+
+// Reporter 任务执行过程中报告当前阶段和进度（百分比，-1 表示未知）
+type Reporter func(phase string, progress int)
+
+// Runner 执行任务，params 为提交任务时登记的参数
+type Runner func(job Job, params map[string]string, report Reporter) (*api.DeployResult, error)
+
+// record 持久化的任务，Ready 表示已可执行（上传已完成），Params 为执行参数
+type record struct {
+	Job
+	Ready  bool              `json:"ready"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Manager 异步任务管理器
+// 任务由有限数量的 worker 执行，同一站点的任务按提交顺序依次执行；
+// 任务状态记录在 <dataDir>/jobs.json 中，重启后未完成的任务继续执行，执行中断的任务标记为失败
+type Manager struct {
+	path    string
+	workers int
+	history int // 保留的已结束任务数量
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	records  map[string]*record
+	queue    []*record             // 等待执行的任务，按提交顺序
+	running  map[string]bool       // 正在执行任务的站点 (username/siteID)
+	subs     map[string][]chan Job // 任务 ID -> 订阅者
+	runner   Runner
+	stopping bool
+	wg       sync.WaitGroup
+}
+
+// defaultHistory 未指定保留数量时保留的已结束任务数
+const defaultHistory = 200
+
+// NewManager 创建任务管理器，history 不大于 0 时使用默认值（刚结束的任务必须保留，供提交方查询结果）
+func NewManager(dataDir string, workers, history int) *Manager {
+	if workers <= 0 {
+		workers = 1
+	}
+	if history <= 0 {
+		history = defaultHistory
+	}
+	m := &Manager{
+		path:    filepath.Join(dataDir, "jobs.json"),
+		workers: workers,
+		history: history,
+		records: make(map[string]*record),
+		running: make(map[string]bool),
+		subs:    make(map[string][]chan Job),
+	}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// Load 从任务日志恢复任务
+// 上传未完成或执行被中断的任务标记为失败，已就绪但未开始的任务重新排队
+func (m *Manager) Load() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := os.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取任务日志失败: %w", err)
+	}
+
+	var list []*record
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("解析任务日志失败: %w", err)
+	}
+
+	now := time.Now()
+	for _, r := range list {
+		m.records[r.ID] = r
+		switch {
+		case r.Finished():
+		case r.Status == api.JobQueued && r.Ready:
+			m.queue = append(m.queue, r)
+		default:
+			r.Status = api.JobFailed
+			r.Error = "服务重启，任务已中断"
+			r.FinishedAt = &now
+			removeWorkDir(r)
+			slog.Warn("任务因服务重启中断", "job", r.ID, "tenant", r.Username, "site", r.SiteID, "phase", r.Phase)
+		}
+	}
+	sort.Slice(m.queue, func(i, j int) bool { return m.queue[i].CreatedAt.Before(m.queue[j].CreatedAt) })
+	return m.saveInternal()
+}
+
+// Start 启动 worker，runner 执行就绪的任务
+func (m *Manager) Start(runner Runner) {
+	m.mu.Lock()
+	m.runner = runner
+	m.mu.Unlock()
+
+	for i := 0; i < m.workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+}
+
+// Stop 停止领取新任务并等待执行中的任务结束，未开始的任务保留在日志中
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	m.stopping = true
+	m.cond.Broadcast()
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+// Create 创建任务，任务处于接收上传阶段，Submit 后才会被执行
+// params 为执行参数，其中 ParamWorkDir 指定的目录在任务结束后删除
+func (m *Manager) Create(typ, username, siteID, createdBy string, params map[string]string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := &record{
+		Job: Job{
+			ID:        newJobID(),
+			Type:      typ,
+			Username:  username,
+			SiteID:    siteID,
+			Status:    api.JobQueued,
+			Phase:     api.PhaseUpload,
+			Progress:  -1,
+			CreatedBy: createdBy,
+			CreatedAt: time.Now(),
+		},
+		Params: params,
+	}
+	m.records[r.ID] = r
+	if err := m.saveInternal(); err != nil {
+		delete(m.records, r.ID)
+		return Job{}, err
+	}
+	return r.Job, nil
+}
+
+// Submit 将上传完成的任务加入执行队列
+func (m *Manager) Submit(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.records[id]
+	if !ok {
+		return Job{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	// 上传期间任务可能已被取消（如租户被删除）
+	if r.Finished() {
+		return Job{}, fmt.Errorf("任务已结束: %s", r.Error)
+	}
+	r.Ready = true
+	r.Progress = 100 // 上传已完成，等待执行
+	if err := m.saveInternal(); err != nil {
+		r.Ready = false
+		return Job{}, err
+	}
+	m.queue = append(m.queue, r)
+	m.cond.Broadcast()
+	m.notifyInternal(r)
+	return r.Job, nil
+}
+
+// Report 更新任务阶段和进度；仅阶段变化时写入日志
+func (m *Manager) Report(id, phase string, progress int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.records[id]
+	if !ok || r.Finished() {
+		return
+	}
+	if r.Phase == phase && r.Progress == progress {
+		return
+	}
+	phaseChanged := r.Phase != phase
+	r.Phase = phase
+	r.Progress = progress
+	if phaseChanged {
+		if err := m.saveInternal(); err != nil {
+			slog.Error("写入任务日志失败", "job", id, "error", err)
+		}
+	}
+	m.notifyInternal(r)
+}
+
+// Fail 将任务标记为失败（用于执行前的失败，如上传中断）
+func (m *Manager) Fail(id string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r, ok := m.records[id]; ok && !r.Finished() {
+		m.finishInternal(r, nil, err)
+	}
+}
+
+// CancelTenant 取消租户所有未开始的任务（含仍在接收上传的任务，记为失败），并等待其执行中的任务结束
+// 返回取消的任务数量
+func (m *Manager) CancelTenant(username string, reason error) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	queue := m.queue[:0]
+	for _, r := range m.queue {
+		if r.Username != username {
+			queue = append(queue, r)
+		}
+	}
+	m.queue = queue
+
+	cancelled := 0
+	for _, r := range m.records {
+		if r.Username == username && r.Status == api.JobQueued {
+			m.finishInternal(r, nil, reason)
+			cancelled++
+		}
+	}
+
+	prefix := username + "/"
+	for {
+		running := false
+		for key := range m.running {
+			if strings.HasPrefix(key, prefix) {
+				running = true
+				break
+			}
+		}
+		if !running {
+			return cancelled
+		}
+		m.cond.Wait()
+	}
+}
+
+// Get 获取任务
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.records[id]
+	if !ok {
+		return Job{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return r.Job, nil
+}
+
+// List 列出租户的任务（username 为空时列出全部），按创建时间倒序
+func (m *Manager) List(username string) []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]Job, 0)
+	for _, r := range m.records {
+		if username == "" || r.Username == username {
+			list = append(list, r.Job)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// Subscribe 订阅任务状态变化，返回的通道在任务结束后关闭
+// 通道满时丢弃中间状态，订阅者在通道关闭后应通过 Get 获取最终状态
+func (m *Manager) Subscribe(id string) (<-chan Job, func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ch := make(chan Job, 16)
+	r, ok := m.records[id]
+	if !ok || r.Finished() {
+		close(ch)
+		return ch, func() {}
+	}
+	m.subs[id] = append(m.subs[id], ch)
+
+	cancel := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		subs := m.subs[id]
+		for i, sub := range subs {
+			if sub == ch {
+				m.subs[id] = append(subs[:i], subs[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+		if len(m.subs[id]) == 0 {
+			delete(m.subs, id)
+		}
+	}
+	return ch, cancel
+}
+
+// worker 循环领取并执行任务
+func (m *Manager) worker() {
+	defer m.wg.Done()
+	for {
+		r, job := m.next()
+		if r == nil {
+			return
+		}
+
+		result, err := m.run(job, r.Params)
+
+		m.mu.Lock()
+		m.finishInternal(r, result, err)
+		delete(m.running, siteKey(job))
+		m.cond.Broadcast()
+		m.mu.Unlock()
+	}
+}
+
+// run 执行任务，runner 的 panic 视为任务失败
+func (m *Manager) run(job Job, params map[string]string) (result *api.DeployResult, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("任务执行异常: %v", p)
+		}
+	}()
+	return m.runner(job, params, func(phase string, progress int) {
+		m.Report(job.ID, phase, progress)
+	})
+}
+
+// next 领取下一个可执行的任务（所在站点没有执行中的任务）及其快照，停止时返回 nil
+func (m *Manager) next() (*record, Job) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for {
+		if m.stopping {
+			return nil, Job{}
+		}
+		for i, r := range m.queue {
+			key := siteKey(r.Job)
+			if m.running[key] {
+				continue
+			}
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			m.running[key] = true
+
+			now := time.Now()
+			r.Status = api.JobRunning
+			r.StartedAt = &now
+			if err := m.saveInternal(); err != nil {
+				slog.Error("写入任务日志失败", "job", r.ID, "error", err)
+			}
+			m.notifyInternal(r)
+			return r, r.Job
+		}
+		m.cond.Wait()
+	}
+}
+
+// finishInternal 记录任务结果并通知订阅者（不加锁）
+func (m *Manager) finishInternal(r *record, result *api.DeployResult, err error) {
+	now := time.Now()
+	r.FinishedAt = &now
+	removeWorkDir(r)
+	r.Params = nil
+	if err != nil {
+		r.Status = api.JobFailed
+		r.Error = err.Error()
+		slog.Warn("任务失败", "job", r.ID, "tenant", r.Username, "site", r.SiteID, "phase", r.Phase, "error", err)
+	} else {
+		r.Status = api.JobSucceeded
+		r.Result = result
+		r.Progress = 100
+	}
+
+	m.pruneInternal()
+	if err := m.saveInternal(); err != nil {
+		slog.Error("写入任务日志失败", "job", r.ID, "error", err)
+	}
+
+	m.notifyInternal(r)
+	for _, ch := range m.subs[r.ID] {
+		close(ch)
+	}
+	delete(m.subs, r.ID)
+}
+
+// notifyInternal 向订阅者推送任务状态（不加锁，不阻塞）
+func (m *Manager) notifyInternal(r *record) {
+	for _, ch := range m.subs[r.ID] {
+		select {
+		case ch <- r.Job:
+		default:
+		}
+	}
+}
+
+// pruneInternal 只保留最近的 history 个已结束任务（不加锁）
+func (m *Manager) pruneInternal() {
+	var finished []*record
+	for _, r := range m.records {
+		if r.Finished() {
+			finished = append(finished, r)
+		}
+	}
+	if len(finished) <= m.history {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].CreatedAt.After(finished[j].CreatedAt) })
+	for _, r := range finished[m.history:] {
+		delete(m.records, r.ID)
+	}
+}
+
+// saveInternal 内部保存方法（不加锁）
+func (m *Manager) saveInternal() error {
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	list := make([]*record, 0, len(m.records))
+	for _, r := range m.records {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化任务日志失败: %w", err)
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入任务日志失败: %w", err)
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return fmt.Errorf("写入任务日志失败: %w", err)
+	}
+	return nil
+}
+
+// removeWorkDir 删除任务工作目录
+func removeWorkDir(r *record) {
+	if dir := r.Params[ParamWorkDir]; dir != "" {
+		if err := os.RemoveAll(dir); err != nil {
+			slog.Error("删除任务工作目录失败", "job", r.ID, "dir", dir, "error", err)
+		}
+	}
+}
+
+func siteKey(job Job) string {
+	return job.Username + "/" + job.SiteID
+}
+
+// newJobID 生成任务 ID：时间 + 随机后缀
+func newJobID() string {
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return fmt.Sprintf("%s-%s", time.Now().Format("20060102-150405"), hex.EncodeToString(buf))
+}
@@ -0,0 +1,265 @@
+package jobs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pages/pkg/api"
+)
+
+const testJobType = "deploy"
+
+// submit 创建并提交任务
+func submit(t *testing.T, m *Manager, username, siteID string, params map[string]string) Job {
+	t.Helper()
+	job, err := m.Create(testJobType, username, siteID, "admin", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Submit(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+// wait 等待任务结束并返回最终状态
+func wait(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+	ch, cancel := m.Subscribe(id)
+	defer cancel()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				job, err := m.Get(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return job
+			}
+		case <-timeout:
+			t.Fatalf("任务 %s 未在超时前结束", id)
+		}
+	}
+}
+
+// blockingRunner 每个任务开始时发送任务 ID，并等待 release 中对应的通道关闭后结束
+func blockingRunner(started chan<- string, release map[string]chan struct{}) Runner {
+	return func(job Job, _ map[string]string, _ Reporter) (*api.DeployResult, error) {
+		started <- job.ID
+		<-release[job.ID]
+		return &api.DeployResult{}, nil
+	}
+}
+
+func TestManagerSerializesSite(t *testing.T) {
+	m := NewManager(t.TempDir(), 2, 10)
+	a1 := submit(t, m, "alice", "blog", nil)
+	a2 := submit(t, m, "alice", "blog", nil)
+	b1 := submit(t, m, "bob", "docs", nil)
+
+	started := make(chan string, 3)
+	release := map[string]chan struct{}{a1.ID: make(chan struct{}), a2.ID: make(chan struct{}), b1.ID: make(chan struct{})}
+	m.Start(blockingRunner(started, release))
+	defer m.Stop()
+
+	// 不同站点的任务并行执行，同一站点的第二个任务等待第一个结束
+	first := map[string]bool{<-started: true, <-started: true}
+	if !first[a1.ID] || !first[b1.ID] {
+		t.Fatalf("最先执行的任务 = %v, want %s 和 %s", first, a1.ID, b1.ID)
+	}
+	select {
+	case id := <-started:
+		t.Fatalf("同一站点的任务 %s 在前一个任务结束前开始执行", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if job, _ := m.Get(a2.ID); job.Status != api.JobQueued {
+		t.Errorf("a2 status = %s, want queued", job.Status)
+	}
+
+	close(release[a1.ID])
+	if id := <-started; id != a2.ID {
+		t.Fatalf("开始执行的任务 = %s, want %s", id, a2.ID)
+	}
+	close(release[a2.ID])
+	close(release[b1.ID])
+	for _, id := range []string{a1.ID, a2.ID, b1.ID} {
+		if job := wait(t, m, id); job.Status != api.JobSucceeded {
+			t.Errorf("%s status = %s, want succeeded", id, job.Status)
+		}
+	}
+}
+
+func TestManagerRunnerErrors(t *testing.T) {
+	m := NewManager(t.TempDir(), 1, 10)
+	m.Start(func(job Job, params map[string]string, _ Reporter) (*api.DeployResult, error) {
+		switch params["mode"] {
+		case "error":
+			return nil, errors.New("解压失败")
+		case "panic":
+			panic("boom")
+		}
+		return &api.DeployResult{}, nil
+	})
+	defer m.Stop()
+
+	tests := []struct {
+		mode       string
+		wantStatus string
+	}{
+		{"ok", api.JobSucceeded},
+		{"error", api.JobFailed},
+		{"panic", api.JobFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			job := wait(t, m, submit(t, m, "alice", "blog", map[string]string{"mode": tt.mode}).ID)
+			if job.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s (%s)", job.Status, tt.wantStatus, job.Error)
+			}
+		})
+	}
+}
+
+func TestCancelTenant(t *testing.T) {
+	m := NewManager(t.TempDir(), 1, 10)
+	workDir := filepath.Join(t.TempDir(), "upload")
+	os.MkdirAll(workDir, 0755)
+	uploading, err := m.Create(testJobType, "alice", "blog", "admin", map[string]string{ParamWorkDir: workDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued := submit(t, m, "alice", "docs", nil)
+	other := submit(t, m, "bob", "blog", nil)
+
+	if n := m.CancelTenant("alice", errors.New("租户已删除")); n != 2 {
+		t.Fatalf("CancelTenant = %d, want 2", n)
+	}
+	for _, id := range []string{uploading.ID, queued.ID} {
+		if job, _ := m.Get(id); job.Status != api.JobFailed || job.Error != "租户已删除" {
+			t.Errorf("%s = %s %q, want failed", id, job.Status, job.Error)
+		}
+	}
+	if _, err := os.Stat(workDir); !os.IsNotExist(err) {
+		t.Errorf("取消的任务的工作目录未删除: %v", err)
+	}
+	// 上传结束后提交已取消的任务失败
+	if _, err := m.Submit(uploading.ID); err == nil {
+		t.Error("Submit 已取消的任务应失败")
+	}
+
+	// 其他租户的任务照常执行
+	m.Start(func(Job, map[string]string, Reporter) (*api.DeployResult, error) { return &api.DeployResult{}, nil })
+	defer m.Stop()
+	if job := wait(t, m, other.ID); job.Status != api.JobSucceeded {
+		t.Errorf("其他租户的任务 status = %s", job.Status)
+	}
+}
+
+func TestCancelTenantWaitsForRunningJob(t *testing.T) {
+	m := NewManager(t.TempDir(), 1, 10)
+	job := submit(t, m, "alice", "blog", nil)
+	started := make(chan string, 1)
+	release := map[string]chan struct{}{job.ID: make(chan struct{})}
+	m.Start(blockingRunner(started, release))
+	defer m.Stop()
+	<-started
+
+	done := make(chan int)
+	go func() { done <- m.CancelTenant("alice", errors.New("租户已删除")) }()
+	select {
+	case <-done:
+		t.Fatal("CancelTenant 未等待执行中的任务结束")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release[job.ID])
+	if n := <-done; n != 0 {
+		t.Errorf("CancelTenant = %d, want 0（执行中的任务不取消）", n)
+	}
+	if got, _ := m.Get(job.ID); got.Status != api.JobSucceeded {
+		t.Errorf("status = %s, want succeeded", got.Status)
+	}
+}
+
+func TestLoadRecoversJournal(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(dir, 1, 10)
+	workDir := filepath.Join(t.TempDir(), "upload")
+	os.MkdirAll(workDir, 0755)
+	uploading, err := m.Create(testJobType, "alice", "blog", "admin", map[string]string{ParamWorkDir: workDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ready := submit(t, m, "alice", "docs", map[string]string{"archive": "site.zip"})
+	finished, err := m.Create(testJobType, "bob", "blog", "admin", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Fail(finished.ID, errors.New("上传中断"))
+
+	// 模拟重启：新的管理器从任务日志恢复
+	reloaded := NewManager(dir, 1, 10)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := reloaded.Get(uploading.ID); job.Status != api.JobFailed {
+		t.Errorf("上传未完成的任务 status = %s, want failed", job.Status)
+	}
+	if _, err := os.Stat(workDir); !os.IsNotExist(err) {
+		t.Errorf("中断任务的工作目录未删除: %v", err)
+	}
+	if job, _ := reloaded.Get(finished.ID); job.Status != api.JobFailed || job.Error != "上传中断" {
+		t.Errorf("已结束的任务 = %s %q", job.Status, job.Error)
+	}
+
+	// 已就绪的任务重新排队，执行参数随任务日志保留
+	var gotParams map[string]string
+	reloaded.Start(func(job Job, params map[string]string, _ Reporter) (*api.DeployResult, error) {
+		gotParams = params
+		return &api.DeployResult{}, nil
+	})
+	defer reloaded.Stop()
+	if job := wait(t, reloaded, ready.ID); job.Status != api.JobSucceeded {
+		t.Fatalf("重新排队的任务 status = %s", job.Status)
+	}
+	if gotParams["archive"] != "site.zip" {
+		t.Errorf("params = %v", gotParams)
+	}
+}
+
+func TestPruneHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		history int
+		jobs    int
+		want    int
+	}{
+		{"超出保留数量时删除最早的任务", 2, 4, 2},
+		{"0 使用默认值，不会立即删除刚结束的任务", 0, 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(t.TempDir(), 1, tt.history)
+			var last Job
+			for i := 0; i < tt.jobs; i++ {
+				var err error
+				last, err = m.Create(testJobType, "alice", "blog", "admin", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				m.Fail(last.ID, errors.New("上传中断"))
+				time.Sleep(time.Millisecond) // 保证创建时间不同
+			}
+			if got := len(m.List("")); got != tt.want {
+				t.Errorf("保留 %d 个任务, want %d", got, tt.want)
+			}
+			if _, err := m.Get(last.ID); err != nil {
+				t.Errorf("最近结束的任务被删除: %v", err)
+			}
+		})
+	}
+}
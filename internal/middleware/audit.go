@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/audit"
+)
+
+const (
+	auditBeforeKey = "audit.before"
+	auditAfterKey  = "audit.after"
+
+	// auditBodyLimit 为解析结果消息而缓存的响应体上限
+	auditBodyLimit = 4096
+)
+
+// AuditChange 由处理器调用，为当前请求的审计日志附加变更前/后摘要
+func AuditChange(c echo.Context, before, after map[string]any) {
+	if before != nil {
+		c.Set(auditBeforeKey, before)
+	}
+	if after != nil {
+		c.Set(auditAfterKey, after)
+	}
+}
+
+// Audit 记录所有变更类请求（非 GET/HEAD/OPTIONS）的审计日志
+// 需注册在认证中间件之后，以便记录操作者
+func Audit(logger *audit.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			switch req.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return next(c)
+			}
+
+			rec := &bodyRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = rec
+
+			err := next(c)
+			if err != nil {
+				// 交由错误处理器写入响应，以便记录最终状态码
+				c.Error(err)
+			}
+
+			entry := &audit.Entry{
+				Time:         time.Now(),
+				IP:           c.RealIP(),
+				RemoteAddr:   req.RemoteAddr,
+				Method:       req.Method,
+				Route:        c.Path(),
+				Path:         req.URL.Path,
+				Tenant:       c.Param("username"),
+				SiteID:       c.Param("id"),
+				CheckpointID: c.Param("checkpoint_id"),
+				Status:       c.Response().Status,
+			}
+			if p := CurrentPrincipal(c); p != nil {
+				entry.Actor = p.Username
+				entry.AuthMethod = p.Method
+				entry.TokenID = p.TokenID
+			}
+			entry.Before, _ = c.Get(auditBeforeKey).(map[string]any)
+			entry.After, _ = c.Get(auditAfterKey).(map[string]any)
+
+			var result struct {
+				Success bool   `json:"success"`
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(rec.body.Bytes(), &result) == nil {
+				entry.Success = result.Success
+				entry.Message = result.Message
+			}
+			if entry.Status >= 400 {
+				entry.Success = false
+			}
+
+			if err := logger.Record(entry); err != nil {
+				slog.Error("写入审计日志失败", "route", entry.Route, "error", err)
+			}
+			return err
+		}
+	}
+}
+
+// bodyRecorder 缓存响应体前 auditBodyLimit 字节
+type bodyRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	if remain := auditBodyLimit - r.body.Len(); remain > 0 {
+		if len(b) < remain {
+			remain = len(b)
+		}
+		r.body.Write(b[:remain])
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush 透传 http.Flusher
+func (r *bodyRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap 供 http.ResponseController 使用
+func (r *bodyRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/audit"
+)
+
+func TestAuditRecordsResult(t *testing.T) {
+	logger := audit.NewLogger(t.TempDir(), 0, 0)
+	defer logger.Close()
+
+	e := echo.New()
+	e.Use(Audit(logger))
+	e.POST("/sites/:username/:id", func(c echo.Context) error {
+		return c.JSON(http.StatusBadRequest, map[string]any{"success": false, "message": "域名无效"})
+	})
+	e.GET("/sites/:username/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/sites/alice/blog", nil))
+	}
+
+	entries, err := logger.Query(audit.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("应只记录变更类请求，got %d 条", len(entries))
+	}
+	got := entries[0]
+	if got.Method != http.MethodPost || got.Route != "/sites/:username/:id" || got.Tenant != "alice" || got.SiteID != "blog" {
+		t.Errorf("entry = %+v", got)
+	}
+	if got.Status != http.StatusBadRequest || got.Success || got.Message != "域名无效" {
+		t.Errorf("status/success/message = %d/%v/%q", got.Status, got.Success, got.Message)
+	}
+}
+
+func TestAuditBodyLimit(t *testing.T) {
+	logger := audit.NewLogger(t.TempDir(), 0, 0)
+	defer logger.Close()
+
+	// 响应体超出缓存上限：客户端收到完整内容，审计日志无法解析结果消息
+	payload := strings.Repeat("x", 2*auditBodyLimit)
+	e := echo.New()
+	e.Use(Audit(logger))
+	e.POST("/", func(c echo.Context) error {
+		return c.JSON(http.StatusOK, map[string]any{"success": true, "message": payload})
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if !strings.Contains(rec.Body.String(), payload) {
+		t.Fatalf("响应体被截断，长度 %d", rec.Body.Len())
+	}
+
+	entries, err := logger.Query(audit.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d 条审计日志, want 1", len(entries))
+	}
+	if entries[0].Message != "" || entries[0].Status != http.StatusOK {
+		t.Errorf("status = %d, message 长度 = %d", entries[0].Status, len(entries[0].Message))
+	}
+}
+
+func TestBodyRecorderLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []int
+		want   int
+	}{
+		{"未超出上限", []int{100, 200}, 300},
+		{"恰好达到上限", []int{auditBodyLimit}, auditBodyLimit},
+		{"单次写入超出上限", []int{auditBodyLimit + 1}, auditBodyLimit},
+		{"多次写入累计超出上限", []int{auditBodyLimit - 10, 100, 100}, auditBodyLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			rec := &bodyRecorder{ResponseWriter: w}
+			total := 0
+			for _, n := range tt.writes {
+				written, err := rec.Write([]byte(strings.Repeat("x", n)))
+				if err != nil || written != n {
+					t.Fatalf("Write(%d) = %d, %v", n, written, err)
+				}
+				total += n
+			}
+			if rec.body.Len() != tt.want {
+				t.Errorf("缓存 %d 字节, want %d", rec.body.Len(), tt.want)
+			}
+			if w.Body.Len() != total {
+				t.Errorf("透传 %d 字节, want %d", w.Body.Len(), total)
+			}
+		})
+	}
+}
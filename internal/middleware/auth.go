@@ -0,0 +1,258 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/auth"
+	"pages/pkg/api"
+)
+
+// principalKey 请求上下文中保存认证主体的键
+const principalKey = "principal"
+
+// CurrentPrincipal 获取当前请求的认证主体（未认证时返回 nil）
+func CurrentPrincipal(c echo.Context) *auth.Principal {
+	p, _ := c.Get(principalKey).(*auth.Principal)
+	return p
+}
+
+// BasicAuth 基于账户存储的 HTTP Basic 认证，失败次数过多时按 IP/用户名临时锁定
+func BasicAuth(users *auth.UserStore, limiter *auth.Limiter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			username, password, ok := c.Request().BasicAuth()
+			if !ok {
+				return unauthorized(c, basicChallenge, "未认证")
+			}
+
+			ip := c.RealIP()
+			if wait := limiter.Check(ip, username); wait > 0 {
+				return TooManyAttempts(c, wait)
+			}
+
+			u, ok := users.Authenticate(username, password)
+			if !ok {
+				if wait := limiter.Failure(ip, username); wait > 0 {
+					return TooManyAttempts(c, wait)
+				}
+				return unauthorized(c, basicChallenge, "用户名或密码错误")
+			}
+
+			limiter.Success(ip, username)
+			c.Set(principalKey, auth.PrincipalFromUser(u, auth.MethodBasic))
+			return next(c)
+		}
+	}
+}
+
+// AuthenticateConfig 管理 API 认证配置
+type AuthenticateConfig struct {
+	Users    *auth.UserStore
+	Tokens   *auth.TokenStore
+	Sessions *auth.SessionStore
+	Limiter  *auth.Limiter
+	// PublicRoutes 无需认证的路由（Echo 路由路径），如登录接口
+	PublicRoutes []string
+	// SecureCookie 清除失效会话 Cookie 时是否设置 Secure 属性
+	SecureCookie bool
+}
+
+// Authenticate 依次支持 Authorization: Bearer <token>、会话 Cookie 与 HTTP Basic 认证
+// 会话认证的变更请求必须在 X-CSRF-Token 头中携带会话的 CSRF Token
+func Authenticate(config AuthenticateConfig) echo.MiddlewareFunc {
+	basic := BasicAuth(config.Users, config.Limiter)
+	publicRoutes := make(map[string]struct{}, len(config.PublicRoutes))
+	for _, r := range config.PublicRoutes {
+		publicRoutes[r] = struct{}{}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		basicNext := basic(next)
+		return func(c echo.Context) error {
+			if _, ok := publicRoutes[c.Path()]; ok {
+				return next(c)
+			}
+
+			if plain, ok := bearerToken(c.Request()); ok {
+				return authenticateToken(c, config, plain, next)
+			}
+
+			if config.Sessions != nil {
+				sess, p, present := lookupSession(c, config.Users, config.Sessions)
+				if sess != nil {
+					if !isSafeMethod(c.Request().Method) && !sess.CheckCSRF(c.Request().Header.Get(CSRFHeader)) {
+						return c.JSON(http.StatusForbidden, api.Response{
+							Success: false,
+							Message: "CSRF Token 无效",
+						})
+					}
+					c.Set(sessionKey, sess)
+					c.Set(principalKey, p)
+					return next(c)
+				}
+
+				// 会话失效且未携带其他凭据时不返回 Basic 质询，避免浏览器弹出登录框
+				if present {
+					ClearSessionCookie(c, config.SecureCookie)
+					if _, _, ok := c.Request().BasicAuth(); !ok {
+						return c.JSON(http.StatusUnauthorized, api.Response{
+							Success: false,
+							Message: "会话已过期，请重新登录",
+						})
+					}
+				}
+			}
+
+			return basicNext(c)
+		}
+	}
+}
+
+// authenticateToken 校验 API Token
+func authenticateToken(c echo.Context, config AuthenticateConfig, plain string, next echo.HandlerFunc) error {
+	// Token 无用户名，仅按 IP 限流
+	ip := c.RealIP()
+	if wait := config.Limiter.Check(ip, ""); wait > 0 {
+		return TooManyAttempts(c, wait)
+	}
+
+	t, ok := config.Tokens.Authenticate(plain)
+	if !ok {
+		if wait := config.Limiter.Failure(ip, ""); wait > 0 {
+			return TooManyAttempts(c, wait)
+		}
+		return unauthorized(c, bearerChallenge, "Token 无效或已过期")
+	}
+
+	config.Limiter.Success(ip, "")
+	c.Set(principalKey, auth.PrincipalFromToken(t))
+	return next(c)
+}
+
+const (
+	basicChallenge  = `Basic realm="pages"`
+	bearerChallenge = `Bearer realm="pages"`
+)
+
+// unauthorized 返回 401 及认证质询
+func unauthorized(c echo.Context, challenge, message string) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, challenge)
+	return c.JSON(http.StatusUnauthorized, api.Response{
+		Success: false,
+		Message: message,
+	})
+}
+
+// TooManyAttempts 返回 429 及 Retry-After（秒，向上取整）
+func TooManyAttempts(c echo.Context, wait time.Duration) error {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(seconds))
+	return c.JSON(http.StatusTooManyRequests, api.Response{
+		Success: false,
+		Message: fmt.Sprintf("认证失败次数过多，请 %d 秒后重试", seconds),
+	})
+}
+
+// bearerToken 从 Authorization 头提取 Bearer Token
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get(echo.HeaderAuthorization)
+	scheme, token, ok := strings.Cut(h, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// AuthorizeConfig 权限校验配置
+type AuthorizeConfig struct {
+	// OpenRoutes 对所有已认证主体开放的路由（Echo 路由路径）
+	OpenRoutes []string
+	// PublicRoutes 无需认证的路由（Echo 路由路径），与 AuthenticateConfig.PublicRoutes 一致
+	PublicRoutes []string
+	// RequiredScope 返回路由要求的 Token 权限范围，为空表示不允许 Token 访问
+	RequiredScope func(method, path string) string
+	// TenantSuspended 判断租户是否已停用；已停用租户的路由仅超级管理员可访问
+	TenantSuspended func(tenant string) bool
+}
+
+// Authorize 按角色或 Token 权限范围校验管理 API 的访问权限
+// 租户路由 (/users/:username/...) 按 :username 匹配租户；其余路由仅超级管理员和全局只读账户（GET）可访问
+func Authorize(config AuthorizeConfig) echo.MiddlewareFunc {
+	openRoutes := make(map[string]struct{}, len(config.OpenRoutes))
+	for _, r := range config.OpenRoutes {
+		openRoutes[r] = struct{}{}
+	}
+	publicRoutes := make(map[string]struct{}, len(config.PublicRoutes))
+	for _, r := range config.PublicRoutes {
+		publicRoutes[r] = struct{}{}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if _, ok := publicRoutes[c.Path()]; ok {
+				return next(c)
+			}
+
+			p := CurrentPrincipal(c)
+			if p == nil {
+				return c.JSON(http.StatusUnauthorized, api.Response{
+					Success: false,
+					Message: "未认证",
+				})
+			}
+
+			if _, ok := openRoutes[c.Path()]; ok {
+				return next(c)
+			}
+
+			tenant := c.Param("username")
+			if tenant != "" && p.Role != auth.RoleSuperAdmin && config.TenantSuspended != nil && config.TenantSuspended(tenant) {
+				return c.JSON(http.StatusForbidden, api.Response{
+					Success: false,
+					Message: "租户已停用",
+				})
+			}
+
+			method := c.Request().Method
+			var allowed bool
+			if p.Method == auth.MethodToken {
+				scope := ""
+				if config.RequiredScope != nil {
+					scope = config.RequiredScope(method, c.Path())
+				}
+				allowed = p.AllowsScope(scope, tenant, c.Param("id"))
+			} else {
+				allowed = p.Allows(method, tenant)
+			}
+
+			if !allowed {
+				return c.JSON(http.StatusForbidden, api.Response{
+					Success: false,
+					Message: "无权访问该资源",
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// Permits 判断当前主体能否访问租户资源
+// 用于路径中不含 :username 的开放路由，由处理器按资源所属的租户和站点校验
+func Permits(c echo.Context, method, scope, tenant, siteID string) bool {
+	p := CurrentPrincipal(c)
+	if p == nil {
+		return false
+	}
+	if p.Method == auth.MethodToken {
+		return p.AllowsScope(scope, tenant, siteID)
+	}
+	return p.Allows(method, tenant)
+}
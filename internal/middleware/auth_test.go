@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/auth"
+	"pages/pkg/api"
+)
+
+// testPrincipals 测试使用的主体，按 X-Test-Principal 请求头选择
+var testPrincipals = map[string]*auth.Principal{
+	"super":      {Username: "root", Role: auth.RoleSuperAdmin, Method: auth.MethodBasic},
+	"alice":      {Username: "a", Role: auth.RoleTenantAdmin, Tenant: "alice", Method: auth.MethodBasic},
+	"bob":        {Username: "b", Role: auth.RoleTenantAdmin, Tenant: "bob", Method: auth.MethodSession},
+	"carol":      {Username: "c", Role: auth.RoleTenantAdmin, Tenant: "carol", Method: auth.MethodBasic},
+	"reader":     {Username: "r", Role: auth.RoleReadOnly, Method: auth.MethodBasic},
+	"aliceRead":  {Username: "ar", Role: auth.RoleReadOnly, Tenant: "alice", Method: auth.MethodOIDC},
+	"aliceToken": {Username: "a", Tenant: "alice", Method: auth.MethodToken, Scopes: []string{api.ScopeSiteRead}},
+	"blogToken":  {Username: "a", Tenant: "alice", Method: auth.MethodToken, SiteID: "blog", Scopes: []string{api.ScopeSiteRead, api.ScopeSiteDeploy}},
+}
+
+// newAuthorizeApp 注册各类路由：租户路由、全局路由、开放路由和公开路由
+func newAuthorizeApp() *echo.Echo {
+	e := echo.New()
+	g := e.Group("/_api", func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if p, ok := testPrincipals[c.Request().Header.Get("X-Test-Principal")]; ok {
+				c.Set(principalKey, p)
+			}
+			return next(c)
+		}
+	})
+	g.Use(Authorize(AuthorizeConfig{
+		OpenRoutes:   []string{"/_api/session"},
+		PublicRoutes: []string{"/_api/login"},
+		RequiredScope: func(method, path string) string {
+			switch method + " " + path {
+			case "GET /_api/users/:username/sites", "GET /_api/users/:username/sites/:id":
+				return api.ScopeSiteRead
+			case "POST /_api/users/:username/sites/:id/deploy":
+				return api.ScopeSiteDeploy
+			}
+			return ""
+		},
+		TenantSuspended: func(tenant string) bool { return tenant == "carol" },
+	}))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	g.GET("/users/:username/sites", ok)
+	g.POST("/users/:username/sites", ok)
+	g.GET("/users/:username/sites/:id", ok)
+	g.POST("/users/:username/sites/:id/deploy", ok)
+	g.GET("/accounts", ok)
+	g.POST("/accounts", ok)
+	g.GET("/session", ok)
+	g.POST("/login", ok)
+	return e
+}
+
+func TestAuthorize(t *testing.T) {
+	e := newAuthorizeApp()
+	tests := []struct {
+		principal string
+		method    string
+		path      string
+		want      int
+	}{
+		// 未认证
+		{"", http.MethodGet, "/_api/users/alice/sites", http.StatusUnauthorized},
+		{"", http.MethodGet, "/_api/session", http.StatusUnauthorized},
+		{"", http.MethodPost, "/_api/login", http.StatusOK},
+
+		// 超级管理员
+		{"super", http.MethodPost, "/_api/users/alice/sites", http.StatusOK},
+		{"super", http.MethodPost, "/_api/accounts", http.StatusOK},
+		{"super", http.MethodGet, "/_api/users/carol/sites", http.StatusOK},
+
+		// 租户管理员只能访问所属租户的 /users/:username/... 路由
+		{"alice", http.MethodGet, "/_api/users/alice/sites", http.StatusOK},
+		{"alice", http.MethodPost, "/_api/users/alice/sites", http.StatusOK},
+		{"alice", http.MethodGet, "/_api/users/bob/sites", http.StatusForbidden},
+		{"alice", http.MethodPost, "/_api/users/bob/sites/blog/deploy", http.StatusForbidden},
+		{"bob", http.MethodGet, "/_api/users/alice/sites/blog", http.StatusForbidden},
+		{"alice", http.MethodGet, "/_api/accounts", http.StatusForbidden},
+		{"alice", http.MethodGet, "/_api/session", http.StatusOK},
+
+		// 已停用租户的路由仅超级管理员可访问，开放路由不受影响
+		{"carol", http.MethodGet, "/_api/users/carol/sites", http.StatusForbidden},
+		{"carol", http.MethodGet, "/_api/session", http.StatusOK},
+
+		// 只读账户
+		{"reader", http.MethodGet, "/_api/users/bob/sites", http.StatusOK},
+		{"reader", http.MethodGet, "/_api/accounts", http.StatusOK},
+		{"reader", http.MethodPost, "/_api/accounts", http.StatusForbidden},
+		{"reader", http.MethodPost, "/_api/users/bob/sites", http.StatusForbidden},
+		{"aliceRead", http.MethodGet, "/_api/users/alice/sites", http.StatusOK},
+		{"aliceRead", http.MethodGet, "/_api/users/bob/sites", http.StatusForbidden},
+		{"aliceRead", http.MethodGet, "/_api/accounts", http.StatusForbidden},
+
+		// Token 按路由的权限范围、租户和绑定站点校验
+		{"aliceToken", http.MethodGet, "/_api/users/alice/sites", http.StatusOK},
+		{"aliceToken", http.MethodGet, "/_api/users/bob/sites", http.StatusForbidden},
+		{"aliceToken", http.MethodPost, "/_api/users/alice/sites/blog/deploy", http.StatusForbidden},
+		{"aliceToken", http.MethodPost, "/_api/users/alice/sites", http.StatusForbidden},
+		{"aliceToken", http.MethodGet, "/_api/accounts", http.StatusForbidden},
+		{"blogToken", http.MethodPost, "/_api/users/alice/sites/blog/deploy", http.StatusOK},
+		{"blogToken", http.MethodPost, "/_api/users/alice/sites/docs/deploy", http.StatusForbidden},
+		{"blogToken", http.MethodGet, "/_api/users/alice/sites", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.principal+" "+tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("X-Test-Principal", tt.principal)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("状态码 = %d, want %d (%s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestPermits(t *testing.T) {
+	tests := []struct {
+		principal string
+		method    string
+		tenant    string
+		siteID    string
+		want      bool
+	}{
+		{"", http.MethodGet, "alice", "blog", false},
+		{"super", http.MethodPost, "bob", "blog", true},
+		{"alice", http.MethodGet, "alice", "blog", true},
+		{"alice", http.MethodGet, "bob", "blog", false},
+		{"reader", http.MethodGet, "bob", "blog", true},
+		{"reader", http.MethodPost, "bob", "blog", false},
+		{"aliceToken", http.MethodGet, "alice", "docs", true},
+		{"aliceToken", http.MethodGet, "bob", "docs", false},
+		{"blogToken", http.MethodGet, "alice", "blog", true},
+		{"blogToken", http.MethodGet, "alice", "docs", false},
+	}
+	for _, tt := range tests {
+		c := echo.New().NewContext(httptest.NewRequest(tt.method, "/", nil), httptest.NewRecorder())
+		if p := testPrincipals[tt.principal]; p != nil {
+			c.Set(principalKey, p)
+		}
+		if got := Permits(c, tt.method, api.ScopeSiteRead, tt.tenant, tt.siteID); got != tt.want {
+			t.Errorf("Permits(%s, %s, %s/%s) = %v, want %v", tt.principal, tt.method, tt.tenant, tt.siteID, got, tt.want)
+		}
+	}
+}
+
+func TestAuthenticateToken(t *testing.T) {
+	tokens := auth.NewTokenStore(t.TempDir())
+	newToken := func(siteID string) (*auth.Token, string) {
+		tok, plain, err := auth.NewToken("ci", "alice", siteID, []string{api.ScopeSiteRead}, nil, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tokens.Add(tok); err != nil {
+			t.Fatal(err)
+		}
+		return tok, plain
+	}
+	_, valid := newToken("blog")
+	expired, expiredPlain := newToken("")
+	past := time.Now().Add(-time.Second)
+	expired.ExpiresAt = &past
+	revoked, revokedPlain := newToken("")
+	if err := tokens.Revoke("alice", revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	e.Use(Authenticate(AuthenticateConfig{Tokens: tokens, Limiter: auth.NewLimiter(auth.LimiterConfig{})}))
+	e.GET("/", func(c echo.Context) error {
+		p := CurrentPrincipal(c)
+		if p == nil || p.Method != auth.MethodToken || p.Tenant != "alice" || p.SiteID != "blog" {
+			t.Errorf("principal = %+v", p)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"有效", valid, http.StatusOK},
+		{"未知", "pages_0000", http.StatusUnauthorized},
+		{"已过期", expiredPlain, http.StatusUnauthorized},
+		{"已吊销", revokedPlain, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get(echo.HeaderWWWAuthenticate) != bearerChallenge {
+				t.Errorf("WWW-Authenticate = %q", rec.Header().Get(echo.HeaderWWWAuthenticate))
+			}
+		})
+	}
+}
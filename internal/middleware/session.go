@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/auth"
+)
+
+const (
+	// SessionCookieName 管理界面会话 Cookie 名称
+	SessionCookieName = "pages_session"
+	// CSRFHeader 会话认证的变更请求需携带的 CSRF Token 请求头
+	CSRFHeader = "X-CSRF-Token"
+
+	// sessionKey 请求上下文中保存会话的键
+	sessionKey = "session"
+)
+
+// SessionConfig 会话认证配置
+type SessionConfig struct {
+	Users    *auth.UserStore
+	Sessions *auth.SessionStore
+	// SecureCookie 强制为会话 Cookie 设置 Secure 属性（HTTPS 请求始终设置）
+	SecureCookie bool
+}
+
+// CurrentSession 获取当前请求的会话（非会话认证时返回 nil）
+func CurrentSession(c echo.Context) *auth.Session {
+	s, _ := c.Get(sessionKey).(*auth.Session)
+	return s
+}
+
+// SetPrincipal 设置当前请求的认证主体（供登录接口在认证成功后使用，以便审计日志记录操作者）
+func SetPrincipal(c echo.Context, p *auth.Principal) {
+	c.Set(principalKey, p)
+}
+
+// lookupSession 根据请求 Cookie 查找有效会话及其认证主体
+// present 表示请求携带了会话 Cookie（无论是否有效）
+func lookupSession(c echo.Context, users *auth.UserStore, sessions *auth.SessionStore) (sess *auth.Session, p *auth.Principal, present bool) {
+	cookie, err := c.Cookie(SessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, nil, false
+	}
+
+	sess = sessions.Touch(cookie.Value)
+	if sess == nil {
+		return nil, nil, true
+	}
+
+	// 单点登录会话的权限在登录时确定
+	if sess.Method == auth.MethodOIDC {
+		return sess, sess.Principal(), true
+	}
+
+	// 账户密码会话每次请求重新读取账户，使角色变更和账户删除立即生效
+	u := users.Get(sess.Username)
+	if u == nil {
+		sessions.Delete(sess.ID)
+		return nil, nil, true
+	}
+	return sess, auth.PrincipalFromUser(u, auth.MethodSession), true
+}
+
+// SetSessionCookie 写入会话 Cookie（HttpOnly、SameSite=Lax）
+// 单点登录回调是从身份提供方开始的跳转链，Strict 的 Cookie 在落地请求中不会被携带；
+// 变更请求另有 CSRF Token 保护，因此使用 Lax
+func SetSessionCookie(c echo.Context, sess *auth.Session, secure bool) {
+	c.SetCookie(&http.Cookie{
+		Name:     SessionCookieName,
+		Value:    sess.ID,
+		Path:     "/",
+		Expires:  sess.ExpiresAt,
+		MaxAge:   int(time.Until(sess.ExpiresAt).Seconds()),
+		Secure:   secure || c.Scheme() == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearSessionCookie 清除会话 Cookie
+func ClearSessionCookie(c echo.Context, secure bool) {
+	c.SetCookie(&http.Cookie{
+		Name:     SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   secure || c.Scheme() == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// RequireSession 要求有效的登录会话，否则重定向到登录页（用于管理界面）
+func RequireSession(config SessionConfig, loginPath string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			sess, p, present := lookupSession(c, config.Users, config.Sessions)
+			if sess == nil {
+				if present {
+					ClearSessionCookie(c, config.SecureCookie)
+				}
+				return c.Redirect(http.StatusFound, loginPath)
+			}
+
+			c.Set(sessionKey, sess)
+			c.Set(principalKey, p)
+			return next(c)
+		}
+	}
+}
+
+// isSafeMethod 判断是否为无副作用的 HTTP 方法（无需 CSRF 校验）
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
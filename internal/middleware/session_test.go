@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"pages/internal/auth"
+)
+
+// newSessionApp 创建使用账户存储、会话存储与限流器的认证应用
+func newSessionApp(t *testing.T, limiter *auth.Limiter) (*echo.Echo, *auth.Session) {
+	t.Helper()
+	users := auth.NewUserStore(t.TempDir())
+	u, err := auth.NewUser("alice", "secret", auth.RoleTenantAdmin, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Add(u); err != nil {
+		t.Fatal(err)
+	}
+	sessions := auth.NewSessionStore(time.Hour, time.Hour)
+	sess, err := sessions.Create(auth.PrincipalFromUser(u, auth.MethodSession))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	e.Use(Authenticate(AuthenticateConfig{Users: users, Sessions: sessions, Limiter: limiter}))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/", ok)
+	e.POST("/", ok)
+	return e, sess
+}
+
+func TestAuthenticateSessionCSRF(t *testing.T) {
+	e, sess := newSessionApp(t, auth.NewLimiter(auth.LimiterConfig{}))
+	tests := []struct {
+		name   string
+		method string
+		cookie string
+		csrf   string
+		want   int
+	}{
+		{"安全方法无需 CSRF Token", http.MethodGet, sess.ID, "", http.StatusOK},
+		{"变更请求携带正确的 CSRF Token", http.MethodPost, sess.ID, sess.CSRFToken, http.StatusOK},
+		{"变更请求缺少 CSRF Token", http.MethodPost, sess.ID, "", http.StatusForbidden},
+		{"变更请求 CSRF Token 错误", http.MethodPost, sess.ID, sess.CSRFToken + "x", http.StatusForbidden},
+		{"会话已失效", http.MethodPost, "missing", sess.CSRFToken, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: tt.cookie})
+			if tt.csrf != "" {
+				req.Header.Set(CSRFHeader, tt.csrf)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			// 会话失效时不返回 Basic 质询，避免浏览器弹出登录框
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get(echo.HeaderWWWAuthenticate) != "" {
+				t.Errorf("WWW-Authenticate = %q", rec.Header().Get(echo.HeaderWWWAuthenticate))
+			}
+		})
+	}
+}
+
+func TestBasicAuthLockout(t *testing.T) {
+	limiter := auth.NewLimiter(auth.LimiterConfig{MaxFailuresPerUser: 2, LockoutBase: 90 * time.Second})
+	e, _ := newSessionApp(t, limiter)
+	basic := func(password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth("alice", password)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := basic("wrong"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("第 1 次失败 status = %d, want 401", rec.Code)
+	}
+	rec := basic("wrong")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("第 2 次失败 status = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get(echo.HeaderRetryAfter); got != strconv.Itoa(90) {
+		t.Errorf("Retry-After = %q, want 90", got)
+	}
+	// 锁定期间即使密码正确也拒绝
+	if rec := basic("secret"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("锁定期间 status = %d, want 429", rec.Code)
+	}
+}
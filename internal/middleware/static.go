@@ -16,7 +16,8 @@ import (
 )
 
 // StaticFileServer 静态文件服务中间件
-func StaticFileServer(sm *site.ManagerLockFree, am *analytics.Manager) echo.MiddlewareFunc {
+// bandwidthExceeded 判断站点本月流量是否超出配额（为 nil 时不限制）
+func StaticFileServer(sm *site.ManagerLockFree, am *analytics.Manager, bandwidthExceeded func(snap *site.SiteSnapshot) bool) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			start := time.Now()
@@ -68,6 +69,13 @@ func StaticFileServer(sm *site.ManagerLockFree, am *analytics.Manager) echo.Midd
 				return next(c)
 			}
 
+			if bandwidthExceeded != nil && bandwidthExceeded(snap) {
+				return c.JSON(http.StatusTooManyRequests, map[string]string{
+					"error":   "流量超限",
+					"message": fmt.Sprintf("域名 %s 对应的站点本月流量已超出配额", host),
+				})
+			}
+
 			// 构建文件路径
 			sitesDirVal := c.Get("sitesDir")
 			baseDir, ok := sitesDirVal.(string)
@@ -77,6 +85,10 @@ func StaticFileServer(sm *site.ManagerLockFree, am *analytics.Manager) echo.Midd
 				})
 			}
 			rootDir := filepath.Join(baseDir, snap.Username, snap.ID)
+			// 站点目录可能是指向发布目录的符号链接：解析一次，整个请求都使用同一个发布
+			if realRoot, err := filepath.EvalSymlinks(rootDir); err == nil {
+				rootDir = realRoot
+			}
 			filePath := filepath.Join(rootDir, reqPath)
 
 			// 安全检查：防止路径遍历攻击
@@ -103,6 +115,7 @@ func StaticFileServer(sm *site.ManagerLockFree, am *analytics.Manager) echo.Midd
 }
 
 // isPathSafe 检查路径是否安全（防止路径遍历攻击）
+// 除检查路径本身外，还解析路径中的符号链接，确认文件的实际位置仍在站点目录（已解析的发布目录）之内
 func isPathSafe(rootDir, filePath string) bool {
 	absRoot, err := filepath.Abs(rootDir)
 	if err != nil {
@@ -113,8 +126,25 @@ func isPathSafe(rootDir, filePath string) bool {
 	if err != nil {
 		return false
 	}
+	if !isWithin(absRoot, absFile) {
+		return false
+	}
+
+	realRoot, err := filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		// 站点目录不存在时没有可提供的文件，交由后续返回 404
+		return os.IsNotExist(err)
+	}
+	realFile, err := filepath.EvalSymlinks(absFile)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	return isWithin(realRoot, realFile)
+}
 
-	return strings.HasPrefix(absFile, absRoot)
+// isWithin 判断 path 是否为 root 或位于 root 之下
+func isWithin(root, path string) bool {
+	return path == root || strings.HasPrefix(path, root+string(filepath.Separator))
 }
 
 // handleNotFound 处理文件未找到的情况
@@ -0,0 +1,243 @@
+package quota
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"pages/internal/analytics"
+	"pages/internal/handler/deploy"
+	"pages/internal/site"
+	"pages/internal/tenant"
+)
+
+// bandwidthCacheTTL 流量检查结果的缓存时长（避免每个请求都汇总统计数据）
+const bandwidthCacheTTL = time.Minute
+
+// Error 超出配额，Status 为应返回的 HTTP 状态码
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Checker 配额检查器
+// 租户配额中的 max_sites、max_deployed_bytes、max_checkpoint_bytes、max_monthly_bandwidth_bytes
+// 为租户所有站点的合计上限；max_upload_bytes、max_checkpoints 为单个站点的上限。
+// 站点级配额覆盖租户的单站点上限，并为合计类配额额外设置该站点自身的上限。
+type Checker struct {
+	tenants     *tenant.Store
+	sm          *site.ManagerLockFree
+	checkpoints *deploy.CheckpointManager
+	analytics   *analytics.Manager
+
+	mu        sync.Mutex
+	bandwidth map[string]bandwidthState // username:siteID -> 最近一次检查结果
+}
+
+type bandwidthState struct {
+	exceeded  bool
+	checkedAt time.Time
+}
+
+// NewChecker 创建配额检查器
+func NewChecker(tenants *tenant.Store, sm *site.ManagerLockFree, am *analytics.Manager, checkpointsDir string) *Checker {
+	return &Checker{
+		tenants:     tenants,
+		sm:          sm,
+		checkpoints: deploy.NewCheckpointManager(checkpointsDir),
+		analytics:   am,
+		bandwidth:   make(map[string]bandwidthState),
+	}
+}
+
+// CheckSiteCount 创建或恢复站点前检查租户的站点数量
+func (q *Checker) CheckSiteCount(username string) error {
+	limits := q.tenantLimits(username)
+	if limits.MaxSites <= 0 {
+		return nil
+	}
+	sites, err := q.sm.ListAllForUser(username)
+	if err != nil {
+		return err
+	}
+	if len(sites) >= limits.MaxSites {
+		return &Error{
+			Status:  http.StatusTooManyRequests,
+			Message: fmt.Sprintf("站点数量已达租户上限 (%d)", limits.MaxSites),
+		}
+	}
+	return nil
+}
+
+// UploadLimit 返回站点的单次上传大小上限（0 表示不限制）
+func (q *Checker) UploadLimit(s *site.Site) int64 {
+	return pick(siteLimits(s).MaxUploadBytes, q.tenantLimits(s.Username).MaxUploadBytes)
+}
+
+// CheckDeploy 检查部署新版本（大小为 size）后站点和租户的部署文件总大小
+func (q *Checker) CheckDeploy(s *site.Site, size int64) error {
+	if limit := siteLimits(s).MaxDeployedBytes; limit > 0 && size > limit {
+		return &Error{
+			Status:  http.StatusInsufficientStorage,
+			Message: fmt.Sprintf("站点部署文件大小 %s 超出上限 %s", deploy.FormatBytes(size), deploy.FormatBytes(limit)),
+		}
+	}
+
+	limit := q.tenantLimits(s.Username).MaxDeployedBytes
+	if limit <= 0 {
+		return nil
+	}
+	sites, err := q.sm.ListAllForUser(s.Username)
+	if err != nil {
+		return err
+	}
+	total := size
+	for _, other := range sites {
+		if other.ID == s.ID {
+			continue
+		}
+		if usage, err := q.checkpoints.GetStorageUsage(s.Username, other.ID); err == nil {
+			total += usage.DeployedSize
+		}
+	}
+	if total > limit {
+		return &Error{
+			Status:  http.StatusInsufficientStorage,
+			Message: fmt.Sprintf("租户部署文件总大小将达到 %s，超出上限 %s", deploy.FormatBytes(total), deploy.FormatBytes(limit)),
+		}
+	}
+	return nil
+}
+
+// CheckCheckpoint 检查点配额检查（deploy.CheckpointQuota）
+func (q *Checker) CheckCheckpoint(username, siteID string, count int, size int64) error {
+	sites, err := q.sm.ListAllForUser(username)
+	if err != nil {
+		return err
+	}
+	tenantLimits := q.tenantLimits(username)
+	var own site.Limits
+	for _, s := range sites {
+		if s.ID == siteID {
+			own = derefLimits(s.Limits)
+		}
+	}
+
+	if limit := pick(int64(own.MaxCheckpoints), int64(tenantLimits.MaxCheckpoints)); limit > 0 && int64(count) > limit {
+		return &Error{
+			Status:  http.StatusTooManyRequests,
+			Message: fmt.Sprintf("检查点数量已达上限 (%d)，请先删除旧检查点", limit),
+		}
+	}
+
+	if own.MaxCheckpointBytes <= 0 && tenantLimits.MaxCheckpointBytes <= 0 {
+		return nil
+	}
+	var siteTotal, tenantTotal int64
+	for _, s := range sites {
+		usage, err := q.checkpoints.GetStorageUsage(username, s.ID)
+		if err != nil {
+			continue
+		}
+		tenantTotal += usage.CheckpointsSize
+		if s.ID == siteID {
+			siteTotal = usage.CheckpointsSize
+		}
+	}
+	if limit := own.MaxCheckpointBytes; limit > 0 && siteTotal+size > limit {
+		return &Error{
+			Status:  http.StatusInsufficientStorage,
+			Message: fmt.Sprintf("站点检查点总大小将达到 %s，超出上限 %s", deploy.FormatBytes(siteTotal+size), deploy.FormatBytes(limit)),
+		}
+	}
+	if limit := tenantLimits.MaxCheckpointBytes; limit > 0 && tenantTotal+size > limit {
+		return &Error{
+			Status:  http.StatusInsufficientStorage,
+			Message: fmt.Sprintf("租户检查点总大小将达到 %s，超出上限 %s", deploy.FormatBytes(tenantTotal+size), deploy.FormatBytes(limit)),
+		}
+	}
+	return nil
+}
+
+// BandwidthExceeded 判断站点或其租户本月流量是否已超出配额（结果缓存一分钟）
+// 站点和租户都未设置流量配额时直接返回，不查询站点配置和统计数据
+func (q *Checker) BandwidthExceeded(snap *site.SiteSnapshot) bool {
+	username, siteID := snap.Username, snap.ID
+	if snap.MaxMonthlyBandwidthBytes <= 0 && q.tenantLimits(username).MaxMonthlyBandwidthBytes <= 0 {
+		return false
+	}
+
+	key := username + ":" + siteID
+	q.mu.Lock()
+	state, ok := q.bandwidth[key]
+	q.mu.Unlock()
+	if ok && time.Since(state.checkedAt) < bandwidthCacheTTL {
+		return state.exceeded
+	}
+
+	exceeded := q.bandwidthExceeded(username, siteID)
+
+	q.mu.Lock()
+	q.bandwidth[key] = bandwidthState{exceeded: exceeded, checkedAt: time.Now()}
+	q.mu.Unlock()
+	return exceeded
+}
+
+func (q *Checker) bandwidthExceeded(username, siteID string) bool {
+	month := time.Now().Format("2006-01")
+	sites, err := q.sm.ListAllForUser(username)
+	if err != nil {
+		return false
+	}
+
+	for _, s := range sites {
+		if s.ID != siteID {
+			continue
+		}
+		if limit := siteLimits(s).MaxMonthlyBandwidthBytes; limit > 0 && q.analytics.MonthBytes(username, siteID, month) >= limit {
+			return true
+		}
+	}
+
+	limit := q.tenantLimits(username).MaxMonthlyBandwidthBytes
+	if limit <= 0 {
+		return false
+	}
+	var total int64
+	for _, s := range sites {
+		total += q.analytics.MonthBytes(username, s.ID, month)
+	}
+	return total >= limit
+}
+
+// tenantLimits 返回租户配额（未登记的租户不受限制）
+func (q *Checker) tenantLimits(username string) tenant.Limits {
+	if t := q.tenants.Get(username); t != nil {
+		return t.Limits
+	}
+	return tenant.Limits{}
+}
+
+func siteLimits(s *site.Site) site.Limits {
+	return derefLimits(s.Limits)
+}
+
+func derefLimits(l *site.Limits) site.Limits {
+	if l == nil {
+		return site.Limits{}
+	}
+	return *l
+}
+
+// pick 站点级上限优先，未设置时使用租户上限
+func pick(siteLimit, tenantLimit int64) int64 {
+	if siteLimit > 0 {
+		return siteLimit
+	}
+	return tenantLimit
+}
@@ -0,0 +1,35 @@
+package quota
+
+import (
+	"testing"
+
+	"pages/internal/site"
+	"pages/internal/tenant"
+)
+
+func TestBandwidthExceededWithoutLimits(t *testing.T) {
+	tenants := tenant.NewStore(t.TempDir())
+	if err := tenants.Add(tenant.New("alice")); err != nil {
+		t.Fatal(err)
+	}
+	// 站点管理器和统计数据为 nil：未设置流量配额时不能访问它们
+	q := &Checker{tenants: tenants, bandwidth: make(map[string]bandwidthState)}
+
+	tests := []struct {
+		name string
+		snap *site.SiteSnapshot
+	}{
+		{"已登记租户", &site.SiteSnapshot{ID: "blog", Username: "alice"}},
+		{"未登记租户", &site.SiteSnapshot{ID: "blog", Username: "bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if q.BandwidthExceeded(tt.snap) {
+				t.Error("未设置流量配额时 BandwidthExceeded = true")
+			}
+			if len(q.bandwidth) != 0 {
+				t.Errorf("未设置流量配额时不应缓存检查结果: %v", q.bandwidth)
+			}
+		})
+	}
+}
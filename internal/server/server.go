@@ -4,15 +4,24 @@ import (
 	"context"
 	"io/fs"
 	"log/slog"
+	"net"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	echomw "github.com/labstack/echo/v4/middleware"
 
 	"pages/internal/analytics"
+	"pages/internal/audit"
+	"pages/internal/auth"
 	"pages/internal/config"
 	"pages/internal/handler/admin"
+	"pages/internal/handler/deploy"
+	"pages/internal/jobs"
 	"pages/internal/middleware"
+	"pages/internal/quota"
 	"pages/internal/site"
+	"pages/internal/tenant"
+	"pages/internal/trash"
 	adminui "pages/web"
 )
 
@@ -22,19 +31,51 @@ type Server struct {
 	config           *config.Config
 	siteManager      *site.ManagerLockFree
 	analyticsManager *analytics.Manager
+	userStore        *auth.UserStore
+	tokenStore       *auth.TokenStore
+	sessionStore     *auth.SessionStore
+	oidcProvider     *auth.OIDCProvider
+	tenantStore      *tenant.Store
+	trashBin         *trash.Bin
+	jobManager       *jobs.Manager
+	quotaChecker     *quota.Checker
+	auditLog         *audit.Logger
+	loginLimiter     *auth.Limiter
 	initializer      *site.Initializer
+	adminHandler     *admin.Handler
 }
 
+// retentionSweepInterval 按检查点保留策略后台清理的间隔
+const retentionSweepInterval = time.Hour
+
 // New 创建新的服务器实例
-func New(cfg *config.Config, sm *site.ManagerLockFree, am *analytics.Manager) *Server {
+func New(cfg *config.Config, sm *site.ManagerLockFree, am *analytics.Manager, users *auth.UserStore, tokens *auth.TokenStore, auditLog *audit.Logger, oidc *auth.OIDCProvider, tenants *tenant.Store, bin *trash.Bin, jm *jobs.Manager) *Server {
 	e := echo.New()
 	e.HideBanner = true
+	e.IPExtractor = ipExtractor(cfg.Server.TrustedProxies)
 
 	s := &Server{
 		echo:             e,
 		config:           cfg,
 		siteManager:      sm,
 		analyticsManager: am,
+		userStore:        users,
+		tokenStore:       tokens,
+		sessionStore: auth.NewSessionStore(
+			time.Duration(cfg.Auth.SessionIdleMinutes)*time.Minute,
+			time.Duration(cfg.Auth.SessionMaxMinutes)*time.Minute,
+		),
+		oidcProvider:     oidc,
+		tenantStore:      tenants,
+		trashBin:         bin,
+		jobManager:       jm,
+		auditLog:         auditLog,
+		loginLimiter: auth.NewLimiter(auth.LimiterConfig{
+			MaxFailuresPerUser: cfg.Auth.MaxFailuresPerUser,
+			MaxFailuresPerIP:   cfg.Auth.MaxFailuresPerIP,
+			LockoutBase:        time.Duration(cfg.Auth.LockoutBaseSeconds) * time.Second,
+			LockoutMax:         time.Duration(cfg.Auth.LockoutMaxSeconds) * time.Second,
+		}),
 		initializer:      site.NewInitializer(cfg.Server.SitesDir),
 	}
 
@@ -44,6 +85,28 @@ func New(cfg *config.Config, sm *site.ManagerLockFree, am *analytics.Manager) *S
 	return s
 }
 
+// ipExtractor 返回获取客户端 IP 的方式：未配置可信代理时直接使用连接的对端地址，
+// 否则仅在请求来自可信代理时从 X-Forwarded-For 中取第一个不可信的地址
+func ipExtractor(trustedProxies []string) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, cidr := range trustedProxies {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			slog.Warn("忽略无效的可信代理地址段", "cidr", cidr, "error", err)
+			continue
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
 // setupMiddleware 设置中间件
 func (s *Server) setupMiddleware() {
 	// 日志中间件
@@ -86,38 +149,119 @@ func (s *Server) setupMiddleware() {
 
 // setupRoutes 设置路由
 func (s *Server) setupRoutes() {
-	// 统一的认证中间件
-	authMiddleware := echomw.BasicAuth(func(username, password string, c echo.Context) (bool, error) {
-		adminUser := s.config.Server.AdminUser
-		adminPass := s.config.Server.AdminPass
-		return username == adminUser && password == adminPass, nil
-	})
+	// 无需认证的管理 API 路由
+	publicRoutes := []string{
+		admin.APIPrefix + "/login",
+		admin.APIPrefix + "/oidc/login",
+		admin.APIPrefix + "/oidc/callback",
+	}
 
 	// 管理 API（在静态文件中间件之前注册，优先级更高）
-	adminGroup := s.echo.Group("/_api")
-	adminGroup.Use(authMiddleware)
+	// 接受 API Token (Authorization: Bearer)、管理界面会话 Cookie 与 Basic 认证，共用同一个登录限流器
+	adminGroup := s.echo.Group(admin.APIPrefix)
+	adminGroup.Use(middleware.Authenticate(middleware.AuthenticateConfig{
+		Users:        s.userStore,
+		Tokens:       s.tokenStore,
+		Sessions:     s.sessionStore,
+		Limiter:      s.loginLimiter,
+		PublicRoutes: publicRoutes,
+		SecureCookie: s.config.Auth.SessionSecureCookie,
+	}))
+	// 审计日志在权限校验之前，以便同时记录被拒绝的变更请求
+	adminGroup.Use(middleware.Audit(s.auditLog))
+	adminGroup.Use(middleware.Authorize(middleware.AuthorizeConfig{
+		OpenRoutes: []string{
+			admin.APIPrefix + "/system/health",
+			admin.APIPrefix + "/openapi.json",
+			admin.APIPrefix + "/session",
+			admin.APIPrefix + "/logout",
+			// 任务接口由处理器按任务所属的租户和站点校验权限
+			admin.APIPrefix + "/jobs/:job_id",
+			admin.APIPrefix + "/jobs/:job_id/events",
+		},
+		PublicRoutes:    publicRoutes,
+		RequiredScope:   admin.RequiredScope,
+		TenantSuspended: s.tenantStore.IsSuspended,
+	}))
 	
 	// 检查点存储在站点目录的父级 checkpoints 目录
 	checkpointsDir := s.config.Server.SitesDir + "-checkpoints"
-	adminHandler := admin.NewHandler(s.siteManager, s.initializer, checkpointsDir)
+	s.quotaChecker = quota.NewChecker(s.tenantStore, s.siteManager, s.analyticsManager, checkpointsDir)
+	deployLimits := deploy.Limits{
+		MaxUploadBytes:      int64(s.config.Deploy.MaxUploadMB) << 20,
+		MaxExtractedBytes:   int64(s.config.Deploy.MaxExtractedMB) << 20,
+		MaxFiles:            s.config.Deploy.MaxFiles,
+		MaxPathDepth:        s.config.Deploy.MaxPathDepth,
+		MaxCompressionRatio: s.config.Deploy.MaxCompressionRatio,
+	}
+	adminHandler := admin.NewHandler(s.siteManager, s.initializer, checkpointsDir, s.tenantStore, s.analyticsManager, s.trashBin, s.quotaChecker, deployLimits, s.jobManager)
+	adminHandler.SetDefaultRetention(deploy.Retention{
+		KeepLast:   s.config.Checkpoints.KeepLast,
+		KeepDays:   s.config.Checkpoints.KeepDays,
+		KeepDaily:  s.config.Checkpoints.KeepDaily,
+		KeepWeekly: s.config.Checkpoints.KeepWeekly,
+	})
+	adminHandler.SetKeepReleases(s.config.Checkpoints.KeepReleases)
+	adminHandler.SetLoginLimiter(s.loginLimiter)
 	adminHandler.RegisterRoutes(adminGroup)
+	s.adminHandler = adminHandler
+
+	// 启动检查点保留策略的后台清理
+	adminHandler.StartRetentionSweeper(retentionSweepInterval)
+
+	// 启动异步部署任务（含重启前未执行的任务）
+	s.jobManager.Start(adminHandler.RunJob)
+	jobHandler := admin.NewJobHandler(s.jobManager)
+	jobHandler.RegisterRoutes(adminGroup)
 
 	// 注册统计 API
 	analyticsHandler := admin.NewAnalyticsHandler(s.analyticsManager, s.siteManager)
 	analyticsHandler.RegisterRoutes(adminGroup)
 
+	// 注册租户管理 API
+	tenantHandler := admin.NewTenantHandler(s.tenantStore, s.siteManager, s.analyticsManager, s.userStore, s.sessionStore, s.tokenStore, s.trashBin, s.jobManager, adminHandler.SiteLocker(), adminHandler.CheckpointManager(), s.config.Server.SitesDir)
+	tenantHandler.RegisterRoutes(adminGroup)
+
+	// 注册账户管理 API
+	accountHandler := admin.NewAccountHandler(s.userStore, s.sessionStore)
+	accountHandler.RegisterRoutes(adminGroup)
+
+	// 注册 API Token 管理 API
+	tokenHandler := admin.NewTokenHandler(s.tokenStore, s.siteManager)
+	tokenHandler.RegisterRoutes(adminGroup)
+
+	// 注册管理界面登录会话 API
+	sessionHandler := admin.NewSessionHandler(s.userStore, s.sessionStore, s.loginLimiter, s.oidcProvider, s.config.Auth.SessionSecureCookie)
+	sessionHandler.RegisterRoutes(adminGroup)
+
+	// 注册审计日志查询 API
+	auditHandler := admin.NewAuditHandler(s.auditLog)
+	auditHandler.RegisterRoutes(adminGroup)
+
 	// Admin UI
 	adminFS, err := fs.Sub(adminui.FS(), "admin")
 	if err != nil {
 		slog.Error("Failed to load admin UI filesystem", "err", err)
 	} else {
+		// 登录页无需认证，其余页面要求有效的登录会话
+		s.echo.GET("/_admin/login", echo.StaticFileHandler("login.html", adminFS))
+
 		adminUIGroup := s.echo.Group("/_admin")
-		adminUIGroup.Use(authMiddleware)
+		adminUIGroup.Use(middleware.RequireSession(middleware.SessionConfig{
+			Users:        s.userStore,
+			Sessions:     s.sessionStore,
+			SecureCookie: s.config.Auth.SessionSecureCookie,
+		}, "/_admin/login"))
 		adminUIGroup.StaticFS("/", adminFS)
 	}
 
 	// 静态文件服务（作为最后的中间件，处理所有其他请求）
-	s.echo.Use(middleware.StaticFileServer(s.siteManager, s.analyticsManager))
+	s.echo.Use(middleware.StaticFileServer(s.siteManager, s.analyticsManager, s.quotaChecker.BandwidthExceeded))
+
+	// 校验所有管理 API 路由均已登记 OpenAPI 文档
+	for _, route := range admin.UndocumentedRoutes(s.echo.Routes()) {
+		slog.Warn("管理 API 路由缺少 OpenAPI 文档", "route", route)
+	}
 }
 
 // Start 启动服务器
@@ -129,6 +273,7 @@ func (s *Server) Start() error {
 // Shutdown 优雅停止服务器
 func (s *Server) Shutdown(ctx context.Context) error {
 	slog.Info("正在优雅关闭服务器...")
+	s.adminHandler.StopRetentionSweeper()
 	
 	// 关闭 HTTP 服务器
 	if err := s.echo.Shutdown(ctx); err != nil {
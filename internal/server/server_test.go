@@ -0,0 +1,378 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+
+	"pages/internal/analytics"
+	"pages/internal/audit"
+	"pages/internal/auth"
+	"pages/internal/config"
+	"pages/internal/handler/admin"
+	"pages/internal/jobs"
+	"pages/internal/site"
+	"pages/internal/tenant"
+	"pages/internal/trash"
+	"pages/pkg/api"
+)
+
+// testServer 使用临时目录中的存储创建的完整服务器
+type testServer struct {
+	*Server
+	users   *auth.UserStore
+	tokens  *auth.TokenStore
+	tenants *tenant.Store
+}
+
+// newTestServer 按 cmd/server 的方式组装服务器，路由由真实的 setupRoutes 注册
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.Server.DataDir = dir
+	cfg.Server.SitesDir = filepath.Join(dir, "sites")
+
+	sm := site.NewManagerLockFree(site.NewFileStore(dir))
+	if err := sm.Load(); err != nil {
+		t.Fatal(err)
+	}
+	// 静态文件中间件只放行已绑定站点的域名上的管理 API 请求（httptest 请求的域名为 example.com）
+	if err := sm.Add(site.NewSiteForUser("www", "example.com", "ops")); err != nil {
+		t.Fatal(err)
+	}
+	am := analytics.NewManager(filepath.Join(dir, "analytics"))
+	users := auth.NewUserStore(dir)
+	tokens := auth.NewTokenStore(dir)
+	auditLog := audit.NewLogger(filepath.Join(dir, "audit"), 1<<20, 1)
+	tenants := tenant.NewStore(dir)
+	bin := trash.NewBin(dir, time.Hour)
+	jm := jobs.NewManager(dir, 1, 10)
+	if err := jm.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(cfg, sm, am, users, tokens, auditLog, nil, tenants, bin, jm)
+	t.Cleanup(func() {
+		s.adminHandler.StopRetentionSweeper()
+		jm.Stop()
+		am.StopAll()
+		auditLog.Close()
+	})
+	return &testServer{Server: s, users: users, tokens: tokens, tenants: tenants}
+}
+
+func TestAdminRoutesDocumented(t *testing.T) {
+	s := newTestServer(t)
+	if missing := admin.UndocumentedRoutes(s.Echo().Routes()); len(missing) > 0 {
+		t.Fatalf("以下路由未在 operationDocs 中登记:\n%s", strings.Join(missing, "\n"))
+	}
+}
+
+func TestNoStaleRouteEntries(t *testing.T) {
+	s := newTestServer(t)
+	if stale := admin.StaleRouteEntries(s.Echo().Routes()); len(stale) > 0 {
+		t.Fatalf("以下文档或权限范围登记的路由未注册:\n%s", strings.Join(stale, "\n"))
+	}
+}
+
+// addUser 添加账户（使用最低的 bcrypt 成本，加快测试中的 Basic 认证）
+func (s *testServer) addUser(t *testing.T, username, role, tenantName string) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &auth.User{Username: username, PasswordHash: string(hash), Role: role, Tenant: tenantName}
+	if err := s.users.Add(u); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// do 以 Basic 认证发送请求，返回状态码
+func (s *testServer) do(t *testing.T, username, method, path string) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, nil)
+	req.SetBasicAuth(username, "secret")
+	rec := httptest.NewRecorder()
+	s.Echo().ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestTenantIsolation(t *testing.T) {
+	s := newTestServer(t)
+	s.addUser(t, "root", auth.RoleSuperAdmin, "")
+	s.addUser(t, "alice-admin", auth.RoleTenantAdmin, "alice")
+	s.addUser(t, "carol-admin", auth.RoleTenantAdmin, "carol")
+	for _, name := range []string{"alice", "bob", "carol"} {
+		tn := tenant.New(name)
+		tn.Suspended = name == "carol"
+		if err := s.tenants.Add(tn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 租户管理员不能访问其他租户的任何 /users/:username/... 路由
+	checked := 0
+	for _, r := range s.Echo().Routes() {
+		rest, ok := strings.CutPrefix(r.Path, admin.APIPrefix+"/users/:username")
+		if !ok || r.Method == echo.RouteNotFound {
+			continue
+		}
+		path := admin.APIPrefix + "/users/bob" + paramPattern.ReplaceAllString(rest, "x")
+		if code := s.do(t, "alice-admin", r.Method, path); code != http.StatusForbidden {
+			t.Errorf("alice-admin %s %s = %d, want 403", r.Method, path, code)
+		}
+		checked++
+	}
+	if checked < 20 {
+		t.Fatalf("只检查了 %d 个租户路由", checked)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		method   string
+		path     string
+		want     int
+	}{
+		{"访问所属租户", "alice-admin", http.MethodGet, "/_api/users/alice/sites", http.StatusOK},
+		{"所属租户中不存在的站点", "alice-admin", http.MethodGet, "/_api/users/alice/sites/missing", http.StatusNotFound},
+		{"全局路由", "alice-admin", http.MethodGet, "/_api/accounts", http.StatusForbidden},
+		{"全局变更路由", "alice-admin", http.MethodPost, "/_api/users", http.StatusForbidden},
+		{"已停用租户的管理员", "carol-admin", http.MethodGet, "/_api/users/carol/sites", http.StatusForbidden},
+		{"超级管理员访问已停用租户", "root", http.MethodGet, "/_api/users/carol/sites", http.StatusOK},
+		{"超级管理员访问全局路由", "root", http.MethodGet, "/_api/accounts", http.StatusOK},
+		{"错误的密码", "nobody", http.MethodGet, "/_api/users/alice/sites", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := s.do(t, tt.username, tt.method, tt.path); code != tt.want {
+				t.Errorf("%s %s %s = %d, want %d", tt.username, tt.method, tt.path, code, tt.want)
+			}
+		})
+	}
+}
+
+// paramPattern 路由路径中的参数段
+var paramPattern = regexp.MustCompile(`:[a-z_]+`)
+
+func TestSystemHealthAndStatus(t *testing.T) {
+	s := newTestServer(t)
+	s.addUser(t, "root", auth.RoleSuperAdmin, "")
+	s.addUser(t, "reader", auth.RoleReadOnly, "")
+	s.addUser(t, "alice-admin", auth.RoleTenantAdmin, "alice")
+	if err := s.tenants.Add(tenant.New("alice")); err != nil {
+		t.Fatal(err)
+	}
+	tok, plain, err := auth.NewToken("ci", "alice", "blog", []string{api.ScopeSiteDeploy}, nil, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.tokens.Add(tok); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		username  string // 为空时使用站点 Token
+		path      string
+		want      int
+		wantLogin bool
+	}{
+		{"Token 健康检查", "", "/_api/system/health", http.StatusOK, false},
+		{"租户管理员健康检查", "alice-admin", "/_api/system/health", http.StatusOK, false},
+		{"Token 运行状态", "", "/_api/system/status", http.StatusForbidden, false},
+		{"租户管理员运行状态", "alice-admin", "/_api/system/status", http.StatusForbidden, false},
+		{"只读账户运行状态", "reader", "/_api/system/status", http.StatusForbidden, false},
+		{"超级管理员运行状态", "root", "/_api/system/status", http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.username == "" {
+				req.Header.Set(echo.HeaderAuthorization, "Bearer "+plain)
+			} else {
+				req.SetBasicAuth(tt.username, "secret")
+			}
+			rec := httptest.NewRecorder()
+			s.Echo().ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if got := strings.Contains(rec.Body.String(), `"login"`); got != tt.wantLogin {
+				t.Errorf("响应包含登录限流统计 = %v, want %v: %s", got, tt.wantLogin, rec.Body)
+			}
+		})
+	}
+}
+
+func TestDeleteTenantRemovesAccountsAndSessions(t *testing.T) {
+	s := newTestServer(t)
+	s.addUser(t, "root", auth.RoleSuperAdmin, "")
+	s.addUser(t, "alice-admin", auth.RoleTenantAdmin, "alice")
+	s.addUser(t, "alice-reader", auth.RoleReadOnly, "alice")
+	s.addUser(t, "bob-admin", auth.RoleTenantAdmin, "bob")
+	for _, name := range []string{"alice", "bob"} {
+		if err := s.tenants.Add(tenant.New(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sessions := map[string]*auth.Session{}
+	for name, p := range map[string]*auth.Principal{
+		"alice-admin": {Username: "alice-admin", Role: auth.RoleTenantAdmin, Tenant: "alice", Method: auth.MethodSession},
+		"alice-sso":   {Username: "alice-sso", Role: auth.RoleTenantAdmin, Tenant: "alice", Method: auth.MethodOIDC},
+		"bob-admin":   {Username: "bob-admin", Role: auth.RoleTenantAdmin, Tenant: "bob", Method: auth.MethodSession},
+	} {
+		sess, err := s.sessionStore.Create(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions[name] = sess
+	}
+
+	if code := s.do(t, "root", http.MethodDelete, "/_api/users/alice"); code != http.StatusOK {
+		t.Fatalf("DELETE /users/alice = %d", code)
+	}
+
+	for _, name := range []string{"alice-admin", "alice-reader"} {
+		if s.users.Get(name) != nil {
+			t.Errorf("账户 %s 未随租户删除", name)
+		}
+	}
+	if s.users.Get("bob-admin") == nil {
+		t.Error("其他租户的账户不应被删除")
+	}
+	for name, want := range map[string]bool{"alice-admin": false, "alice-sso": false, "bob-admin": true} {
+		if got := s.sessionStore.Touch(sessions[name].ID) != nil; got != want {
+			t.Errorf("%s 会话有效 = %v, want %v", name, got, want)
+		}
+	}
+
+	// 以同名重建租户后，旧账户不能接管
+	if err := s.tenants.Add(tenant.New("alice")); err != nil {
+		t.Fatal(err)
+	}
+	if code := s.do(t, "alice-admin", http.MethodGet, "/_api/users/alice/sites"); code != http.StatusUnauthorized {
+		t.Errorf("旧账户访问重建的租户 = %d, want 401", code)
+	}
+}
+
+// request 以超级管理员 root 的 Basic 认证发送请求
+func (s *testServer) request(t *testing.T, method, path, contentType string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.SetBasicAuth("root", "secret")
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+	rec := httptest.NewRecorder()
+	s.Echo().ServeHTTP(rec, req)
+	return rec
+}
+
+// zipArchive 构建只包含 index.html 的 zip 压缩包
+func zipArchive(t *testing.T, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "index.html", Method: zip.Store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(content)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestQuotaErrorCodes(t *testing.T) {
+	s := newTestServer(t)
+	s.addUser(t, "root", auth.RoleSuperAdmin, "")
+
+	tests := []struct {
+		name   string
+		limits tenant.Limits
+		// run 在已创建站点 site 的租户上执行被配额拒绝的请求
+		run  func(t *testing.T, username string) *httptest.ResponseRecorder
+		want int
+	}{
+		{
+			name:   "站点数量",
+			limits: tenant.Limits{MaxSites: 1},
+			run: func(t *testing.T, username string) *httptest.ResponseRecorder {
+				return s.request(t, http.MethodPost, "/_api/users/"+username+"/sites", echo.MIMEApplicationJSON,
+					[]byte(`{"id":"second","domain":"second.`+username+`.test"}`))
+			},
+			want: http.StatusTooManyRequests,
+		},
+		{
+			name:   "上传大小",
+			limits: tenant.Limits{MaxUploadBytes: 1024},
+			run: func(t *testing.T, username string) *httptest.ResponseRecorder {
+				return s.request(t, http.MethodPost, "/_api/users/"+username+"/sites/site/deploy?filename=site.zip", "application/zip",
+					zipArchive(t, bytes.Repeat([]byte("x"), 4096)))
+			},
+			want: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "部署文件总大小",
+			limits: tenant.Limits{MaxDeployedBytes: 16},
+			run: func(t *testing.T, username string) *httptest.ResponseRecorder {
+				return s.request(t, http.MethodPost, "/_api/users/"+username+"/sites/site/deploy?filename=site.zip", "application/zip",
+					zipArchive(t, bytes.Repeat([]byte("x"), 256)))
+			},
+			want: http.StatusInsufficientStorage,
+		},
+		{
+			name:   "检查点数量",
+			limits: tenant.Limits{MaxCheckpoints: 1},
+			run: func(t *testing.T, username string) *httptest.ResponseRecorder {
+				path := "/_api/users/" + username + "/sites/site/checkpoints"
+				if rec := s.request(t, http.MethodPost, path, echo.MIMEApplicationJSON, []byte(`{}`)); rec.Code != http.StatusCreated {
+					t.Fatalf("第一个检查点 = %d: %s", rec.Code, rec.Body)
+				}
+				return s.request(t, http.MethodPost, path, echo.MIMEApplicationJSON, []byte(`{}`))
+			},
+			want: http.StatusTooManyRequests,
+		},
+		{
+			name:   "检查点总大小",
+			limits: tenant.Limits{MaxCheckpointBytes: 1},
+			run: func(t *testing.T, username string) *httptest.ResponseRecorder {
+				return s.request(t, http.MethodPost, "/_api/users/"+username+"/sites/site/checkpoints", echo.MIMEApplicationJSON, []byte(`{}`))
+			},
+			want: http.StatusInsufficientStorage,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username := fmt.Sprintf("quota%d", i)
+			tn := tenant.New(username)
+			tn.Limits = tt.limits
+			if err := s.tenants.Add(tn); err != nil {
+				t.Fatal(err)
+			}
+			rec := s.request(t, http.MethodPost, "/_api/users/"+username+"/sites", echo.MIMEApplicationJSON,
+				[]byte(`{"id":"site","domain":"site.`+username+`.test"}`))
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("创建站点 = %d: %s", rec.Code, rec.Body)
+			}
+
+			if rec := tt.run(t, username); rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
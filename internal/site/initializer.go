@@ -18,6 +18,11 @@ func NewInitializer(sitesDir string) *Initializer {
 	}
 }
 
+// SiteDir 返回站点文件目录
+func (i *Initializer) SiteDir(site *Site) string {
+	return site.GetRootDir(i.sitesDir)
+}
+
 // InitializeSites 初始化站点目录和示例文件
 func (i *Initializer) InitializeSites(sites []*Site) error {
 	for _, site := range sites {
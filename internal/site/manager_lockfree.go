@@ -9,9 +9,10 @@ import (
 // ManagerLockFree 站点管理器
 // 原子化
 type ManagerLockFree struct {
-	sites atomic.Value // 存储 map[string]*SiteSnapshot
-	store Store
-	mu    sync.Mutex // 仅用于写操作
+	sites     atomic.Value // 存储 map[string]*SiteSnapshot
+	store     Store
+	mu        sync.Mutex          // 仅用于写操作
+	suspended map[string]struct{} // 已停用的租户，其站点不进入快照（受 mu 保护）
 }
 
 // SiteSnapshot 站点快照
@@ -23,12 +24,32 @@ type SiteSnapshot struct {
 	Index    string
 	Enabled  bool
 	RootDir  string
+
+	// MaxMonthlyBandwidthBytes 站点级月流量上限（0 表示未设置），静态文件请求据此跳过流量统计的查询
+	MaxMonthlyBandwidthBytes int64
+}
+
+// newSnapshot 由站点构建快照
+func newSnapshot(site *Site) *SiteSnapshot {
+	snap := &SiteSnapshot{
+		ID:       site.ID,
+		Username: site.Username,
+		Domain:   site.Domain,
+		Index:    site.Index,
+		Enabled:  site.Enabled,
+		RootDir:  site.GetRelativeRootDir(),
+	}
+	if site.Limits != nil {
+		snap.MaxMonthlyBandwidthBytes = site.Limits.MaxMonthlyBandwidthBytes
+	}
+	return snap
 }
 
 // NewManagerLockFree 创建无锁站点管理器
 func NewManagerLockFree(store Store) *ManagerLockFree {
 	m := &ManagerLockFree{
-		store: store,
+		store:     store,
+		suspended: make(map[string]struct{}),
 	}
 	m.sites.Store(make(map[string]*SiteSnapshot))
 	return m
@@ -41,17 +62,13 @@ func (m *ManagerLockFree) Load() error {
 		return err
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	newSites := make(map[string]*SiteSnapshot)
 	for _, site := range sites {
-		if site.Enabled {
-			newSites[site.Domain] = &SiteSnapshot{
-				ID:       site.ID,
-				Username: site.Username,
-				Domain:   site.Domain,
-				Index:    site.Index,
-				Enabled:  site.Enabled,
-				RootDir:  site.GetRelativeRootDir(),
-			}
+		if m.servable(site) {
+			newSites[site.Domain] = newSnapshot(site)
 		}
 	}
 
@@ -65,21 +82,14 @@ func (m *ManagerLockFree) Add(site *Site) error {
 		return err
 	}
 
-	if site.Enabled {
-		m.mu.Lock()
+	m.mu.Lock()
+	if m.servable(site) {
 		oldSites := m.sites.Load().(map[string]*SiteSnapshot)
 		newSites := m.copyMap(oldSites)
-		newSites[site.Domain] = &SiteSnapshot{
-			ID:       site.ID,
-			Username: site.Username,
-			Domain:   site.Domain,
-			Index:    site.Index,
-			Enabled:  site.Enabled,
-			RootDir:  site.GetRelativeRootDir(),
-		}
+		newSites[site.Domain] = newSnapshot(site)
 		m.sites.Store(newSites)
-		m.mu.Unlock()
 	}
+	m.mu.Unlock()
 
 	return nil
 }
@@ -118,15 +128,8 @@ func (m *ManagerLockFree) Update(site *Site) error {
 	}
 	
 	// 添加新映射
-	if site.Enabled {
-		newSites[site.Domain] = &SiteSnapshot{
-			ID:       site.ID,
-			Username: site.Username,
-			Domain:   site.Domain,
-			Index:    site.Index,
-			Enabled:  site.Enabled,
-			RootDir:  site.GetRelativeRootDir(),
-		}
+	if m.servable(site) {
+		newSites[site.Domain] = newSnapshot(site)
 	}
 	
 	m.sites.Store(newSites)
@@ -286,6 +289,64 @@ func (m *ManagerLockFree) RemoveForUser(username, id string) error {
 	return m.store.RemoveForUser(username, id)
 }
 
+// SuspendUser 停用租户：原子地从快照中移除该租户的所有站点
+// 站点自身的启用状态不变，恢复租户后按原状态重新提供服务
+func (m *ManagerLockFree) SuspendUser(username string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.suspended[username] = struct{}{}
+
+	oldSites := m.sites.Load().(map[string]*SiteSnapshot)
+	newSites := make(map[string]*SiteSnapshot, len(oldSites))
+	for domain, snap := range oldSites {
+		if snap.Username != username {
+			newSites[domain] = snap
+		}
+	}
+	m.sites.Store(newSites)
+}
+
+// ResumeUser 恢复租户：原子地将该租户已启用的站点重新加入快照
+func (m *ManagerLockFree) ResumeUser(username string) error {
+	sites, err := m.store.LoadForUser(username)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.suspended, username)
+
+	newSites := m.copyMap(m.sites.Load().(map[string]*SiteSnapshot))
+	for _, site := range sites {
+		if site.Enabled {
+			newSites[site.Domain] = newSnapshot(site)
+		}
+	}
+	m.sites.Store(newSites)
+	return nil
+}
+
+// IsUserSuspended 判断租户是否已停用
+func (m *ManagerLockFree) IsUserSuspended(username string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.suspended[username]
+	return ok
+}
+
+// servable 判断站点是否应进入快照（需持有 mu）
+func (m *ManagerLockFree) servable(site *Site) bool {
+	if !site.Enabled {
+		return false
+	}
+	_, suspended := m.suspended[site.Username]
+	return !suspended
+}
+
 // copyMap 辅助函数：复制 map
 func (m *ManagerLockFree) copyMap(src map[string]*SiteSnapshot) map[string]*SiteSnapshot {
 	dst := make(map[string]*SiteSnapshot, len(src))
@@ -4,19 +4,29 @@ import (
 	"fmt"
 	"path/filepath"
 	"time"
+
+	"pages/pkg/api"
 )
 
+// Limits 站点级配额（与 pkg/api 共用）
+type Limits = api.TenantLimits
+
+// Retention 站点级检查点保留策略（与 pkg/api 共用）
+type Retention = api.CheckpointRetention
+
 // Site 站点数据结构
 // Site 对象在 Manager 中是不可变的，读取操作无需加锁
 // 所有修改操作都通过 Manager 进行，Manager 会创建新的 Site 对象
 type Site struct {
-	ID        string    `json:"id" toml:"id"`                   // 站点唯一标识（可用于目录名）
-	Username  string    `json:"username" toml:"username"`       // 租户用户名（默认为"default"）
-	Domain    string    `json:"domain" toml:"domain"`           // 绑定的域名
-	Index     string    `json:"index" toml:"index"`             // 默认首页文件
-	Enabled   bool      `json:"enabled" toml:"enabled"`         // 是否启用
-	CreatedAt time.Time `json:"created_at" toml:"created_at"`   // 创建时间
-	UpdatedAt time.Time `json:"updated_at" toml:"updated_at"`   // 更新时间
+	ID        string     `json:"id" toml:"id"`                                   // 站点唯一标识（可用于目录名）
+	Username  string     `json:"username" toml:"username"`                       // 租户用户名（默认为"default"）
+	Domain    string     `json:"domain" toml:"domain"`                           // 绑定的域名
+	Index     string     `json:"index" toml:"index"`                             // 默认首页文件
+	Enabled   bool       `json:"enabled" toml:"enabled"`                         // 是否启用
+	Limits    *Limits    `json:"limits,omitempty" toml:"limits,omitempty"`       // 站点级配额（为空时仅受租户配额限制）
+	Retention *Retention `json:"retention,omitempty" toml:"retention,omitempty"` // 检查点保留策略（为空时使用服务器默认策略）
+	CreatedAt time.Time  `json:"created_at" toml:"created_at"`                   // 创建时间
+	UpdatedAt time.Time  `json:"updated_at" toml:"updated_at"`                   // 更新时间
 }
 
 // NewSite 创建新站点（默认租户为"default"）
@@ -59,11 +69,29 @@ func (s *Site) Clone() *Site {
 		Domain:    s.Domain,
 		Index:     s.Index,
 		Enabled:   s.Enabled,
+		Limits:    cloneLimits(s.Limits),
+		Retention: cloneRetention(s.Retention),
 		CreatedAt: s.CreatedAt,
 		UpdatedAt: s.UpdatedAt,
 	}
 }
 
+func cloneLimits(l *Limits) *Limits {
+	if l == nil {
+		return nil
+	}
+	clone := *l
+	return &clone
+}
+
+func cloneRetention(r *Retention) *Retention {
+	if r == nil {
+		return nil
+	}
+	clone := *r
+	return &clone
+}
+
 // GetIndex 安全获取 Index 字段
 func (s *Site) GetIndex() string {
 	return s.Index
@@ -0,0 +1,234 @@
+package tenant
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"pages/pkg/api"
+)
+
+// Limits 租户配额（与 pkg/client 共用）
+type Limits = api.TenantLimits
+
+// usernamePattern 租户名同时用作目录名，只允许安全字符
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// ValidateUsername 校验租户名
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) || username == "." || username == ".." {
+		return fmt.Errorf("租户名只能包含字母、数字、下划线、点和连字符，且不超过 64 个字符")
+	}
+	return nil
+}
+
+// Tenant 租户元数据
+type Tenant struct {
+	Username    string     `json:"username"`
+	DisplayName string     `json:"display_name,omitempty"`
+	Contact     string     `json:"contact,omitempty"`
+	Limits      Limits     `json:"limits"`
+	Suspended   bool       `json:"suspended"`
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// New 创建租户
+func New(username string) *Tenant {
+	now := time.Now()
+	return &Tenant{
+		Username:  username,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Info 返回租户的 API 视图（不含站点统计）
+func (t *Tenant) Info() api.Tenant {
+	return api.Tenant{
+		Username:    t.Username,
+		DisplayName: t.DisplayName,
+		Contact:     t.Contact,
+		Limits:      t.Limits,
+		Suspended:   t.Suspended,
+		SuspendedAt: t.SuspendedAt,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+}
+
+// Store 基于文件的租户存储
+type Store struct {
+	path    string
+	mu      sync.RWMutex
+	tenants map[string]*Tenant
+}
+
+// NewStore 创建租户存储（<dataDir>/tenants.json）
+func NewStore(dataDir string) *Store {
+	return &Store{
+		path:    filepath.Join(dataDir, "tenants.json"),
+		tenants: make(map[string]*Tenant),
+	}
+}
+
+// Load 从文件加载租户
+func (s *Store) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenants := make(map[string]*Tenant)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		s.tenants = tenants
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取租户文件失败: %w", err)
+	}
+
+	var list []*Tenant
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("解析租户文件失败: %w", err)
+	}
+	for _, t := range list {
+		tenants[t.Username] = t
+	}
+	s.tenants = tenants
+	return nil
+}
+
+// Get 获取租户（不存在时返回 nil）
+func (s *Store) Get(username string) *Tenant {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tenants[username]
+	if !ok {
+		return nil
+	}
+	clone := *t
+	return &clone
+}
+
+// List 列出所有租户（按租户名排序）
+func (s *Store) List() []*Tenant {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*Tenant, 0, len(s.tenants))
+	for _, t := range s.tenants {
+		clone := *t
+		list = append(list, &clone)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Username < list[j].Username })
+	return list
+}
+
+// IsSuspended 判断租户是否已停用
+func (s *Store) IsSuspended(username string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tenants[username]
+	return ok && t.Suspended
+}
+
+// Add 添加租户
+func (s *Store) Add(t *Tenant) error {
+	if err := ValidateUsername(t.Username); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tenants[t.Username]; exists {
+		return fmt.Errorf("租户 %s 已存在", t.Username)
+	}
+	s.tenants[t.Username] = t
+	if err := s.saveInternal(); err != nil {
+		delete(s.tenants, t.Username)
+		return err
+	}
+	return nil
+}
+
+// Ensure 租户不存在时自动登记（兼容仅通过站点隐式存在的租户），返回是否新建
+func (s *Store) Ensure(username string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tenants[username]; exists {
+		return false, nil
+	}
+	s.tenants[username] = New(username)
+	if err := s.saveInternal(); err != nil {
+		delete(s.tenants, username)
+		return false, err
+	}
+	return true, nil
+}
+
+// Update 更新租户
+func (s *Store) Update(t *Tenant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, exists := s.tenants[t.Username]
+	if !exists {
+		return fmt.Errorf("%s: %s", api.MsgTenantNotFound, t.Username)
+	}
+	s.tenants[t.Username] = t
+	if err := s.saveInternal(); err != nil {
+		s.tenants[t.Username] = old
+		return err
+	}
+	return nil
+}
+
+// Remove 删除租户
+func (s *Store) Remove(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, exists := s.tenants[username]
+	if !exists {
+		return fmt.Errorf("%s: %s", api.MsgTenantNotFound, username)
+	}
+	delete(s.tenants, username)
+	if err := s.saveInternal(); err != nil {
+		s.tenants[username] = old
+		return err
+	}
+	return nil
+}
+
+// saveInternal 内部保存方法（不加锁）
+func (s *Store) saveInternal() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	list := make([]*Tenant, 0, len(s.tenants))
+	for _, t := range s.tenants {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Username < list[j].Username })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化租户失败: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("写入租户文件失败: %w", err)
+	}
+	return nil
+}
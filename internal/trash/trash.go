@@ -0,0 +1,422 @@
+package trash
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"pages/pkg/api"
+)
+
+// Entry 回收站条目（与 pkg/client 共用）
+type Entry = api.TrashedSite
+
+// 可通过 errors.Is 判断的错误
+var (
+	ErrNotFound = errors.New(api.MsgTrashNotFound)
+	ErrConflict = errors.New("原位置已存在数据，无法恢复")
+)
+
+// record 持久化的回收站条目，Paths 记录每类数据的原始位置
+type record struct {
+	Entry
+	Paths map[string]string `json:"paths"` // 数据类别 -> 原始目录
+}
+
+// Bin 站点回收站
+// 删除站点时将其目录整体移入 <dataDir>/trash/<username>/<trash_id>/<类别>，
+// 恢复时移回原位置，保留期满后由后台任务彻底删除
+type Bin struct {
+	path      string // 索引文件 <dataDir>/trash.json
+	root      string // 数据目录 <dataDir>/trash
+	retention time.Duration
+	mu        sync.Mutex
+	records   map[string]*record // trash_id -> 条目
+	onPurge   func(kind, dir string)
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewBin 创建回收站，retention 为保留时长（0 表示删除后立即清除）
+func NewBin(dataDir string, retention time.Duration) *Bin {
+	return &Bin{
+		path:      filepath.Join(dataDir, "trash.json"),
+		root:      filepath.Join(dataDir, "trash"),
+		retention: retention,
+		records:   make(map[string]*record),
+	}
+}
+
+// Retention 返回保留时长
+func (b *Bin) Retention() time.Duration {
+	return b.retention
+}
+
+// SetPurgeHook 设置彻底删除前的回调，对条目中的每类数据以其在回收站中的目录调用一次
+// 用于释放数据在其他位置持有的资源（如检查点引用的共享内容）
+func (b *Bin) SetPurgeHook(fn func(kind, dir string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onPurge = fn
+}
+
+// Load 从文件加载回收站索引
+func (b *Bin) Load() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	records := make(map[string]*record)
+	data, err := os.ReadFile(b.path)
+	if os.IsNotExist(err) {
+		b.records = records
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取回收站索引失败: %w", err)
+	}
+
+	var list []*record
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("解析回收站索引失败: %w", err)
+	}
+	for _, r := range list {
+		records[r.TrashID] = r
+	}
+	b.records = records
+	return nil
+}
+
+// Put 将站点数据移入回收站，dirs 为数据类别到目录的映射（不存在的目录会被忽略）
+func (b *Bin) Put(site api.Site, deletedBy string, dirs map[string]string) (*Entry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	r := &record{
+		Entry: Entry{
+			TrashID:   newTrashID(site.ID, now),
+			Site:      site,
+			DeletedAt: now,
+			DeletedBy: deletedBy,
+			PurgeAt:   now.Add(b.retention),
+		},
+		Paths: make(map[string]string),
+	}
+
+	dir := b.entryDir(r)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建回收站目录失败: %w", err)
+	}
+	for kind, src := range dirs {
+		// 站点目录可能是指向发布目录的符号链接，移动链接本身
+		if _, err := os.Lstat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := moveDir(src, filepath.Join(dir, kind)); err != nil {
+			// 已移入的数据放回原处，避免站点数据分散在两个位置
+			b.restoreDirs(r)
+			os.RemoveAll(dir)
+			return nil, fmt.Errorf("移动%s数据失败: %w", kind, err)
+		}
+		r.Paths[kind] = src
+	}
+
+	b.records[r.TrashID] = r
+	if err := b.saveInternal(); err != nil {
+		delete(b.records, r.TrashID)
+		b.restoreDirs(r)
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	entry := r.Entry
+	return &entry, nil
+}
+
+// Get 获取租户回收站中的条目
+func (b *Bin) Get(username, trashID string) (*Entry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, err := b.lookup(username, trashID)
+	if err != nil {
+		return nil, err
+	}
+	entry := r.Entry
+	return &entry, nil
+}
+
+// List 列出租户回收站中的条目（username 为空时列出全部），按删除时间倒序
+func (b *Bin) List(username string) []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	list := make([]Entry, 0)
+	for _, r := range b.records {
+		if username == "" || r.Site.Username == username {
+			list = append(list, r.Entry)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].DeletedAt.After(list[j].DeletedAt) })
+	return list
+}
+
+// Restore 将条目的数据移回原位置并从回收站移除
+// 任一原位置已存在数据时返回 ErrConflict，不做任何移动
+func (b *Bin) Restore(username, trashID string) (*Entry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, err := b.lookup(username, trashID)
+	if err != nil {
+		return nil, err
+	}
+	for _, dst := range r.Paths {
+		if _, err := os.Lstat(dst); err == nil {
+			return nil, fmt.Errorf("%w: %s", ErrConflict, dst)
+		}
+	}
+	if err := b.restoreDirs(r); err != nil {
+		return nil, err
+	}
+
+	delete(b.records, r.TrashID)
+	if err := b.saveInternal(); err != nil {
+		return nil, err
+	}
+	b.removeEntryDir(r)
+
+	entry := r.Entry
+	return &entry, nil
+}
+
+// Purge 彻底删除回收站中的条目
+func (b *Bin) Purge(username, trashID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, err := b.lookup(username, trashID)
+	if err != nil {
+		return err
+	}
+	return b.purgeInternal(r)
+}
+
+// PurgeUser 彻底删除租户回收站中的所有条目，返回删除数量
+func (b *Bin) PurgeUser(username string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := 0
+	for _, r := range b.records {
+		if r.Site.Username != username {
+			continue
+		}
+		if err := b.purgeInternal(r); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// PurgeExpired 彻底删除已到期的条目，返回删除数量
+func (b *Bin) PurgeExpired() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	count := 0
+	for _, r := range b.records {
+		if now.Before(r.PurgeAt) {
+			continue
+		}
+		if err := b.purgeInternal(r); err != nil {
+			slog.Error("清除回收站站点失败", "tenant", r.Site.Username, "site", r.Site.ID, "trash_id", r.TrashID, "error", err)
+			continue
+		}
+		slog.Info("已清除回收站站点", "tenant", r.Site.Username, "site", r.Site.ID, "trash_id", r.TrashID)
+		count++
+	}
+	return count
+}
+
+// Start 启动后台清除任务，每隔 interval 检查一次到期条目
+func (b *Bin) Start(interval time.Duration) {
+	b.stopChan = make(chan struct{})
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		b.PurgeExpired()
+		for {
+			select {
+			case <-ticker.C:
+				b.PurgeExpired()
+			case <-b.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台清除任务
+func (b *Bin) Stop() {
+	if b.stopChan == nil {
+		return
+	}
+	close(b.stopChan)
+	b.wg.Wait()
+}
+
+// lookup 查找属于租户的条目（不加锁）
+func (b *Bin) lookup(username, trashID string) (*record, error) {
+	r, ok := b.records[trashID]
+	if !ok || r.Site.Username != username {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, trashID)
+	}
+	return r, nil
+}
+
+// purgeInternal 删除条目数据和索引（不加锁）
+func (b *Bin) purgeInternal(r *record) error {
+	if b.onPurge != nil {
+		for kind := range r.Paths {
+			b.onPurge(kind, filepath.Join(b.entryDir(r), kind))
+		}
+	}
+	if err := os.RemoveAll(b.entryDir(r)); err != nil {
+		return fmt.Errorf("删除回收站数据失败: %w", err)
+	}
+	b.removeEntryDir(r)
+	delete(b.records, r.TrashID)
+	if err := b.saveInternal(); err != nil {
+		b.records[r.TrashID] = r
+		return err
+	}
+	return nil
+}
+
+// restoreDirs 将条目中已移入的目录移回原位置（不加锁）
+func (b *Bin) restoreDirs(r *record) error {
+	dir := b.entryDir(r)
+	for kind, dst := range r.Paths {
+		if err := moveDir(filepath.Join(dir, kind), dst); err != nil {
+			return fmt.Errorf("恢复%s数据失败: %w", kind, err)
+		}
+	}
+	return nil
+}
+
+// removeEntryDir 删除条目目录，租户目录为空时一并删除
+func (b *Bin) removeEntryDir(r *record) {
+	os.RemoveAll(b.entryDir(r))
+	os.Remove(filepath.Join(b.root, r.Site.Username))
+}
+
+func (b *Bin) entryDir(r *record) string {
+	return filepath.Join(b.root, r.Site.Username, r.TrashID)
+}
+
+// saveInternal 内部保存方法（不加锁）
+func (b *Bin) saveInternal() error {
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	list := make([]*record, 0, len(b.records))
+	for _, r := range b.records {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].DeletedAt.Before(list[j].DeletedAt) })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化回收站索引失败: %w", err)
+	}
+	if err := os.WriteFile(b.path, data, 0644); err != nil {
+		return fmt.Errorf("写入回收站索引失败: %w", err)
+	}
+	return nil
+}
+
+// newTrashID 生成条目 ID：站点 ID + 删除时间 + 随机后缀
+func newTrashID(siteID string, t time.Time) string {
+	buf := make([]byte, 3)
+	rand.Read(buf)
+	return fmt.Sprintf("%s-%s-%s", siteID, t.Format("20060102150405"), hex.EncodeToString(buf))
+}
+
+// moveDir 移动目录；跨文件系统无法重命名时改为复制后删除源目录
+func moveDir(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyDir(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// copyDir 递归复制目录（符号链接按原样复制）
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
@@ -0,0 +1,148 @@
+package trash
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"pages/pkg/api"
+)
+
+// siteDirs 创建站点的各类数据目录，每个目录中写入一个以类别命名的文件
+func siteDirs(t *testing.T, base, siteID string) map[string]string {
+	t.Helper()
+	dirs := map[string]string{
+		"site":        filepath.Join(base, "sites", "alice", siteID),
+		"checkpoints": filepath.Join(base, "checkpoints", "alice", siteID),
+		"analytics":   filepath.Join(base, "analytics", "alice", siteID),
+		"releases":    filepath.Join(base, "sites", "alice", "."+siteID+".releases"), // 不存在的目录被忽略
+	}
+	for kind, dir := range dirs {
+		if kind == "releases" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(filepath.Join(dir, kind+".txt"), []byte(kind), 0644)
+	}
+	return dirs
+}
+
+func TestRestore(t *testing.T) {
+	base := t.TempDir()
+	b := NewBin(base, time.Hour)
+	dirs := siteDirs(t, base, "blog")
+
+	entry, err := b.Put(api.Site{ID: "blog", Username: "alice"}, "admin", dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for kind, dir := range dirs {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("%s 目录未移入回收站: %v", kind, err)
+		}
+	}
+
+	// 索引持久化，其他租户不可见
+	reloaded := NewBin(base, time.Hour)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reloaded.Get("bob", entry.TrashID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("其他租户 Get err = %v, want ErrNotFound", err)
+	}
+	if list := reloaded.List("alice"); len(list) != 1 || list[0].TrashID != entry.TrashID {
+		t.Fatalf("List = %v", list)
+	}
+
+	if _, err := reloaded.Restore("alice", entry.TrashID); err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []string{"site", "checkpoints", "analytics"} {
+		if data, err := os.ReadFile(filepath.Join(dirs[kind], kind+".txt")); err != nil || string(data) != kind {
+			t.Errorf("%s 未恢复: %q, %v", kind, data, err)
+		}
+	}
+	if _, err := os.Stat(dirs["releases"]); !os.IsNotExist(err) {
+		t.Errorf("删除时不存在的目录不应被恢复: %v", err)
+	}
+	if len(reloaded.List("")) != 0 {
+		t.Error("恢复后条目仍在回收站中")
+	}
+	if _, err := os.Stat(filepath.Join(base, "trash", "alice")); !os.IsNotExist(err) {
+		t.Errorf("回收站中的租户目录未删除: %v", err)
+	}
+}
+
+func TestRestoreConflict(t *testing.T) {
+	base := t.TempDir()
+	b := NewBin(base, time.Hour)
+	dirs := siteDirs(t, base, "blog")
+	entry, err := b.Put(api.Site{ID: "blog", Username: "alice"}, "admin", dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 站点 ID 被新站点重新使用
+	if err := os.MkdirAll(dirs["site"], 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dirs["site"], "new.txt"), []byte("new"), 0644)
+
+	if _, err := b.Restore("alice", entry.TrashID); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Restore err = %v, want ErrConflict", err)
+	}
+	// 冲突时不移动任何数据，条目保留在回收站中
+	if _, err := os.Stat(dirs["checkpoints"]); !os.IsNotExist(err) {
+		t.Errorf("冲突时检查点目录不应被恢复: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dirs["site"], "site.txt")); !os.IsNotExist(err) {
+		t.Errorf("冲突时不应覆盖新站点: %v", err)
+	}
+	if _, err := b.Get("alice", entry.TrashID); err != nil {
+		t.Errorf("冲突后条目应保留: %v", err)
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	base := t.TempDir()
+	b := NewBin(base, 0)
+	var purged []string
+	b.SetPurgeHook(func(kind, dir string) {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("回调时 %s 数据应仍存在: %v", kind, err)
+		}
+		purged = append(purged, kind)
+	})
+
+	expired, err := b.Put(api.Site{ID: "blog", Username: "alice"}, "admin", siteDirs(t, base, "blog"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := b.Put(api.Site{ID: "docs", Username: "alice"}, "admin", siteDirs(t, base, "docs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.records[kept.TrashID].PurgeAt = time.Now().Add(time.Hour)
+
+	if n := b.PurgeExpired(); n != 1 {
+		t.Fatalf("PurgeExpired = %d, want 1", n)
+	}
+	sort.Strings(purged)
+	if want := []string{"analytics", "checkpoints", "site"}; len(purged) != len(want) || purged[0] != want[0] || purged[1] != want[1] || purged[2] != want[2] {
+		t.Errorf("回调的数据类别 = %v, want %v", purged, want)
+	}
+	if _, err := b.Get("alice", expired.TrashID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("到期条目仍存在: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(base, "trash", "alice", expired.TrashID)); !os.IsNotExist(err) {
+		t.Errorf("到期条目的检查点和统计数据未删除: %v", err)
+	}
+	if _, err := b.Get("alice", kept.TrashID); err != nil {
+		t.Errorf("未到期的条目被删除: %v", err)
+	}
+}
@@ -0,0 +1,467 @@
+// Package api 定义管理 API 的公共请求/响应类型
+// 服务端 (internal/handler/admin) 与客户端 (pkg/client) 共用这些类型，避免两端结构漂移
+package api
+
+import "time"
+
+// 常用响应消息（客户端据此映射为类型化错误）
+const (
+	MsgSiteNotFound       = "站点不存在"
+	MsgCheckpointNotFound = "检查点不存在"
+	MsgUsernameRequired   = "用户名不能为空"
+	MsgInvalidRequest     = "请求参数错误"
+	MsgAccountNotFound    = "账户不存在"
+	MsgTokenNotFound      = "Token 不存在"
+	MsgTenantNotFound     = "租户不存在"
+	MsgTrashNotFound      = "回收站中不存在该站点"
+	MsgJobNotFound        = "任务不存在"
+	MsgPreconditionFailed = "站点当前检查点与 If-Match 不符"
+	MsgSessionNotFound    = "部署会话不存在或已过期"
+)
+
+// Response 通用响应结构
+type Response struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// CreateSiteRequest 创建站点请求
+type CreateSiteRequest struct {
+	ID     string `json:"id" validate:"required"`
+	Domain string `json:"domain" validate:"required"`
+	Index  string `json:"index"`
+}
+
+// UpdateSiteRequest 更新站点请求
+type UpdateSiteRequest struct {
+	Domain    string               `json:"domain"`
+	Index     string               `json:"index"`
+	Enabled   *bool                `json:"enabled"`
+	Limits    *TenantLimits        `json:"limits"`    // 站点级配额（仅超级管理员可修改，全为 0 时清除）
+	Retention *CheckpointRetention `json:"retention"` // 站点级检查点保留策略（全为 0 时清除，改用服务器默认策略）
+}
+
+// Site 站点对象（JSON 视图，与 internal/site.Site 的序列化结果一致）
+type Site struct {
+	ID        string               `json:"id"`
+	Username  string               `json:"username"`
+	Domain    string               `json:"domain"`
+	Index     string               `json:"index"`
+	Enabled   bool                 `json:"enabled"`
+	Limits    *TenantLimits        `json:"limits,omitempty"`    // 站点级配额（max_sites 不适用）
+	Retention *CheckpointRetention `json:"retention,omitempty"` // 站点级检查点保留策略（为空时使用服务器默认策略）
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// SiteList 站点列表响应数据
+type SiteList struct {
+	Sites []Site `json:"sites"`
+	Total int    `json:"total"`
+}
+
+// Checkpoint 表示一个站点部署检查点
+type Checkpoint struct {
+	ID          string    `json:"id"`                     // 检查点唯一标识（时间戳+哈希）
+	CreatedAt   time.Time `json:"created_at"`             // 创建时间
+	FileSize    int64     `json:"file_size"`              // 备份占用的存储大小（内容寻址格式为创建时新增的内容大小）
+	LogicalSize int64     `json:"logical_size,omitempty"` // 检查点中所有文件的总大小
+	Format      string    `json:"format,omitempty"`       // 存储格式："content"（内容寻址）或 "tar.gz"（早期格式，为空时同此）
+	FileName    string    `json:"file_name"`              // 原始上传文件名
+	Note        string    `json:"note"`                   // 备注信息
+	Source      string    `json:"source"`                 // 来源："deploy" 或 "manual"
+	Description string    `json:"description"`            // 描述信息
+	Labels      []string  `json:"labels,omitempty"`       // 标签（站点内唯一，可代替 ID 切换检查点）
+	Pinned      bool      `json:"pinned,omitempty"`       // 固定的检查点不能删除，也不会被保留策略删除
+}
+
+// CheckpointList 检查点列表响应数据
+type CheckpointList struct {
+	Current     string       `json:"current"`
+	Checkpoints []Checkpoint `json:"checkpoints"`
+	Total       int          `json:"total"`
+}
+
+// CreateCheckpointRequest 手动创建检查点请求
+type CreateCheckpointRequest struct {
+	Note string `json:"note"` // 备注
+}
+
+// UpdateCheckpointRequest 修改检查点请求（省略的字段保持不变）
+type UpdateCheckpointRequest struct {
+	Note   *string   `json:"note"`   // 备注
+	Labels *[]string `json:"labels"` // 标签（整体替换，空数组表示清除）
+	Pinned *bool     `json:"pinned"` // 是否固定
+}
+
+// CheckpointRef 检查点操作（删除/切换）的响应数据
+type CheckpointRef struct {
+	Username     string `json:"username"`
+	ID           string `json:"id"`
+	CheckpointID string `json:"checkpoint_id"`
+}
+
+// DeployResult 部署响应数据
+type DeployResult struct {
+	Username   string      `json:"username"`
+	ID         string      `json:"id"`
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"`
+}
+
+// DeployManifestRequest 增量部署清单
+type DeployManifestRequest struct {
+	Files map[string]string `json:"files"` // 站点内相对路径 -> 文件内容的 SHA-256（十六进制）
+}
+
+// DeploySession 增量部署会话
+type DeploySession struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	SiteID    string    `json:"site_id"`
+	Files     int       `json:"files"`   // 清单中的文件数
+	Reused    int       `json:"reused"`  // 从当前部署或检查点复用的文件数
+	Missing   []string  `json:"missing"` // 仍需上传的内容哈希
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"` // 超过该时间未提交的会话被丢弃
+}
+
+// CheckpointRetention 检查点保留策略，满足任一规则的检查点被保留（0 表示不启用该规则）
+// 固定的检查点和当前激活的检查点始终保留；所有规则均为 0 时不自动删除
+type CheckpointRetention struct {
+	KeepLast   int  `json:"keep_last,omitempty"`   // 保留最近创建的 N 个
+	KeepDays   int  `json:"keep_days,omitempty"`   // 保留最近 N 天内创建的
+	KeepDaily  int  `json:"keep_daily,omitempty"`  // 在 keep_days 之前的检查点中，为最近 N 个有检查点的日期各保留当天最新的一个
+	KeepWeekly int  `json:"keep_weekly,omitempty"` // 在 keep_days 之前的检查点中，为最近 N 个有检查点的周各保留该周最新的一个
+	KeepAll    bool `json:"keep_all,omitempty"`    // 不自动删除（用于站点级覆盖服务器默认策略）
+}
+
+// Enabled 判断策略是否会自动删除检查点
+func (r CheckpointRetention) Enabled() bool {
+	return !r.KeepAll && (r.KeepLast > 0 || r.KeepDays > 0 || r.KeepDaily > 0 || r.KeepWeekly > 0)
+}
+
+// RetentionPlan 按保留策略预览的清理结果（不实际删除）
+type RetentionPlan struct {
+	Policy    CheckpointRetention `json:"policy"`    // 生效的保留策略
+	Inherited bool                `json:"inherited"` // 是否为服务器默认策略（站点未单独设置）
+	Keep      int                 `json:"keep"`      // 保留的检查点数量
+	Prune     []Checkpoint        `json:"prune"`     // 将被删除的检查点（从新到旧）
+}
+
+// CheckpointDiff 两个检查点之间的文件差异（从 from 到 to）
+type CheckpointDiff struct {
+	From      string     `json:"from"`      // 起始检查点 ID
+	To        string     `json:"to"`        // 目标检查点 ID
+	Added     []FileDiff `json:"added"`     // to 中新增的文件
+	Removed   []FileDiff `json:"removed"`   // to 中删除的文件
+	Modified  []FileDiff `json:"modified"`  // 内容有变化的文件
+	Unchanged int        `json:"unchanged"` // 内容相同的文件数量
+}
+
+// FileDiff 单个文件的差异，新增的文件只有 to，删除的文件只有 from
+type FileDiff struct {
+	Path string       `json:"path"`
+	From *FileVersion `json:"from,omitempty"`
+	To   *FileVersion `json:"to,omitempty"`
+	Diff string       `json:"diff,omitempty"` // 统一格式的文本差异（请求 diff=1 且两端均为较小的文本文件时提供）
+}
+
+// FileVersion 文件在某个检查点中的内容
+type FileVersion struct {
+	Hash string `json:"hash"` // 内容的 SHA-256
+	Size int64  `json:"size"` // 文件大小（字节）
+}
+
+// DiskUsage 磁盘使用情况统计
+type DiskUsage struct {
+	DeployedSize             int64  `json:"deployed_size"`            // 当前部署的站点大小(字节)
+	CheckpointsSize          int64  `json:"checkpoints_size"`         // 检查点独占的存储大小(字节)，相同内容只计一次，与其他站点共享的内容不计入
+	CheckpointsLogicalSize   int64  `json:"checkpoints_logical_size"` // 各检查点文件总大小之和(字节)，即不去重时的大小
+	ReleasesSize             int64  `json:"releases_size"`            // 当前发布以外保留的发布目录大小(字节)
+	TotalSize                int64  `json:"total_size"`               // 总使用大小(字节)
+	DeployedSizeHR           string `json:"deployed_size_h"`          // 人类可读格式
+	CheckpointsSizeHR        string `json:"checkpoints_size_h"`
+	CheckpointsLogicalSizeHR string `json:"checkpoints_logical_size_h"`
+	ReleasesSizeHR           string `json:"releases_size_h"`
+	TotalSizeHR              string `json:"total_size_h"`
+	FileCount                int64  `json:"file_count"`       // 文件总数
+	CheckpointCount          int    `json:"checkpoint_count"` // 检查点数量
+}
+
+// DailyStats 每日统计聚合
+type DailyStats struct {
+	Date          string `json:"date"`           // 日期 "2006-01-02"
+	PV            int64  `json:"pv"`             // 页面浏览量
+	UV            int64  `json:"uv"`             // 独立访客 (简单计数)
+	Bytes         int64  `json:"bytes"`          // 流量 (字节)
+	TotalDuration int64  `json:"total_duration"` // 总响应时间 (用于计算平均值)
+	ErrorCount    int64  `json:"error_count"`    // 错误数 (状态码 >= 400)
+}
+
+// SiteStats 站点完整统计（今日 + 历史）
+type SiteStats struct {
+	Today   *DailyStats            `json:"today"`
+	History map[string]*DailyStats `json:"history"`
+}
+
+// ReloadResult 热重载响应数据
+type ReloadResult struct {
+	SitesCount int       `json:"sites_count"`
+	ReloadedAt time.Time `json:"reloaded_at"`
+}
+
+// HealthStatus 健康检查响应数据
+type HealthStatus struct {
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SystemStatus 系统运行状态响应数据
+type SystemStatus struct {
+	SitesCount int               `json:"sites_count"`
+	Login      LoginLimiterStats `json:"login"` // 登录限流统计
+	Timestamp  time.Time         `json:"timestamp"`
+}
+
+// LoginLimiterStats 登录限流统计
+type LoginLimiterStats struct {
+	Failures int64 `json:"failures"` // 累计认证失败次数
+	Lockouts int64 `json:"lockouts"` // 累计锁定次数
+	Locked   int   `json:"locked"`   // 当前处于锁定状态的 IP 与用户名数量
+}
+
+// Account 管理员账户（不含密码）
+type Account struct {
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`             // superadmin / tenant_admin / readonly
+	Tenant    string    `json:"tenant,omitempty"` // 绑定的租户
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AccountList 账户列表响应数据
+type AccountList struct {
+	Accounts []Account `json:"accounts"`
+	Total    int       `json:"total"`
+}
+
+// CreateAccountRequest 创建账户请求
+type CreateAccountRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+	Tenant   string `json:"tenant"`
+}
+
+// UpdateAccountRequest 更新账户请求（字段为空表示不修改）
+type UpdateAccountRequest struct {
+	Password string  `json:"password"`
+	Role     string  `json:"role"`
+	Tenant   *string `json:"tenant"`
+}
+
+// API Token 权限范围
+const (
+	ScopeSiteRead        = "site:read"        // 读取站点、用量与检查点
+	ScopeSiteDeploy      = "site:deploy"      // 部署站点
+	ScopeCheckpointWrite = "checkpoint:write" // 切换/删除检查点
+	ScopeAnalyticsRead   = "analytics:read"   // 读取统计
+)
+
+// APIToken API Token 信息（不含明文）
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`            // 明文前缀，便于识别
+	Tenant     string     `json:"tenant"`            // 绑定的租户
+	SiteID     string     `json:"site_id,omitempty"` // 绑定的站点（为空表示租户内所有站点）
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// APITokenList Token 列表响应数据
+type APITokenList struct {
+	Tokens []APIToken `json:"tokens"`
+	Total  int        `json:"total"`
+}
+
+// CreateTokenRequest 创建 Token 请求
+type CreateTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	SiteID    string     `json:"site_id"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreatedToken 创建 Token 响应数据，明文 Token 仅在此返回一次
+type CreatedToken struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+// AuditEntry 审计日志条目
+type AuditEntry struct {
+	Time         time.Time      `json:"time"`
+	Actor        string         `json:"actor"`                   // 操作者（账户名，Token 认证时为 Token 创建者）
+	AuthMethod   string         `json:"auth_method"`             // 认证方式
+	TokenID      string         `json:"token_id,omitempty"`      // Token 认证时的 Token ID
+	IP           string         `json:"ip"`                      // 客户端 IP（经可信代理时取自 X-Forwarded-For）
+	RemoteAddr   string         `json:"remote_addr"`             // TCP 连接的对端地址（IP:端口）
+	Method       string         `json:"method"`                  // HTTP 方法
+	Route        string         `json:"route"`                   // 路由模板
+	Path         string         `json:"path"`                    // 实际请求路径
+	Tenant       string         `json:"tenant,omitempty"`        // 目标租户
+	SiteID       string         `json:"site_id,omitempty"`       // 目标站点
+	CheckpointID string         `json:"checkpoint_id,omitempty"` // 目标检查点
+	Before       map[string]any `json:"before,omitempty"`        // 变更前摘要
+	After        map[string]any `json:"after,omitempty"`         // 变更后摘要
+	Status       int            `json:"status"`                  // HTTP 状态码
+	Success      bool           `json:"success"`                 // 是否成功
+	Message      string         `json:"message,omitempty"`       // 结果消息
+}
+
+// AuditList 审计日志查询响应数据
+type AuditList struct {
+	Entries []AuditEntry `json:"entries"`
+	Total   int          `json:"total"`
+}
+
+// LoginRequest 管理界面登录请求
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// SessionInfo 当前会话信息
+// 通过会话 Cookie 发起的变更请求需在 X-CSRF-Token 头中携带 CSRFToken
+type SessionInfo struct {
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Method    string    `json:"method"` // 登录方式：session（账户密码）或 oidc（单点登录）
+	CSRFToken string    `json:"csrf_token"`
+	ExpiresAt time.Time `json:"expires_at"` // 会话绝对过期时间
+}
+
+// LoginOptions 登录页可用的登录方式
+type LoginOptions struct {
+	OIDC bool `json:"oidc"` // 是否启用单点登录（跳转 /_api/oidc/login）
+}
+
+// TenantLimits 租户配额（0 表示不限制）
+type TenantLimits struct {
+	MaxSites                 int   `json:"max_sites,omitempty"`                   // 站点数量上限
+	MaxDeployedBytes         int64 `json:"max_deployed_bytes,omitempty"`          // 部署文件总大小上限
+	MaxCheckpointBytes       int64 `json:"max_checkpoint_bytes,omitempty"`        // 检查点总大小上限
+	MaxCheckpoints           int   `json:"max_checkpoints,omitempty"`             // 单个站点检查点数量上限
+	MaxUploadBytes           int64 `json:"max_upload_bytes,omitempty"`            // 单次上传大小上限
+	MaxMonthlyBandwidthBytes int64 `json:"max_monthly_bandwidth_bytes,omitempty"` // 每月流量上限
+}
+
+// Tenant 租户信息
+type Tenant struct {
+	Username         string       `json:"username"`
+	DisplayName      string       `json:"display_name,omitempty"`
+	Contact          string       `json:"contact,omitempty"`
+	Limits           TenantLimits `json:"limits"`
+	Suspended        bool         `json:"suspended"`
+	SuspendedAt      *time.Time   `json:"suspended_at,omitempty"`
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+	SiteCount        int          `json:"site_count"`         // 站点数量（含禁用）
+	EnabledSiteCount int          `json:"enabled_site_count"` // 启用的站点数量
+	Usage            DiskUsage    `json:"usage"`              // 磁盘用量汇总
+}
+
+// TenantList 租户列表响应数据
+type TenantList struct {
+	Tenants []Tenant `json:"tenants"`
+	Total   int      `json:"total"`
+}
+
+// CreateTenantRequest 创建租户请求
+type CreateTenantRequest struct {
+	Username    string       `json:"username"`
+	DisplayName string       `json:"display_name"`
+	Contact     string       `json:"contact"`
+	Limits      TenantLimits `json:"limits"`
+}
+
+// UpdateTenantRequest 更新租户请求，nil 字段不修改
+type UpdateTenantRequest struct {
+	DisplayName *string       `json:"display_name"`
+	Contact     *string       `json:"contact"`
+	Limits      *TenantLimits `json:"limits"`
+}
+
+// DeleteTenantResult 删除租户响应数据
+type DeleteTenantResult struct {
+	Username        string   `json:"username"`
+	SitesRemoved    int      `json:"sites_removed"`
+	TokensRevoked   int      `json:"tokens_revoked"`
+	AccountsRemoved []string `json:"accounts_removed,omitempty"` // 随租户删除的绑定账户
+}
+
+// TrashedSite 回收站中的站点（站点文件、检查点和统计数据随站点一起保留）
+type TrashedSite struct {
+	TrashID   string    `json:"trash_id"`
+	Site      Site      `json:"site"`
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy string    `json:"deleted_by,omitempty"`
+	PurgeAt   time.Time `json:"purge_at"` // 到期后后台彻底删除
+}
+
+// TrashList 回收站列表响应数据
+type TrashList struct {
+	Sites []TrashedSite `json:"sites"`
+	Total int           `json:"total"`
+}
+
+// 异步任务状态
+const (
+	JobQueued    = "queued"    // 等待上传完成或等待执行
+	JobRunning   = "running"   // 执行中
+	JobSucceeded = "succeeded" // 已成功
+	JobFailed    = "failed"    // 已失败
+)
+
+// 部署任务阶段
+const (
+	PhaseUpload     = "upload"     // 接收上传
+	PhaseExtract    = "extract"    // 解压
+	PhaseNormalize  = "normalize"  // 整理目录结构
+	PhaseCheckpoint = "checkpoint" // 为新版本创建检查点
+	PhaseSwap       = "swap"       // 上线新版本（切换发布链接或替换站点目录）
+)
+
+// Job 异步任务
+type Job struct {
+	ID         string        `json:"id"`
+	Type       string        `json:"type"` // deploy
+	Username   string        `json:"username"`
+	SiteID     string        `json:"site_id"`
+	Status     string        `json:"status"`
+	Phase      string        `json:"phase,omitempty"`
+	Progress   int           `json:"progress"` // 当前阶段进度百分比，-1 表示未知
+	Error      string        `json:"error,omitempty"`
+	Result     *DeployResult `json:"result,omitempty"`
+	CreatedBy  string        `json:"created_by,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	StartedAt  *time.Time    `json:"started_at,omitempty"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+}
+
+// Finished 任务是否已结束
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed
+}
+
+// JobList 任务列表响应数据
+type JobList struct {
+	Jobs  []Job `json:"jobs"`
+	Total int   `json:"total"`
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"pages/pkg/api"
+)
+
+// ListAccounts 列出管理员账户（需要超级管理员）
+func (c *Client) ListAccounts(ctx context.Context) (*api.AccountList, error) {
+	var out api.AccountList
+	if err := c.do(ctx, http.MethodGet, "/accounts", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateAccount 创建管理员账户
+func (c *Client) CreateAccount(ctx context.Context, req api.CreateAccountRequest) (*api.Account, error) {
+	var out api.Account
+	if err := c.do(ctx, http.MethodPost, "/accounts", req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAccount 获取管理员账户
+func (c *Client) GetAccount(ctx context.Context, name string) (*api.Account, error) {
+	var out api.Account
+	if err := c.do(ctx, http.MethodGet, "/accounts/"+url.PathEscape(name), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateAccount 更新管理员账户
+func (c *Client) UpdateAccount(ctx context.Context, name string, req api.UpdateAccountRequest) (*api.Account, error) {
+	var out api.Account
+	if err := c.do(ctx, http.MethodPut, "/accounts/"+url.PathEscape(name), req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteAccount 删除管理员账户
+func (c *Client) DeleteAccount(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/accounts/"+url.PathEscape(name), nil, nil)
+}
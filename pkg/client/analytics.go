@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"pages/pkg/api"
+)
+
+// GetUserStats 获取用户所有站点的今日统计（按站点 ID 索引）
+func (c *Client) GetUserStats(ctx context.Context, username string) (map[string]api.DailyStats, error) {
+	out := make(map[string]api.DailyStats)
+	if err := c.doRaw(ctx, http.MethodGet, userPath(username, "analytics"), &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSiteStats 获取站点今日统计
+func (c *Client) GetSiteStats(ctx context.Context, username, id string) (*api.DailyStats, error) {
+	var out api.DailyStats
+	if err := c.doRaw(ctx, http.MethodGet, userPath(username, "sites", id, "analytics"), &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSiteFullStats 获取站点完整统计（包含历史）
+func (c *Client) GetSiteFullStats(ctx context.Context, username, id string) (*api.SiteStats, error) {
+	var out api.SiteStats
+	if err := c.doRaw(ctx, http.MethodGet, userPath(username, "sites", id, "analytics")+"?scope=full", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"pages/pkg/api"
+)
+
+// AuditQuery 审计日志查询条件（零值字段表示不过滤）
+type AuditQuery struct {
+	Tenant string
+	SiteID string
+	Actor  string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// QueryAudit 查询审计日志（按时间倒序）
+func (c *Client) QueryAudit(ctx context.Context, q AuditQuery) (*api.AuditList, error) {
+	v := url.Values{}
+	if q.Tenant != "" {
+		v.Set("tenant", q.Tenant)
+	}
+	if q.SiteID != "" {
+		v.Set("site", q.SiteID)
+	}
+	if q.Actor != "" {
+		v.Set("actor", q.Actor)
+	}
+	if !q.Since.IsZero() {
+		v.Set("since", q.Since.Format(time.RFC3339))
+	}
+	if !q.Until.IsZero() {
+		v.Set("until", q.Until.Format(time.RFC3339))
+	}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+
+	path := "/audit"
+	if len(v) > 0 {
+		path += "?" + v.Encode()
+	}
+
+	var out api.AuditList
+	if err := c.do(ctx, http.MethodGet, path, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"pages/pkg/api"
+)
+
+// ListCheckpoints 列出站点的所有检查点
+func (c *Client) ListCheckpoints(ctx context.Context, username, id string) (*api.CheckpointList, error) {
+	var out api.CheckpointList
+	if err := c.do(ctx, http.MethodGet, userPath(username, "sites", id, "checkpoints"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateCheckpoint 为当前部署的站点内容手动创建检查点
+func (c *Client) CreateCheckpoint(ctx context.Context, username, id, note string) (*api.Checkpoint, error) {
+	var out api.Checkpoint
+	if err := c.do(ctx, http.MethodPost, userPath(username, "sites", id, "checkpoints"), api.CreateCheckpointRequest{Note: note}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetCheckpoint 获取检查点详情，checkpointID 也可以是检查点的标签
+func (c *Client) GetCheckpoint(ctx context.Context, username, id, checkpointID string) (*api.Checkpoint, error) {
+	var out api.Checkpoint
+	if err := c.do(ctx, http.MethodGet, userPath(username, "sites", id, "checkpoints", checkpointID), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateCheckpoint 修改检查点的备注、标签和固定状态（req 中为 nil 的字段保持不变）
+func (c *Client) UpdateCheckpoint(ctx context.Context, username, id, checkpointID string, req api.UpdateCheckpointRequest) (*api.Checkpoint, error) {
+	var out api.Checkpoint
+	if err := c.do(ctx, http.MethodPatch, userPath(username, "sites", id, "checkpoints", checkpointID), req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteCheckpoint 删除检查点
+func (c *Client) DeleteCheckpoint(ctx context.Context, username, id, checkpointID string) error {
+	return c.do(ctx, http.MethodDelete, userPath(username, "sites", id, "checkpoints", checkpointID), nil, nil)
+}
+
+// CheckoutCheckpoint 将站点切换到指定检查点，checkpointID 也可以是检查点的标签
+func (c *Client) CheckoutCheckpoint(ctx context.Context, username, id, checkpointID string) (*api.CheckpointRef, error) {
+	var out api.CheckpointRef
+	if err := c.do(ctx, http.MethodPost, userPath(username, "sites", id, "checkpoints", checkpointID, "checkout"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CheckoutCheckpointIfMatch 仅当站点当前检查点为 current 时切换到指定检查点，否则返回 ErrPreconditionFailed
+func (c *Client) CheckoutCheckpointIfMatch(ctx context.Context, username, id, checkpointID, current string) (*api.CheckpointRef, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, userPath(username, "sites", id, "checkpoints", checkpointID, "checkout"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("If-Match", `"`+current+`"`)
+
+	var out api.CheckpointRef
+	if err := c.send(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetRetentionPlan 预览按保留策略将被删除的检查点（不实际删除）
+func (c *Client) GetRetentionPlan(ctx context.Context, username, id string) (*api.RetentionPlan, error) {
+	var out api.RetentionPlan
+	if err := c.do(ctx, http.MethodGet, userPath(username, "sites", id, "checkpoints", "retention"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DiffCheckpoints 比较两个检查点的文件差异，from、to 可以是检查点 ID、标签或 "current"；
+// withText 为 true 时为较小的文本文件附带统一格式的文本差异
+func (c *Client) DiffCheckpoints(ctx context.Context, username, id, from, to string, withText bool) (*api.CheckpointDiff, error) {
+	path := userPath(username, "sites", id, "checkpoints", from, "diff", to)
+	if withText {
+		path += "?" + url.Values{"diff": {"1"}}.Encode()
+	}
+	var out api.CheckpointDiff
+	if err := c.do(ctx, http.MethodGet, path, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,179 @@
+// Package client 是 Pages 管理 API (/_api) 的 Go 客户端
+//
+// 请求/响应类型来自 pkg/api，与服务端处理器共用:
+//
+//	c := client.New("http://localhost:1323", client.WithBasicAuth("admin", "admin"))
+//	sites, err := c.ListSites(ctx, "default")
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"pages/pkg/api"
+)
+
+// Client 管理 API 客户端
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	username   string
+	password   string
+	token      string
+}
+
+// Option 客户端选项
+type Option func(*Client)
+
+// WithBasicAuth 使用 HTTP Basic Auth 认证
+func WithBasicAuth(username, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithBearerToken 使用 Bearer Token 认证
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient 使用自定义 http.Client
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// New 创建客户端，baseURL 为服务器地址（不含 /_api）
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// userPath 构建 /users/:username/... 路径
+func userPath(username string, parts ...string) string {
+	p := "/users/" + url.PathEscape(username)
+	for _, part := range parts {
+		p += "/" + url.PathEscape(part)
+	}
+	return p
+}
+
+// newRequest 创建带认证信息的请求
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/_api"+path, body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	case c.username != "":
+		req.SetBasicAuth(c.username, c.password)
+	}
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+// do 发送 JSON 请求并将 Response.Data 解码到 out（out 可为 nil）
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("序列化请求失败: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, out)
+}
+
+// send 发送请求并解析统一响应结构
+func (c *Client) send(req *http.Request, out any) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	// 将 Data 直接解码到调用方提供的目标
+	r := api.Response{Data: out}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &r); err != nil && resp.StatusCode < 400 {
+			return fmt.Errorf("解析响应失败: %w", err)
+		}
+	}
+
+	if resp.StatusCode >= 400 || !r.Success {
+		msg := r.Message
+		if msg == "" {
+			msg = strings.TrimSpace(string(data))
+		}
+		return newAPIError(resp.StatusCode, msg)
+	}
+	return nil
+}
+
+// doRaw 发送请求并将响应体直接解码到 out（用于不使用 Response 包装的接口）
+func (c *Client) doRaw(ctx context.Context, method, path string, out any) error {
+	req, err := c.newRequest(ctx, method, path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var e struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(resp.Body)
+		_ = json.Unmarshal(data, &e)
+		msg := e.Error
+		if msg == "" {
+			msg = e.Message
+		}
+		if msg == "" {
+			msg = strings.TrimSpace(string(data))
+		}
+		return newAPIError(resp.StatusCode, msg)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pages/pkg/api"
+)
+
+// newTestServer 启动测试服务器，handler 收到的请求路径不含 /_api 前缀
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.StripPrefix("/_api", handler))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeJSON(w http.ResponseWriter, status int, r api.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(r)
+}
+
+func TestAuthentication(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "Basic Auth", opts: []Option{WithBasicAuth("admin", "secret")}, want: "Basic YWRtaW46c2VjcmV0"},
+		{name: "Bearer Token 优先", opts: []Option{WithBasicAuth("admin", "secret"), WithBearerToken("tok")}, want: "Bearer tok"},
+		{name: "不认证", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("Authorization")
+				writeJSON(w, http.StatusOK, api.Response{Success: true, Data: api.SiteList{}})
+			})
+			if _, err := New(srv.URL+"/", tt.opts...).ListSites(context.Background(), "alice"); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestPathEscaping(t *testing.T) {
+	var got string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.EscapedPath()
+		writeJSON(w, http.StatusOK, api.Response{Success: true, Data: api.Site{ID: "a b"}})
+	})
+	site, err := New(srv.URL).GetSite(context.Background(), "alice", "a b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "/users/alice/sites/a%20b" || site.ID != "a b" {
+		t.Errorf("path = %s, site = %+v", got, site)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    []error
+		notWant []error
+	}{
+		{
+			name:    "站点不存在",
+			status:  http.StatusNotFound,
+			body:    `{"success":false,"message":"站点不存在"}`,
+			want:    []error{ErrSiteNotFound, ErrNotFound},
+			notWant: []error{ErrCheckpointNotFound, ErrServer},
+		},
+		{
+			name:   "检查点不存在（消息带前缀）",
+			status: http.StatusNotFound,
+			body:   `{"success":false,"message":"检出检查点失败: 检查点不存在: cp-1"}`,
+			want:   []error{ErrCheckpointNotFound, ErrNotFound},
+		},
+		{
+			name:    "If-Match 不符",
+			status:  http.StatusPreconditionFailed,
+			body:    `{"success":false,"message":"站点当前检查点与 If-Match 不符"}`,
+			want:    []error{ErrPreconditionFailed},
+			notWant: []error{ErrNotFound},
+		},
+		{name: "配额", status: http.StatusInsufficientStorage, body: `{"success":false,"message":"超出配额"}`, want: []error{ErrQuotaExceeded}},
+		{name: "认证失败", status: http.StatusUnauthorized, body: `{"success":false,"message":"x"}`, want: []error{ErrUnauthorized}},
+		{name: "非 JSON 的服务器错误", status: http.StatusBadGateway, body: "bad gateway\n", want: []error{ErrServer}},
+		{name: "状态码正常但 success 为 false", status: http.StatusOK, body: `{"success":false,"message":"失败"}`, notWant: []error{ErrServer, ErrNotFound}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			})
+			_, err := New(srv.URL).GetSite(context.Background(), "alice", "blog")
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Fatalf("err = %v, want APIError %d", err, tt.status)
+			}
+			if apiErr.Message == "" {
+				t.Error("Message 为空")
+			}
+			for _, target := range tt.want {
+				if !errors.Is(err, target) {
+					t.Errorf("errors.Is(%v, %v) = false", err, target)
+				}
+			}
+			for _, target := range tt.notWant {
+				if errors.Is(err, target) {
+					t.Errorf("errors.Is(%v, %v) = true", err, target)
+				}
+			}
+		})
+	}
+}
+
+func TestDeployStreamsMultipart(t *testing.T) {
+	archive := strings.Repeat("x", 100<<10)
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/users/alice/sites/blog/deploy" {
+			t.Errorf("请求 %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("If-Match"); got != `"cp-1"` {
+			t.Errorf("If-Match = %q", got)
+		}
+		f, hdr, err := r.FormFile("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(f)
+		if hdr.Filename != "site.zip" || string(data) != archive {
+			t.Errorf("上传文件 %s（%d 字节）", hdr.Filename, len(data))
+		}
+		writeJSON(w, http.StatusOK, api.Response{Success: true, Data: api.DeployResult{Username: "alice", ID: "blog", Checkpoint: &api.Checkpoint{ID: "cp-2"}}})
+	})
+
+	var last, total int64
+	res, err := New(srv.URL).Deploy(context.Background(), "alice", "blog", "site.zip", strings.NewReader(archive), &DeployOptions{
+		Size:     int64(len(archive)),
+		IfMatch:  "cp-1",
+		Progress: func(sent, t int64) { last, total = sent, t },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Checkpoint == nil || res.Checkpoint.ID != "cp-2" {
+		t.Errorf("DeployResult = %+v", res)
+	}
+	if last != int64(len(archive)) || total != int64(len(archive)) {
+		t.Errorf("进度 %d/%d, want %d/%d", last, total, len(archive), len(archive))
+	}
+}
+
+func TestDeployRawAsync(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("async") != "1" || q.Get("filename") != "site.tar.gz" {
+			t.Errorf("query = %s", r.URL.RawQuery)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/octet-stream" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if r.ContentLength != 4 {
+			t.Errorf("ContentLength = %d", r.ContentLength)
+		}
+		writeJSON(w, http.StatusAccepted, api.Response{Success: true, Data: api.Job{ID: "job-1"}})
+	})
+	job, err := New(srv.URL).DeployAsync(context.Background(), "alice", "blog", "site.tar.gz", strings.NewReader("data"), &DeployOptions{Size: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID != "job-1" {
+		t.Errorf("Job = %+v", job)
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := New(srv.URL).ListSites(ctx, "alice"); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
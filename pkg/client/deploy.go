@@ -0,0 +1,150 @@
+package client
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+
+	"pages/pkg/api"
+)
+
+// ProgressFunc 上传进度回调，sent 为已发送字节数，total 为总字节数（未知时为 -1）
+type ProgressFunc func(sent, total int64)
+
+// DeployOptions 部署选项
+type DeployOptions struct {
+	Size     int64        // 压缩包大小，用于进度回调（未知时为 0）
+	Progress ProgressFunc // 进度回调（可选）
+	IfMatch  string       // 期望的当前检查点 ID，不符时返回 ErrPreconditionFailed（可选）
+}
+
+// Deploy 以流式 multipart 上传压缩包并部署站点
+// 服务端按文件头识别压缩包格式，无法识别时按 filename 的后缀（.zip / .tar / .tar.gz / .tar.zst / .tar.xz / .tar.bz2）判断
+func (c *Client) Deploy(ctx context.Context, username, id, filename string, archive io.Reader, opts *DeployOptions) (*api.DeployResult, error) {
+	if opts == nil {
+		opts = &DeployOptions{}
+	}
+	total := opts.Size
+	if total <= 0 {
+		total = -1
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	// 边读边写，避免将整个压缩包读入内存
+	go func() {
+		part, err := mw.CreateFormFile("file", filename)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		src := archive
+		if opts.Progress != nil {
+			src = &progressReader{r: archive, total: total, fn: opts.Progress}
+		}
+		if _, err := io.Copy(part, src); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(mw.Close())
+	}()
+
+	req, err := c.newRequest(ctx, http.MethodPost, userPath(username, "sites", id, "deploy"), pr)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	setIfMatch(req, opts.IfMatch)
+
+	var out api.DeployResult
+	if err := c.send(req, &out); err != nil {
+		pr.Close()
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeployRaw 以原始请求体上传压缩包并部署站点，服务端边接收边解压
+// filename 仅用于服务端无法从文件头识别格式时按后缀判断，以及检查点描述，可为空
+func (c *Client) DeployRaw(ctx context.Context, username, id, filename string, archive io.Reader, opts *DeployOptions) (*api.DeployResult, error) {
+	var out api.DeployResult
+	if err := c.deployRaw(ctx, username, id, filename, archive, opts, false, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeployAsync 上传压缩包并提交异步部署任务，上传完成即返回任务
+// 通过 GetJob 轮询或 WatchJob 订阅任务状态
+func (c *Client) DeployAsync(ctx context.Context, username, id, filename string, archive io.Reader, opts *DeployOptions) (*api.Job, error) {
+	var out api.Job
+	if err := c.deployRaw(ctx, username, id, filename, archive, opts, true, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) deployRaw(ctx context.Context, username, id, filename string, archive io.Reader, opts *DeployOptions, async bool, out any) error {
+	if opts == nil {
+		opts = &DeployOptions{}
+	}
+	total := opts.Size
+	if total <= 0 {
+		total = -1
+	}
+
+	body := archive
+	if opts.Progress != nil {
+		body = &progressReader{r: archive, total: total, fn: opts.Progress}
+	}
+
+	v := url.Values{}
+	if filename != "" {
+		v.Set("filename", filename)
+	}
+	if async {
+		v.Set("async", "1")
+	}
+	path := userPath(username, "sites", id, "deploy")
+	if len(v) > 0 {
+		path += "?" + v.Encode()
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	setIfMatch(req, opts.IfMatch)
+	if opts.Size > 0 {
+		req.ContentLength = opts.Size
+	}
+	return c.send(req, out)
+}
+
+// setIfMatch 设置 If-Match 请求头（checkpointID 为空时不设置）
+func setIfMatch(req *http.Request, checkpointID string) {
+	if checkpointID != "" {
+		req.Header.Set("If-Match", `"`+checkpointID+`"`)
+	}
+}
+
+// progressReader 统计读取字节数并回调
+type progressReader struct {
+	r     io.Reader
+	sent  int64
+	total int64
+	fn    ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.sent += int64(n)
+		p.fn(p.sent, p.total)
+	}
+	return n, err
+}
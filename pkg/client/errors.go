@@ -0,0 +1,92 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"pages/pkg/api"
+)
+
+// 可通过 errors.Is 判断的错误类型
+var (
+	ErrBadRequest         = errors.New("请求参数错误")
+	ErrUnauthorized       = errors.New("认证失败")
+	ErrForbidden          = errors.New("无权访问")
+	ErrNotFound           = errors.New("资源不存在")
+	ErrConflict           = errors.New("资源冲突")
+	ErrPreconditionFailed = errors.New(api.MsgPreconditionFailed)
+	ErrServer             = errors.New("服务器错误")
+	ErrQuotaExceeded      = errors.New("超出配额")
+	ErrSiteNotFound       = errors.New(api.MsgSiteNotFound)
+	ErrCheckpointNotFound = errors.New(api.MsgCheckpointNotFound)
+	ErrAccountNotFound    = errors.New(api.MsgAccountNotFound)
+	ErrTokenNotFound      = errors.New(api.MsgTokenNotFound)
+	ErrTenantNotFound     = errors.New(api.MsgTenantNotFound)
+	ErrTrashNotFound      = errors.New(api.MsgTrashNotFound)
+	ErrJobNotFound        = errors.New(api.MsgJobNotFound)
+	ErrSessionNotFound    = errors.New(api.MsgSessionNotFound)
+)
+
+// APIError 服务端返回的错误
+type APIError struct {
+	StatusCode int    // HTTP 状态码
+	Message    string // Response.Message 原文
+	kind       error  // 由消息映射出的具体错误
+}
+
+func newAPIError(status int, message string) *APIError {
+	e := &APIError{StatusCode: status, Message: message}
+	switch {
+	case strings.HasPrefix(message, api.MsgSiteNotFound):
+		e.kind = ErrSiteNotFound
+	case strings.Contains(message, api.MsgCheckpointNotFound):
+		e.kind = ErrCheckpointNotFound
+	case strings.HasPrefix(message, api.MsgAccountNotFound):
+		e.kind = ErrAccountNotFound
+	case strings.HasPrefix(message, api.MsgTokenNotFound):
+		e.kind = ErrTokenNotFound
+	case strings.HasPrefix(message, api.MsgTenantNotFound):
+		e.kind = ErrTenantNotFound
+	case strings.HasPrefix(message, api.MsgTrashNotFound):
+		e.kind = ErrTrashNotFound
+	case strings.HasPrefix(message, api.MsgJobNotFound):
+		e.kind = ErrJobNotFound
+	case strings.HasPrefix(message, api.MsgSessionNotFound):
+		e.kind = ErrSessionNotFound
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("pages api: %d %s", e.StatusCode, e.Message)
+}
+
+// Is 支持 errors.Is(err, client.ErrNotFound) 等判断
+func (e *APIError) Is(target error) bool {
+	if e.kind != nil && target == e.kind {
+		return true
+	}
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound || e.kind != nil
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrPreconditionFailed:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrQuotaExceeded:
+		return e.StatusCode == http.StatusRequestEntityTooLarge ||
+			e.StatusCode == http.StatusTooManyRequests ||
+			e.StatusCode == http.StatusInsufficientStorage
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"pages/pkg/api"
+)
+
+// ListJobs 列出所有异步任务（tenant 为空时不过滤，仅超级管理员和全局只读账户可用）
+func (c *Client) ListJobs(ctx context.Context, tenant string) (*api.JobList, error) {
+	path := "/jobs"
+	if tenant != "" {
+		path += "?" + url.Values{"tenant": {tenant}}.Encode()
+	}
+	var out api.JobList
+	if err := c.do(ctx, http.MethodGet, path, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListUserJobs 列出租户的异步任务
+func (c *Client) ListUserJobs(ctx context.Context, username string) (*api.JobList, error) {
+	var out api.JobList
+	if err := c.do(ctx, http.MethodGet, userPath(username, "jobs"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetJob 获取异步任务状态
+func (c *Client) GetJob(ctx context.Context, id string) (*api.Job, error) {
+	var out api.Job
+	if err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// WatchJob 订阅任务事件流直到任务结束，每次状态变化时调用 fn（可为 nil），返回最终状态
+func (c *Client) WatchJob(ctx context.Context, id string, fn func(api.Job)) (*api.Job, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id)+"/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		data, _ := io.ReadAll(resp.Body)
+		var r api.Response
+		if json.Unmarshal(data, &r) != nil || r.Message == "" {
+			r.Message = strings.TrimSpace(string(data))
+		}
+		return nil, newAPIError(resp.StatusCode, r.Message)
+	}
+
+	var last *api.Job
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var job api.Job
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			return nil, fmt.Errorf("解析任务事件失败: %w", err)
+		}
+		last = &job
+		if fn != nil {
+			fn(job)
+		}
+		if job.Finished() {
+			return last, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, fmt.Errorf("任务事件流意外结束")
+	}
+	// 事件流在任务结束前断开，返回最新状态
+	return c.GetJob(ctx, id)
+}
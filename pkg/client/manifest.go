@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"pages/pkg/api"
+)
+
+// CreateDeploySession 提交增量部署清单（站点内相对路径 -> 文件内容的 SHA-256），返回需要上传的内容哈希
+func (c *Client) CreateDeploySession(ctx context.Context, username, id string, files map[string]string) (*api.DeploySession, error) {
+	var out api.DeploySession
+	if err := c.do(ctx, http.MethodPost, userPath(username, "sites", id, "deploy", "sessions"), api.DeployManifestRequest{Files: files}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDeploySession 获取增量部署会话状态
+func (c *Client) GetDeploySession(ctx context.Context, username, id, sessionID string) (*api.DeploySession, error) {
+	var out api.DeploySession
+	if err := c.do(ctx, http.MethodGet, userPath(username, "sites", id, "deploy", "sessions", sessionID), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UploadDeployBlob 上传增量部署缺失的一个文件内容，size 为内容大小（未知时为 0）
+func (c *Client) UploadDeployBlob(ctx context.Context, username, id, sessionID, hash string, content io.Reader, size int64) (*api.DeploySession, error) {
+	req, err := c.newRequest(ctx, http.MethodPut, userPath(username, "sites", id, "deploy", "sessions", sessionID, "blobs", hash), content)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if size > 0 {
+		req.ContentLength = size
+	}
+
+	var out api.DeploySession
+	if err := c.send(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CommitDeploySession 提交增量部署，服务端组装站点内容并替换站点目录
+// ifMatch 为期望的当前检查点 ID，不符时返回 ErrPreconditionFailed（为空时不检查）
+func (c *Client) CommitDeploySession(ctx context.Context, username, id, sessionID, ifMatch string) (*api.DeployResult, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, userPath(username, "sites", id, "deploy", "sessions", sessionID, "commit"), nil)
+	if err != nil {
+		return nil, err
+	}
+	setIfMatch(req, ifMatch)
+
+	var out api.DeployResult
+	if err := c.send(req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CancelDeploySession 取消增量部署会话
+func (c *Client) CancelDeploySession(ctx context.Context, username, id, sessionID string) error {
+	return c.do(ctx, http.MethodDelete, userPath(username, "sites", id, "deploy", "sessions", sessionID), nil, nil)
+}
+
+// DeployDir 以增量方式部署本地目录：只上传服务端当前部署和检查点中没有的文件内容
+// opts 中仅 IfMatch 生效；失败时取消会话
+func (c *Client) DeployDir(ctx context.Context, username, id, dir string, opts *DeployOptions) (*api.DeployResult, error) {
+	if opts == nil {
+		opts = &DeployOptions{}
+	}
+
+	files, paths, err := hashDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := c.CreateDeploySession(ctx, username, id, files)
+	if err != nil {
+		return nil, err
+	}
+	for _, hash := range session.Missing {
+		if err := c.uploadFile(ctx, username, id, session.ID, hash, paths[hash]); err != nil {
+			c.CancelDeploySession(context.WithoutCancel(ctx), username, id, session.ID)
+			return nil, err
+		}
+	}
+	return c.CommitDeploySession(ctx, username, id, session.ID, opts.IfMatch)
+}
+
+func (c *Client) uploadFile(ctx context.Context, username, id, sessionID, hash, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	_, err = c.UploadDeployBlob(ctx, username, id, sessionID, hash, f, info.Size())
+	return err
+}
+
+// hashDir 计算目录的部署清单，同时返回每个哈希对应的一个本地文件路径
+func hashDir(dir string) (files map[string]string, paths map[string]string, err error) {
+	files = make(map[string]string)
+	paths = make(map[string]string)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		hash := hex.EncodeToString(h.Sum(nil))
+
+		files[filepath.ToSlash(rel)] = hash
+		paths[hash] = path
+		return nil
+	})
+	return files, paths, err
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"pages/pkg/api"
+)
+
+// ListSites 列出用户的所有站点
+func (c *Client) ListSites(ctx context.Context, username string) (*api.SiteList, error) {
+	var out api.SiteList
+	if err := c.do(ctx, http.MethodGet, userPath(username, "sites"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateSite 为用户创建站点
+func (c *Client) CreateSite(ctx context.Context, username string, req api.CreateSiteRequest) (*api.Site, error) {
+	var out api.Site
+	if err := c.do(ctx, http.MethodPost, userPath(username, "sites"), req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSite 获取站点详情
+func (c *Client) GetSite(ctx context.Context, username, id string) (*api.Site, error) {
+	var out api.Site
+	if err := c.do(ctx, http.MethodGet, userPath(username, "sites", id), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateSite 更新站点
+func (c *Client) UpdateSite(ctx context.Context, username, id string, req api.UpdateSiteRequest) (*api.Site, error) {
+	var out api.Site
+	if err := c.do(ctx, http.MethodPut, userPath(username, "sites", id), req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteSite 删除站点（移入回收站，保留期内可通过 RestoreSite 恢复）
+func (c *Client) DeleteSite(ctx context.Context, username, id string) error {
+	return c.do(ctx, http.MethodDelete, userPath(username, "sites", id), nil, nil)
+}
+
+// PurgeSite 删除站点并立即彻底删除其文件、检查点和统计数据（不可恢复）
+func (c *Client) PurgeSite(ctx context.Context, username, id string) error {
+	return c.do(ctx, http.MethodDelete, userPath(username, "sites", id)+"?purge=true", nil, nil)
+}
+
+// GetSiteUsage 获取站点磁盘用量
+func (c *Client) GetSiteUsage(ctx context.Context, username, id string) (*api.DiskUsage, error) {
+	var out api.DiskUsage
+	if err := c.do(ctx, http.MethodGet, userPath(username, "sites", id, "usage"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUserUsage 获取用户所有站点的用量汇总
+func (c *Client) GetUserUsage(ctx context.Context, username string) (*api.DiskUsage, error) {
+	var out api.DiskUsage
+	if err := c.do(ctx, http.MethodGet, userPath(username, "usage"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
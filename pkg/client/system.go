@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"pages/pkg/api"
+)
+
+// Reload 热重载站点配置
+func (c *Client) Reload(ctx context.Context) (*api.ReloadResult, error) {
+	var out api.ReloadResult
+	if err := c.do(ctx, http.MethodPost, "/system/reload", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Health 健康检查
+func (c *Client) Health(ctx context.Context) (*api.HealthStatus, error) {
+	var out api.HealthStatus
+	if err := c.do(ctx, http.MethodGet, "/system/health", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Status 系统运行状态（仅超级管理员）
+func (c *Client) Status(ctx context.Context) (*api.SystemStatus, error) {
+	var out api.SystemStatus
+	if err := c.do(ctx, http.MethodGet, "/system/status", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"pages/pkg/api"
+)
+
+// ListTenants 列出所有租户
+func (c *Client) ListTenants(ctx context.Context) (*api.TenantList, error) {
+	var out api.TenantList
+	if err := c.do(ctx, http.MethodGet, "/users", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetTenant 获取租户详情
+func (c *Client) GetTenant(ctx context.Context, username string) (*api.Tenant, error) {
+	var out api.Tenant
+	if err := c.do(ctx, http.MethodGet, userPath(username), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateTenant 创建租户
+func (c *Client) CreateTenant(ctx context.Context, req api.CreateTenantRequest) (*api.Tenant, error) {
+	var out api.Tenant
+	if err := c.do(ctx, http.MethodPost, "/users", req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateTenant 更新租户元数据（仅修改非 nil 字段）
+func (c *Client) UpdateTenant(ctx context.Context, username string, req api.UpdateTenantRequest) (*api.Tenant, error) {
+	var out api.Tenant
+	if err := c.do(ctx, http.MethodPut, userPath(username), req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SuspendTenant 停用租户，其所有站点立即停止服务
+func (c *Client) SuspendTenant(ctx context.Context, username string) (*api.Tenant, error) {
+	var out api.Tenant
+	if err := c.do(ctx, http.MethodPost, userPath(username, "suspend"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ResumeTenant 恢复已停用的租户
+func (c *Client) ResumeTenant(ctx context.Context, username string) (*api.Tenant, error) {
+	var out api.Tenant
+	if err := c.do(ctx, http.MethodPost, userPath(username, "resume"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteTenant 删除租户及其全部站点、文件、检查点、访问统计和 API Token
+func (c *Client) DeleteTenant(ctx context.Context, username string) (*api.DeleteTenantResult, error) {
+	var out api.DeleteTenantResult
+	if err := c.do(ctx, http.MethodDelete, userPath(username), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
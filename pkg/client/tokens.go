@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"pages/pkg/api"
+)
+
+// ListTokens 列出租户的 API Token
+func (c *Client) ListTokens(ctx context.Context, username string) (*api.APITokenList, error) {
+	var out api.APITokenList
+	if err := c.do(ctx, http.MethodGet, userPath(username, "tokens"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateToken 为租户创建 API Token，返回值中的 Token 明文仅此一次可见
+func (c *Client) CreateToken(ctx context.Context, username string, req api.CreateTokenRequest) (*api.CreatedToken, error) {
+	var out api.CreatedToken
+	if err := c.do(ctx, http.MethodPost, userPath(username, "tokens"), req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RevokeToken 吊销 API Token
+func (c *Client) RevokeToken(ctx context.Context, username, tokenID string) error {
+	return c.do(ctx, http.MethodDelete, userPath(username, "tokens", tokenID), nil, nil)
+}
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"pages/pkg/api"
+)
+
+// ListTrash 列出租户回收站中的站点
+func (c *Client) ListTrash(ctx context.Context, username string) (*api.TrashList, error) {
+	var out api.TrashList
+	if err := c.do(ctx, http.MethodGet, userPath(username, "trash"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RestoreSite 从回收站恢复站点
+func (c *Client) RestoreSite(ctx context.Context, username, trashID string) (*api.Site, error) {
+	var out api.Site
+	if err := c.do(ctx, http.MethodPost, userPath(username, "trash", trashID, "restore"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PurgeTrashedSite 立即彻底删除回收站中的站点
+func (c *Client) PurgeTrashedSite(ctx context.Context, username, trashID string) error {
+	return c.do(ctx, http.MethodDelete, userPath(username, "trash", trashID), nil, nil)
+}